@@ -18,6 +18,13 @@ import (
 func main() {
 	cfg := config.Load()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), cfg, os.Args[2:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
 	logger, err := logger.Init(cfg.Production)
 	if err != nil {
 		log.Fatalf("failed to initialize logger: %v", err)
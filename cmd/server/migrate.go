@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"pfg/infra/atlas"
+	"pfg/internal/config"
+	"pfg/internal/db"
+	"pfg/internal/migrate"
+)
+
+const migrateUsage = "usage: server migrate up | down [n] | status"
+
+// runMigrate handles the migrate subcommand: up applies every pending
+// migration, down reverts the last n (default 1) and status lists them.
+func runMigrate(ctx context.Context, cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	conn, err := db.Connect(cfg.GetPostgresURL())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := migrate.New(conn.Pool(), atlas.Migrations)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %s_%s\n", m.Version, m.Description)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("nothing to apply")
+		}
+		return err
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations %q", args[1])
+			}
+		}
+		reverted, err := migrator.Down(ctx, n)
+		for _, m := range reverted {
+			fmt.Printf("reverted %s_%s\n", m.Version, m.Description)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+		}
+		return err
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tDESCRIPTION\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			status, at := "pending", ""
+			if s.Applied {
+				status, at = "applied", s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Changed {
+				status = "changed since applied"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Version, s.Description, status, at)
+		}
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -0,0 +1,2 @@
+-- Drop "pack_sizes" table
+DROP TABLE "pack_sizes";
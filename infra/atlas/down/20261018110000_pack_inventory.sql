@@ -0,0 +1,2 @@
+-- Drop "pack_inventory" table
+DROP TABLE "pack_inventory";
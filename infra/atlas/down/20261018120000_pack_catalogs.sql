@@ -0,0 +1,9 @@
+-- Only the default catalog's pack sizes and stock can be kept
+DELETE FROM "pack_inventory" WHERE "catalog_id" <> 'default';
+DELETE FROM "pack_sizes" WHERE "catalog_id" <> 'default';
+-- Modify "pack_inventory" table
+ALTER TABLE "pack_inventory" DROP CONSTRAINT "pack_inventory_pkey", DROP COLUMN "catalog_id", ADD PRIMARY KEY ("size");
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" DROP COLUMN "catalog_id";
+-- Drop "pack_catalogs" table
+DROP TABLE "pack_catalogs";
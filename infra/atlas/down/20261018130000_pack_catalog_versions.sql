@@ -0,0 +1,2 @@
+-- Drop "pack_catalog_versions" table
+DROP TABLE "pack_catalog_versions";
@@ -0,0 +1,2 @@
+-- Drop "pack_orders" table
+DROP TABLE "pack_orders";
@@ -0,0 +1,2 @@
+-- Drop "order_transitions" table
+DROP TABLE "order_transitions";
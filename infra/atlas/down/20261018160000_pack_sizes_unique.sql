@@ -0,0 +1,2 @@
+-- Drop index "pack_sizes_catalog_id_size_key" from table: "pack_sizes"
+DROP INDEX "pack_sizes_catalog_id_size_key";
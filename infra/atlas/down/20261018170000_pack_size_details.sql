@@ -0,0 +1,2 @@
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" DROP COLUMN "label", DROP COLUMN "sku", DROP COLUMN "length_mm", DROP COLUMN "width_mm", DROP COLUMN "height_mm", DROP COLUMN "tare_weight_g";
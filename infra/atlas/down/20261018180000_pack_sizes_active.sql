@@ -0,0 +1,2 @@
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" DROP COLUMN "active";
@@ -0,0 +1,6 @@
+-- Modify "users" table
+ALTER TABLE "users" ADD COLUMN "is_admin" boolean NOT NULL DEFAULT false;
+-- Carry over admin rights
+UPDATE "users" SET "is_admin" = true WHERE "role" = 'admin';
+-- Modify "users" table
+ALTER TABLE "users" DROP COLUMN "role";
@@ -0,0 +1,11 @@
+// Package atlas embeds the database migrations so the server binary can
+// apply them without the Atlas CLI.
+package atlas
+
+import "embed"
+
+// Migrations holds migrations/, the Atlas migration directory with its
+// atlas.sum, and down/, which reverts each migration of the same name.
+//
+//go:embed migrations/*.sql migrations/atlas.sum down/*.sql
+var Migrations embed.FS
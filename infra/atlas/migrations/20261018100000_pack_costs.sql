@@ -0,0 +1,2 @@
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" ADD COLUMN "unit_cost_cents" bigint NOT NULL DEFAULT 0;
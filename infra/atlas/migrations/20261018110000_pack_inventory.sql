@@ -0,0 +1,7 @@
+-- Create "pack_inventory" table
+CREATE TABLE "pack_inventory" (
+  "size" integer NOT NULL,
+  "on_hand" integer NOT NULL,
+  PRIMARY KEY ("size"),
+  CONSTRAINT "pack_inventory_on_hand_check" CHECK (on_hand >= 0)
+);
@@ -0,0 +1,14 @@
+-- Create "pack_catalogs" table
+CREATE TABLE "pack_catalogs" (
+  "id" text NOT NULL,
+  "name" text NOT NULL,
+  PRIMARY KEY ("id")
+);
+-- Existing pack sizes and stock become the default catalog
+INSERT INTO "pack_catalogs" ("id", "name") VALUES ('default', 'Default');
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" ADD COLUMN "catalog_id" text NOT NULL DEFAULT 'default', ADD CONSTRAINT "pack_sizes_catalog_id_fkey" FOREIGN KEY ("catalog_id") REFERENCES "pack_catalogs" ("id") ON UPDATE NO ACTION ON DELETE CASCADE;
+ALTER TABLE "pack_sizes" ALTER COLUMN "catalog_id" DROP DEFAULT;
+-- Modify "pack_inventory" table
+ALTER TABLE "pack_inventory" ADD COLUMN "catalog_id" text NOT NULL DEFAULT 'default', DROP CONSTRAINT "pack_inventory_pkey", ADD PRIMARY KEY ("catalog_id", "size"), ADD CONSTRAINT "pack_inventory_catalog_id_fkey" FOREIGN KEY ("catalog_id") REFERENCES "pack_catalogs" ("id") ON UPDATE NO ACTION ON DELETE CASCADE;
+ALTER TABLE "pack_inventory" ALTER COLUMN "catalog_id" DROP DEFAULT;
@@ -0,0 +1,18 @@
+-- Create "pack_catalog_versions" table
+CREATE TABLE "pack_catalog_versions" (
+  "catalog_id" text NOT NULL,
+  "version" integer NOT NULL,
+  "changed_at" timestamptz NOT NULL DEFAULT now(),
+  "changed_by" text NOT NULL,
+  "change" text NOT NULL,
+  "sizes" jsonb NOT NULL,
+  PRIMARY KEY ("catalog_id", "version"),
+  CONSTRAINT "pack_catalog_versions_catalog_id_fkey" FOREIGN KEY ("catalog_id") REFERENCES "pack_catalogs" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Record the current pack sizes of every catalog as its first version
+INSERT INTO "pack_catalog_versions" ("catalog_id", "version", "changed_by", "change", "sizes")
+SELECT c."id", 1, 'migration', 'initial version', COALESCE(
+  (SELECT jsonb_agg(jsonb_build_object('size', s."size", 'unitCost', s."unit_cost_cents") ORDER BY s."size")
+   FROM "pack_sizes" s WHERE s."catalog_id" = c."id"),
+  '[]'::jsonb)
+FROM "pack_catalogs" c;
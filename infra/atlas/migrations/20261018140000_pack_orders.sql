@@ -0,0 +1,18 @@
+-- Create "pack_orders" table
+CREATE TABLE "pack_orders" (
+  "id" bigserial NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  "requested_by" text NOT NULL,
+  "catalog_id" text NOT NULL,
+  "catalog_version" integer NOT NULL,
+  "sizes" jsonb NOT NULL,
+  "strategy" text NOT NULL,
+  "requested" integer NOT NULL,
+  "total_items" integer NOT NULL,
+  "total_packs" integer NOT NULL,
+  "total_cost_cents" bigint NOT NULL,
+  "packs" jsonb NOT NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "pack_orders_created_at_idx" to table: "pack_orders"
+CREATE INDEX "pack_orders_created_at_idx" ON "pack_orders" ("created_at");
@@ -0,0 +1,11 @@
+-- Create "order_transitions" table
+CREATE TABLE "order_transitions" (
+  "order_id" bigint NOT NULL,
+  "seq" integer NOT NULL,
+  "from_state" text NOT NULL,
+  "to_state" text NOT NULL,
+  "changed_at" timestamptz NOT NULL DEFAULT now(),
+  "changed_by" text NOT NULL,
+  PRIMARY KEY ("order_id", "seq"),
+  CONSTRAINT "order_transitions_order_id_fkey" FOREIGN KEY ("order_id") REFERENCES "pack_orders" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
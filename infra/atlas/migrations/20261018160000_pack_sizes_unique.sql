@@ -0,0 +1,4 @@
+-- Keep the first row of any pack size that was added more than once
+DELETE FROM "pack_sizes" a USING "pack_sizes" b WHERE a."catalog_id" = b."catalog_id" AND a."size" = b."size" AND a."id" > b."id";
+-- Create index "pack_sizes_catalog_id_size_key" to table: "pack_sizes"
+CREATE UNIQUE INDEX "pack_sizes_catalog_id_size_key" ON "pack_sizes" ("catalog_id", "size");
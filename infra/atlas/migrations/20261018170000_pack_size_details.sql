@@ -0,0 +1,2 @@
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" ADD COLUMN "label" text NOT NULL DEFAULT '', ADD COLUMN "sku" text NOT NULL DEFAULT '', ADD COLUMN "length_mm" integer NOT NULL DEFAULT 0, ADD COLUMN "width_mm" integer NOT NULL DEFAULT 0, ADD COLUMN "height_mm" integer NOT NULL DEFAULT 0, ADD COLUMN "tare_weight_g" integer NOT NULL DEFAULT 0;
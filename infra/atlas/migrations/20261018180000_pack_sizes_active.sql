@@ -0,0 +1,2 @@
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" ADD COLUMN "active" boolean NOT NULL DEFAULT true;
@@ -0,0 +1,2 @@
+-- Modify "pack_sizes" table
+ALTER TABLE "pack_sizes" ADD COLUMN "available_from" timestamptz NULL, ADD COLUMN "retires_at" timestamptz NULL;
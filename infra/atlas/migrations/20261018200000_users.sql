@@ -0,0 +1,10 @@
+-- Create "users" table
+CREATE TABLE "users" (
+  "id" bigserial NOT NULL,
+  "email" text NOT NULL,
+  "password_hash" text NOT NULL,
+  "is_admin" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY ("id"),
+  CONSTRAINT "users_email_key" UNIQUE ("email")
+);
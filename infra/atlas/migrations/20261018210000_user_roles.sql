@@ -0,0 +1,6 @@
+-- Modify "users" table
+ALTER TABLE "users" ADD COLUMN "role" text NOT NULL DEFAULT 'viewer', ADD CONSTRAINT "users_role_check" CHECK (role IN ('viewer', 'operator', 'admin'));
+-- Carry over admin rights
+UPDATE "users" SET "role" = 'admin' WHERE "is_admin";
+-- Modify "users" table
+ALTER TABLE "users" DROP COLUMN "is_admin";
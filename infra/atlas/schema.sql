@@ -1,4 +1,76 @@
+CREATE TABLE pack_catalogs (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL
+);
+
 CREATE TABLE pack_sizes (
   id SERIAL PRIMARY KEY,
-  size INTEGER NOT NULL
-);
\ No newline at end of file
+  catalog_id TEXT NOT NULL REFERENCES pack_catalogs (id) ON DELETE CASCADE,
+  size INTEGER NOT NULL,
+  unit_cost_cents BIGINT NOT NULL DEFAULT 0,
+  label TEXT NOT NULL DEFAULT '',
+  sku TEXT NOT NULL DEFAULT '',
+  length_mm INTEGER NOT NULL DEFAULT 0,
+  width_mm INTEGER NOT NULL DEFAULT 0,
+  height_mm INTEGER NOT NULL DEFAULT 0,
+  tare_weight_g INTEGER NOT NULL DEFAULT 0,
+  active BOOLEAN NOT NULL DEFAULT true,
+  available_from TIMESTAMPTZ,
+  retires_at TIMESTAMPTZ,
+  UNIQUE (catalog_id, size)
+);
+
+CREATE TABLE pack_inventory (
+  catalog_id TEXT NOT NULL REFERENCES pack_catalogs (id) ON DELETE CASCADE,
+  size INTEGER NOT NULL,
+  on_hand INTEGER NOT NULL CHECK (on_hand >= 0),
+  PRIMARY KEY (catalog_id, size)
+);
+
+CREATE TABLE pack_catalog_versions (
+  catalog_id TEXT NOT NULL REFERENCES pack_catalogs (id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  changed_by TEXT NOT NULL,
+  change TEXT NOT NULL,
+  sizes JSONB NOT NULL,
+  PRIMARY KEY (catalog_id, version)
+);
+
+-- Orders keep their catalog ID and a copy of its pack sizes, so they
+-- outlive the catalog they were placed against.
+CREATE TABLE pack_orders (
+  id BIGSERIAL PRIMARY KEY,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  requested_by TEXT NOT NULL,
+  catalog_id TEXT NOT NULL,
+  catalog_version INTEGER NOT NULL,
+  sizes JSONB NOT NULL,
+  strategy TEXT NOT NULL,
+  requested INTEGER NOT NULL,
+  total_items INTEGER NOT NULL,
+  total_packs INTEGER NOT NULL,
+  total_cost_cents BIGINT NOT NULL,
+  packs JSONB NOT NULL
+);
+
+CREATE INDEX pack_orders_created_at_idx ON pack_orders (created_at);
+
+CREATE TABLE order_transitions (
+  order_id BIGINT NOT NULL REFERENCES pack_orders (id) ON DELETE CASCADE,
+  seq INTEGER NOT NULL,
+  from_state TEXT NOT NULL,
+  to_state TEXT NOT NULL,
+  changed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  changed_by TEXT NOT NULL,
+  PRIMARY KEY (order_id, seq)
+);
+
+-- Passwords are stored as bcrypt hashes. Emails are kept in lower case.
+CREATE TABLE users (
+  id BIGSERIAL PRIMARY KEY,
+  email TEXT NOT NULL UNIQUE,
+  password_hash TEXT NOT NULL,
+  role TEXT NOT NULL DEFAULT 'viewer' CHECK (role IN ('viewer', 'operator', 'admin')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
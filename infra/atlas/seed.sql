@@ -1,5 +1,22 @@
-INSERT INTO pack_sizes (size) VALUES
-  (250),
-  (500),
-  (1000),
-  (5000);
+-- Seeds the default catalog with its demo pack sizes and records them as a
+-- catalog version, since calculations read sizes from the versions. A
+-- catalog that already has pack sizes is left alone, so seeding twice is
+-- harmless.
+BEGIN;
+
+-- Number the version under the same lock the service takes.
+SELECT 1 FROM pack_catalogs WHERE id = 'default' FOR UPDATE;
+
+WITH seeded AS (
+  INSERT INTO pack_sizes (catalog_id, size)
+  SELECT 'default', size FROM (VALUES (250), (500), (1000), (5000)) AS demo (size)
+  WHERE NOT EXISTS (SELECT 1 FROM pack_sizes WHERE catalog_id = 'default')
+  RETURNING size, unit_cost_cents
+)
+INSERT INTO pack_catalog_versions (catalog_id, version, changed_by, change, sizes)
+SELECT 'default', COALESCE(MAX(version), 0) + 1, 'seed', 'seeded demo pack sizes',
+  (SELECT jsonb_agg(jsonb_build_object('size', size, 'unitCost', unit_cost_cents) ORDER BY size) FROM seeded)
+FROM pack_catalog_versions WHERE catalog_id = 'default'
+HAVING EXISTS (SELECT 1 FROM seeded);
+
+COMMIT;
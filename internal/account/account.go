@@ -0,0 +1,171 @@
+// Package account keeps the user accounts that may sign in to the admin
+// pages and the API, with their passwords stored as bcrypt hashes and a role
+// deciding what each may do.
+package account
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest password accepted for an account.
+const MinPasswordLength = 8
+
+// Kinds of failure callers can act on without knowing the specific cause.
+// Every error of a kind matches it with errors.Is, e.g. ErrUnknownUser is
+// ErrNotFound.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("already exists")
+	ErrInvalid   = errors.New("invalid input")
+)
+
+var (
+	ErrUnknownUser   = fmt.Errorf("user %w", ErrNotFound)
+	ErrDuplicateUser = fmt.Errorf("user %w", ErrDuplicate)
+	// ErrLastAdmin is returned for changes that would leave no admin to
+	// manage the accounts.
+	ErrLastAdmin = fmt.Errorf("%w: the last admin cannot be removed or demoted", ErrInvalid)
+	// ErrInvalidCredentials is returned by Authenticate whether the email
+	// or the password is wrong, so callers cannot tell which.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+type User struct {
+	ID           int64
+	Email        string
+	PasswordHash string
+	Role         Role
+	CreatedAt    time.Time
+}
+
+type Service struct {
+	repo Repository
+	cost int
+	// dummyHash is compared against for unknown emails, so that signing in
+	// takes as long whether or not the account exists.
+	dummyHash []byte
+}
+
+type Option func(*Service)
+
+// WithHashCost sets the bcrypt cost of new password hashes. It defaults to
+// bcrypt.DefaultCost; tests lower it to bcrypt.MinCost to run faster.
+func WithHashCost(cost int) Option {
+	return func(s *Service) {
+		s.cost = cost
+	}
+}
+
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{repo: repo, cost: bcrypt.DefaultCost}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), s.cost)
+	return s
+}
+
+// normaliseEmail validates an email address and returns it in lower case,
+// so accounts are found whatever case it is typed in.
+func normaliseEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return "", fmt.Errorf("%w: invalid email %q", ErrInvalid, email)
+	}
+	return email, nil
+}
+
+func (s *Service) hash(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("%w: passwords must be at least %d characters", ErrInvalid, MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", fmt.Errorf("%w: passwords must be at most 72 bytes", ErrInvalid)
+	}
+	return string(hash), err
+}
+
+// Bootstrap creates an admin with the given credentials if there are no
+// accounts yet, and reports whether it did. Once any account exists the
+// credentials are ignored, so changing them cannot take over the store.
+func (s *Service) Bootstrap(ctx context.Context, email, password string) (bool, error) {
+	users, err := s.repo.GetUsers(ctx)
+	if err != nil || len(users) > 0 {
+		return false, err
+	}
+	if _, err := s.AddUser(ctx, email, password, RoleAdmin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Authenticate returns the user with the given email and password, or
+// ErrInvalidCredentials.
+func (s *Service) Authenticate(ctx context.Context, email, password string) (User, error) {
+	u, err := s.repo.GetUserByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if errors.Is(err, ErrUnknownUser) {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return User{}, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		return User{}, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+func (s *Service) ListUsers(ctx context.Context) ([]User, error) {
+	return s.repo.GetUsers(ctx)
+}
+
+func (s *Service) GetUser(ctx context.Context, id int64) (User, error) {
+	return s.repo.GetUser(ctx, id)
+}
+
+func (s *Service) AddUser(ctx context.Context, email, password string, role Role) (User, error) {
+	email, err := normaliseEmail(email)
+	if err != nil {
+		return User{}, err
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return User{}, err
+	}
+	hash, err := s.hash(password)
+	if err != nil {
+		return User{}, err
+	}
+	return s.repo.InsertUser(ctx, User{Email: email, PasswordHash: hash, Role: role})
+}
+
+// SetPassword replaces the password of a user.
+func (s *Service) SetPassword(ctx context.Context, id int64, password string) error {
+	hash, err := s.hash(password)
+	if err != nil {
+		return err
+	}
+	return s.repo.UpdatePassword(ctx, id, hash)
+}
+
+// SetRole changes the role of a user. The last admin keeps theirs.
+func (s *Service) SetRole(ctx context.Context, id int64, role Role) error {
+	if _, err := ParseRole(string(role)); err != nil {
+		return err
+	}
+	return s.repo.UpdateRole(ctx, id, role)
+}
+
+// RemoveUser deletes a user. The last admin cannot be removed.
+func (s *Service) RemoveUser(ctx context.Context, id int64) error {
+	return s.repo.DeleteUser(ctx, id)
+}
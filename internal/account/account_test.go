@@ -0,0 +1,138 @@
+package account_test
+
+import (
+	"context"
+	"testing"
+
+	"pfg/internal/account"
+	"pfg/internal/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newService() *account.Service {
+	return account.NewService(memory.NewRepository(), account.WithHashCost(bcrypt.MinCost))
+}
+
+func TestBootstrap(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+
+	created, err := service.Bootstrap(ctx, "Admin@Example.com", "first-secret")
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	// Later credentials are ignored once an account exists.
+	created, err = service.Bootstrap(ctx, "other@example.com", "second-secret")
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	users, err := service.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "admin@example.com", users[0].Email)
+	assert.Equal(t, account.RoleAdmin, users[0].Role)
+	assert.NotContains(t, users[0].PasswordHash, "first-secret")
+}
+
+func TestAuthenticate(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+
+	amy, err := service.AddUser(ctx, "amy@example.com", "correct horse", account.RoleViewer)
+	require.NoError(t, err)
+
+	u, err := service.Authenticate(ctx, " AMY@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, amy.ID, u.ID)
+
+	_, err = service.Authenticate(ctx, "amy@example.com", "wrong horse")
+	assert.ErrorIs(t, err, account.ErrInvalidCredentials)
+	_, err = service.Authenticate(ctx, "bob@example.com", "correct horse")
+	assert.ErrorIs(t, err, account.ErrInvalidCredentials)
+
+	require.NoError(t, service.SetPassword(ctx, amy.ID, "battery staple"))
+	_, err = service.Authenticate(ctx, "amy@example.com", "correct horse")
+	assert.ErrorIs(t, err, account.ErrInvalidCredentials)
+	_, err = service.Authenticate(ctx, "amy@example.com", "battery staple")
+	assert.NoError(t, err)
+}
+
+func TestAddUserValidation(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+
+	_, err := service.AddUser(ctx, "not an email", "long enough", account.RoleViewer)
+	assert.ErrorIs(t, err, account.ErrInvalid)
+	_, err = service.AddUser(ctx, "Amy <amy@example.com>", "long enough", account.RoleViewer)
+	assert.ErrorIs(t, err, account.ErrInvalid)
+	_, err = service.AddUser(ctx, "amy@example.com", "short", account.RoleViewer)
+	assert.ErrorIs(t, err, account.ErrInvalid)
+
+	_, err = service.AddUser(ctx, "amy@example.com", "long enough", "owner")
+	assert.ErrorIs(t, err, account.ErrInvalid)
+
+	_, err = service.AddUser(ctx, "amy@example.com", "long enough", account.RoleViewer)
+	require.NoError(t, err)
+	_, err = service.AddUser(ctx, "AMY@example.com", "long enough", account.RoleViewer)
+	assert.ErrorIs(t, err, account.ErrDuplicate)
+
+	assert.ErrorIs(t, service.SetPassword(ctx, 42, "long enough"), account.ErrNotFound)
+}
+
+func TestLastAdmin(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+
+	root, err := service.AddUser(ctx, "root@example.com", "long enough", account.RoleAdmin)
+	require.NoError(t, err)
+	amy, err := service.AddUser(ctx, "amy@example.com", "long enough", account.RoleViewer)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.SetRole(ctx, root.ID, account.RoleOperator), account.ErrLastAdmin)
+	assert.ErrorIs(t, service.RemoveUser(ctx, root.ID), account.ErrLastAdmin)
+
+	require.NoError(t, service.SetRole(ctx, amy.ID, account.RoleAdmin))
+	require.NoError(t, service.RemoveUser(ctx, root.ID))
+	assert.ErrorIs(t, service.SetRole(ctx, amy.ID, account.RoleViewer), account.ErrLastAdmin)
+
+	_, err = service.GetUser(ctx, root.ID)
+	assert.ErrorIs(t, err, account.ErrUnknownUser)
+}
+
+func TestRoles(t *testing.T) {
+	assert.Equal(t, []account.Role{account.RoleViewer, account.RoleOperator, account.RoleAdmin}, account.Roles())
+
+	role, err := account.ParseRole("operator")
+	require.NoError(t, err)
+	assert.Equal(t, account.RoleOperator, role)
+	_, err = account.ParseRole("root")
+	assert.ErrorIs(t, err, account.ErrInvalid)
+
+	assert.True(t, account.RoleViewer.Can(account.PermCalculate))
+	assert.True(t, account.RoleViewer.Can(account.PermViewCatalog))
+	assert.False(t, account.RoleViewer.Can(account.PermEditCatalog))
+	assert.True(t, account.RoleOperator.Can(account.PermEditCatalog))
+	assert.True(t, account.RoleOperator.Can(account.PermManageOrders))
+	assert.False(t, account.RoleOperator.Can(account.PermManageUsers))
+	assert.True(t, account.RoleAdmin.Can(account.PermManageUsers))
+	assert.Empty(t, account.Role("root").Permissions())
+}
+
+func TestSetRole(t *testing.T) {
+	service := newService()
+	ctx := context.Background()
+
+	amy, err := service.AddUser(ctx, "amy@example.com", "long enough", account.RoleViewer)
+	require.NoError(t, err)
+
+	require.NoError(t, service.SetRole(ctx, amy.ID, account.RoleOperator))
+	got, err := service.GetUser(ctx, amy.ID)
+	require.NoError(t, err)
+	assert.Equal(t, account.RoleOperator, got.Role)
+
+	assert.ErrorIs(t, service.SetRole(ctx, amy.ID, "owner"), account.ErrInvalid)
+	assert.ErrorIs(t, service.SetRole(ctx, 42, account.RoleViewer), account.ErrNotFound)
+}
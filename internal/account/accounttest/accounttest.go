@@ -0,0 +1,153 @@
+// Package accounttest checks that an account.Repository behaves the way
+// account.Service relies on, so every storage backend is held to the same
+// contract.
+package accounttest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"pfg/internal/account"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewRepository returns a repository without users. It is called once per
+// test.
+type NewRepository func(t *testing.T) account.Repository
+
+// RunRepositoryTests runs the conformance suite against repositories made
+// by newRepo.
+func RunRepositoryTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo account.Repository)
+	}{
+		{"Users", testUsers},
+		{"DuplicateEmail", testDuplicateEmail},
+		{"NotFound", testNotFound},
+		{"LastAdmin", testLastAdmin},
+		{"LastAdminDemoted", testLastAdminDemoted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+// Users are listed by email and keep what they were stored with.
+func testUsers(t *testing.T, repo account.Repository) {
+	ctx := context.Background()
+
+	users, err := repo.GetUsers(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	zoe, err := repo.InsertUser(ctx, account.User{Email: "zoe@example.com", PasswordHash: "hash-1", Role: account.RoleAdmin})
+	require.NoError(t, err)
+	assert.NotZero(t, zoe.ID)
+	assert.False(t, zoe.CreatedAt.IsZero())
+	amy, err := repo.InsertUser(ctx, account.User{Email: "amy@example.com", PasswordHash: "hash-2", Role: account.RoleViewer})
+	require.NoError(t, err)
+	assert.NotEqual(t, zoe.ID, amy.ID)
+
+	users, err = repo.GetUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "amy@example.com", users[0].Email)
+	assert.Equal(t, "zoe@example.com", users[1].Email)
+	assert.Equal(t, account.RoleViewer, users[0].Role)
+	assert.Equal(t, account.RoleAdmin, users[1].Role)
+
+	require.NoError(t, repo.UpdatePassword(ctx, amy.ID, "hash-3"))
+	require.NoError(t, repo.UpdateRole(ctx, amy.ID, account.RoleOperator))
+	got, err := repo.GetUserByEmail(ctx, "amy@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, amy.ID, got.ID)
+	assert.Equal(t, "hash-3", got.PasswordHash)
+	assert.Equal(t, account.RoleOperator, got.Role)
+
+	require.NoError(t, repo.UpdateRole(ctx, amy.ID, account.RoleAdmin))
+	require.NoError(t, repo.UpdateRole(ctx, zoe.ID, account.RoleViewer))
+	require.NoError(t, repo.DeleteUser(ctx, zoe.ID))
+	_, err = repo.GetUser(ctx, zoe.ID)
+	assert.ErrorIs(t, err, account.ErrUnknownUser)
+}
+
+func testDuplicateEmail(t *testing.T, repo account.Repository) {
+	ctx := context.Background()
+	_, err := repo.InsertUser(ctx, account.User{Email: "amy@example.com", PasswordHash: "hash"})
+	require.NoError(t, err)
+	_, err = repo.InsertUser(ctx, account.User{Email: "amy@example.com", PasswordHash: "other"})
+	assert.ErrorIs(t, err, account.ErrDuplicateUser)
+}
+
+func testNotFound(t *testing.T, repo account.Repository) {
+	ctx := context.Background()
+	_, err := repo.GetUser(ctx, 42)
+	assert.ErrorIs(t, err, account.ErrUnknownUser)
+	_, err = repo.GetUserByEmail(ctx, "nobody@example.com")
+	assert.ErrorIs(t, err, account.ErrUnknownUser)
+	assert.ErrorIs(t, repo.UpdatePassword(ctx, 42, "hash"), account.ErrUnknownUser)
+	assert.ErrorIs(t, repo.UpdateRole(ctx, 42, account.RoleViewer), account.ErrUnknownUser)
+	assert.ErrorIs(t, repo.DeleteUser(ctx, 42), account.ErrUnknownUser)
+}
+
+// Admins removing each other at the same time leave exactly one of them.
+func testLastAdmin(t *testing.T, repo account.Repository) {
+	raceAdmins(t, repo, repo.DeleteUser)
+}
+
+// Admins demoting each other at the same time leave exactly one of them.
+func testLastAdminDemoted(t *testing.T, repo account.Repository) {
+	raceAdmins(t, repo, func(ctx context.Context, id int64) error {
+		return repo.UpdateRole(ctx, id, account.RoleViewer)
+	})
+}
+
+// raceAdmins adds a few admins, has each one take the admin role away from
+// itself at once with change, and checks that exactly one is refused.
+func raceAdmins(t *testing.T, repo account.Repository, change func(ctx context.Context, id int64) error) {
+	ctx := context.Background()
+	const admins = 5
+	ids := make([]int64, admins)
+	for i := range ids {
+		u, err := repo.InsertUser(ctx, account.User{Email: fmt.Sprintf("admin%d@example.com", i), PasswordHash: "hash", Role: account.RoleAdmin})
+		require.NoError(t, err)
+		ids[i] = u.ID
+	}
+
+	errs := make([]error, admins)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = change(ctx, id)
+		}()
+	}
+	wg.Wait()
+
+	refused := 0
+	for _, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, account.ErrLastAdmin)
+			refused++
+		}
+	}
+	assert.Equal(t, 1, refused)
+
+	users, err := repo.GetUsers(ctx)
+	require.NoError(t, err)
+	left := 0
+	for _, u := range users {
+		if u.Role == account.RoleAdmin {
+			left++
+		}
+	}
+	assert.Equal(t, 1, left)
+}
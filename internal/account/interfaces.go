@@ -0,0 +1,29 @@
+package account
+
+import "context"
+
+// Repository stores user accounts. Emails are stored as given; the Service
+// normalises them first.
+type Repository interface {
+	// GetUsers returns every user, ordered by email.
+	GetUsers(ctx context.Context) ([]User, error)
+	// GetUser returns the user with the given ID, or ErrUnknownUser.
+	GetUser(ctx context.Context, id int64) (User, error)
+	// GetUserByEmail returns the user with the given email, or
+	// ErrUnknownUser.
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	// InsertUser stores u and returns it with its ID and CreatedAt set. It
+	// fails with ErrDuplicateUser if the email is taken.
+	InsertUser(ctx context.Context, u User) (User, error)
+	// UpdatePassword replaces the password hash of a user, or fails with
+	// ErrUnknownUser.
+	UpdatePassword(ctx context.Context, id int64, hash string) error
+	// UpdateRole changes the role of a user, or fails with ErrUnknownUser.
+	// Like DeleteUser, it fails with ErrLastAdmin rather than demote the only
+	// admin, checking and updating atomically.
+	UpdateRole(ctx context.Context, id int64, role Role) error
+	// DeleteUser removes a user, or fails with ErrUnknownUser. It fails with
+	// ErrLastAdmin rather than remove the only admin, checking and deleting
+	// atomically so concurrent removals cannot leave no admin.
+	DeleteUser(ctx context.Context, id int64) error
+}
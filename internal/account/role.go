@@ -0,0 +1,76 @@
+package account
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Permission is something a signed-in user may do. Tokens carry the
+// permissions of their user's role, and each route declares the one it
+// needs.
+type Permission string
+
+const (
+	PermCalculate    Permission = "calculate"
+	PermViewCatalog  Permission = "view_catalog"
+	PermEditCatalog  Permission = "edit_catalog"
+	PermManageOrders Permission = "manage_orders"
+	PermManageUsers  Permission = "manage_users"
+)
+
+// Role decides the permissions of a user.
+type Role string
+
+const (
+	// RoleViewer may calculate packs and look at catalogs.
+	RoleViewer Role = "viewer"
+	// RoleOperator may also change catalogs and stock and work on orders.
+	RoleOperator Role = "operator"
+	// RoleAdmin may do everything, including managing user accounts.
+	RoleAdmin Role = "admin"
+)
+
+// roles lists the roles from fewest permissions to most.
+var roles = []struct {
+	role        Role
+	permissions []Permission
+}{
+	{RoleViewer, []Permission{PermCalculate, PermViewCatalog}},
+	{RoleOperator, []Permission{PermCalculate, PermViewCatalog, PermEditCatalog, PermManageOrders}},
+	{RoleAdmin, []Permission{PermCalculate, PermViewCatalog, PermEditCatalog, PermManageOrders, PermManageUsers}},
+}
+
+// Roles returns every role, from fewest permissions to most.
+func Roles() []Role {
+	all := make([]Role, len(roles))
+	for i, r := range roles {
+		all[i] = r.role
+	}
+	return all
+}
+
+// ParseRole returns the role called name.
+func ParseRole(name string) (Role, error) {
+	for _, r := range roles {
+		if string(r.role) == name {
+			return r.role, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown role %q", ErrInvalid, name)
+}
+
+// Permissions returns the permissions the role grants, none for an unknown
+// role.
+func (r Role) Permissions() []Permission {
+	for _, known := range roles {
+		if known.role == r {
+			return slices.Clone(known.permissions)
+		}
+	}
+	return nil
+}
+
+// Can reports whether the role grants perm.
+func (r Role) Can(perm Permission) bool {
+	return slices.Contains(r.Permissions(), perm)
+}
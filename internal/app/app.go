@@ -3,22 +3,40 @@ package app
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 
+	"pfg/infra/atlas"
+	"pfg/internal/account"
 	"pfg/internal/config"
 	"pfg/internal/db"
 	"pfg/internal/handler"
 	"pfg/internal/html"
 	"pfg/internal/jwt"
+	"pfg/internal/lifecycle"
+	"pfg/internal/memory"
+	"pfg/internal/migrate"
 	"pfg/internal/pack"
 	"pfg/internal/server"
+	"pfg/internal/sqlite"
 
 	"go.uber.org/zap"
 )
 
+// repository is what a storage backend has to provide.
+type repository interface {
+	pack.Repository
+	lifecycle.Repository
+	account.Repository
+}
+
+// demoPackSizes are the pack sizes in-memory storage starts with, matching
+// infra/atlas/seed.sql.
+var demoPackSizes = []pack.PackSize{{Size: 250}, {Size: 500}, {Size: 1000}, {Size: 5000}}
+
 type App struct {
 	cfg     *config.Config
-	dbConn  db.Conn
+	storage io.Closer
 	httpSrv *http.Server
 	logger  *zap.Logger
 }
@@ -29,17 +47,56 @@ func New(cfg *config.Config, logger *zap.Logger) (*App, error) {
 	jwt.InitTokenAuth(cfg.JWTSecret)
 	logger.Info("JWT auth initialized")
 
-	conn, err := db.Connect(cfg.GetPostgresURL())
-	if err != nil {
-		logger.Error("Failed to connect to database", zap.Error(err))
-		return nil, err
+	var (
+		storage io.Closer
+		repo    repository
+	)
+	switch cfg.Storage {
+	case config.StorageMemory:
+		repo = memory.NewRepository(demoPackSizes...)
+		logger.Warn("Using in-memory storage, all data is lost on shutdown")
+	case config.StorageSQLite:
+		lite, err := sqlite.Open(cfg.SQLitePath)
+		if err != nil {
+			logger.Error("Failed to open SQLite database", zap.String("path", cfg.SQLitePath), zap.Error(err))
+			return nil, err
+		}
+		logger.Info("SQLite database opened", zap.String("path", cfg.SQLitePath))
+		storage, repo = lite, lite
+	default:
+		conn, err := db.Connect(cfg.GetPostgresURL())
+		if err != nil {
+			logger.Error("Failed to connect to database", zap.Error(err))
+			return nil, err
+		}
+		logger.Info("Database connection established")
+		if cfg.AutoMigrate {
+			if err := migrateDatabase(context.Background(), conn, logger); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		storage, repo = conn, db.NewRepository(conn)
 	}
-	logger.Info("Database connection established")
 
-	repo := db.NewRepository(conn)
-	service := pack.NewService(repo)
+	service := pack.NewService(repo, pack.WithLimits(pack.Limits{
+		MaxQuantity:   cfg.CalcMaxQuantity,
+		MaxWork:       cfg.CalcMaxWork,
+		MaxOrderLines: cfg.CalcMaxOrderLines,
+		MaxPackSizes:  cfg.CatalogMaxSizes,
+	}))
+
+	orders := lifecycle.NewService(service, repo)
+
+	accounts := account.NewService(repo)
+	if err := bootstrapAdmin(context.Background(), accounts, cfg, logger); err != nil {
+		if storage != nil {
+			storage.Close()
+		}
+		return nil, err
+	}
 
-	jsonHandler := handler.NewHandler(service, logger)
+	jsonHandler := handler.NewHandler(service, orders, accounts, logger)
 
 	tmpls, err := html.ParseTemplates()
 	if err != nil {
@@ -51,14 +108,14 @@ func New(cfg *config.Config, logger *zap.Logger) (*App, error) {
 		fmt.Println("Loaded template:", tmpl.Name())
 	}
 
-	htmlHandler := html.NewHTMLHandler(service, tmpls, cfg, logger)
+	htmlHandler := html.NewHTMLHandler(service, orders, accounts, tmpls, cfg, logger)
 
 	router := server.NewRouter(jsonHandler, htmlHandler, logger)
 
 	app := &App{
-		cfg:    cfg,
-		dbConn: conn,
-		logger: logger,
+		cfg:     cfg,
+		storage: storage,
+		logger:  logger,
 		httpSrv: &http.Server{
 			Addr:    ":" + cfg.Port,
 			Handler: router,
@@ -69,6 +126,56 @@ func New(cfg *config.Config, logger *zap.Logger) (*App, error) {
 	return app, nil
 }
 
+// bootstrapAdmin creates the first admin from the configured credentials
+// when there are no accounts yet.
+func bootstrapAdmin(ctx context.Context, accounts *account.Service, cfg *config.Config, logger *zap.Logger) error {
+	if cfg.AdminEmail == "" {
+		users, err := accounts.ListUsers(ctx)
+		if err != nil {
+			logger.Error("Failed to load user accounts", zap.Error(err))
+			return err
+		}
+		if len(users) == 0 {
+			logger.Warn("There are no user accounts and no ADMIN_EMAIL to create one, nobody can log in")
+		}
+		return nil
+	}
+
+	created, err := accounts.Bootstrap(ctx, cfg.AdminEmail, cfg.AdminPassword)
+	if err != nil {
+		logger.Error("Failed to create the first admin account", zap.String("email", cfg.AdminEmail), zap.Error(err))
+		return err
+	}
+	if created {
+		logger.Info("First admin account created", zap.String("email", cfg.AdminEmail))
+	}
+	return nil
+}
+
+// migrateDatabase applies the pending embedded migrations to the database.
+func migrateDatabase(ctx context.Context, conn db.Conn, logger *zap.Logger) error {
+	migrator, err := migrate.New(conn.Pool(), atlas.Migrations)
+	if err != nil {
+		logger.Error("Failed to load migrations", zap.Error(err))
+		return err
+	}
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		logger.Info("Migration applied", zap.String("version", m.Version), zap.String("description", m.Description))
+	}
+	if err != nil {
+		logger.Error("Failed to migrate database", zap.Error(err))
+		return err
+	}
+	logger.Info("Database schema is up to date", zap.Int("applied", len(applied)))
+	return nil
+}
+
+// Handler returns the router serving the API and the HTML pages.
+func (a *App) Handler() http.Handler {
+	return a.httpSrv.Handler
+}
+
 func (a *App) Start() error {
 	a.logger.Info("Starting HTTP server", zap.String("addr", a.httpSrv.Addr))
 	return a.httpSrv.ListenAndServe()
@@ -82,10 +189,12 @@ func (a *App) Shutdown(ctx context.Context) error {
 		return err
 	}
 
-	a.logger.Info("Closing database connection")
-	if err := a.dbConn.Close(); err != nil {
-		a.logger.Error("Failed to close DB", zap.Error(err))
-		return err
+	if a.storage != nil {
+		a.logger.Info("Closing database connection")
+		if err := a.storage.Close(); err != nil {
+			a.logger.Error("Failed to close DB", zap.Error(err))
+			return err
+		}
 	}
 
 	a.logger.Info("Shutdown complete")
@@ -0,0 +1,727 @@
+package app_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"pfg/internal/account"
+	"pfg/internal/app"
+	"pfg/internal/config"
+	"pfg/internal/jwt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newServer starts the whole application on in-memory storage.
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg := &config.Config{
+		Storage:       config.StorageMemory,
+		JWTSecret:     "test-secret",
+		AdminEmail:    "admin@example.com",
+		AdminPassword: "admin-secret",
+	}
+	a, err := app.New(cfg, zap.NewNop())
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(a.Handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// api sends a JSON request with an admin token and decodes the response
+// into out, if given.
+func api(t *testing.T, srv *httptest.Server, method, path string, body, out any) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, srv.URL+path, reader)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+jwt.GenerateDevToken())
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if out != nil && resp.StatusCode < 300 {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+	}
+	return resp.StatusCode
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	srv := newServer(t)
+
+	resp, err := http.Get(srv.URL + "/api/packs")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestAPIPermissions(t *testing.T) {
+	srv := newServer(t)
+
+	do := func(role account.Role, method, path, body string) int {
+		t.Helper()
+		_, token, err := jwt.Auth.Encode(map[string]any{
+			"email":       string(role) + "@example.com",
+			"role":        role,
+			"permissions": role.Permissions(),
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for _, c := range []struct {
+		role               account.Role
+		method, path, body string
+		want               int
+	}{
+		{account.RoleViewer, http.MethodGet, "/api/packs", "", http.StatusOK},
+		{account.RoleViewer, http.MethodPost, "/api/calculate", `{"quantity": 251}`, http.StatusOK},
+		{account.RoleViewer, http.MethodPost, "/api/calculate", `{"quantity": 251, "confirm": true}`, http.StatusForbidden},
+		{account.RoleViewer, http.MethodPost, "/api/packs", `{"size": 42}`, http.StatusForbidden},
+		{account.RoleViewer, http.MethodDelete, "/api/packs?size=250", "", http.StatusForbidden},
+		{account.RoleViewer, http.MethodGet, "/api/orders", "", http.StatusForbidden},
+		{account.RoleOperator, http.MethodPost, "/api/packs", `{"size": 42}`, http.StatusNoContent},
+		{account.RoleOperator, http.MethodPost, "/api/calculate", `{"quantity": 251, "confirm": true}`, http.StatusOK},
+		{account.RoleOperator, http.MethodGet, "/api/orders", "", http.StatusOK},
+		{account.RoleOperator, http.MethodGet, "/api/users", "", http.StatusForbidden},
+		{account.RoleAdmin, http.MethodGet, "/api/users", "", http.StatusOK},
+		{account.Role("root"), http.MethodGet, "/api/packs", "", http.StatusForbidden},
+	} {
+		assert.Equal(t, c.want, do(c.role, c.method, c.path, c.body), "%s %s %s", c.role, c.method, c.path)
+	}
+}
+
+func TestAPIPackSizesAndCalculate(t *testing.T) {
+	srv := newServer(t)
+
+	var sizes []int
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/packs", nil, &sizes))
+	assert.Equal(t, []int{250, 500, 1000, 5000}, sizes)
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodGet, "/api/packs?catalog=nope", nil, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodGet, "/api/v2/packs?catalog=nope", nil, nil))
+
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPost, "/api/packs", map[string]any{"size": 2000, "unitCost": 300}, nil))
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodDelete, "/api/packs?size=5000", nil, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodDelete, "/api/packs?size=5000", nil, nil))
+	assert.Equal(t, http.StatusConflict, api(t, srv, http.MethodPost, "/api/packs", map[string]any{"size": 2000}, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPut, "/api/packs/cost", map[string]any{"size": 42, "unitCost": 1}, nil))
+	assert.Equal(t, http.StatusConflict, api(t, srv, http.MethodPost, "/api/catalogs", map[string]any{"id": "default"}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodDelete, "/api/catalogs?id=default", nil, nil))
+
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/packs", nil, &sizes))
+	assert.Equal(t, []int{250, 500, 1000, 2000}, sizes)
+
+	var result struct {
+		Fulfilled      int `json:"fulfilled"`
+		TotalPacks     int `json:"totalPacks"`
+		CatalogVersion int `json:"catalogVersion"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 12001}, &result))
+	assert.Equal(t, 12250, result.Fulfilled)
+	assert.Equal(t, 7, result.TotalPacks)
+	assert.Equal(t, 3, result.CatalogVersion)
+
+	// The first version still has the 5000 pack.
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 12001, "version": 1}, &result))
+	assert.Equal(t, 5, result.TotalPacks)
+
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 1, "catalog": "nope"}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 0}, nil))
+}
+
+func TestAPIPackMetadata(t *testing.T) {
+	srv := newServer(t)
+
+	box := map[string]any{"size": 2000, "unitCost": 300, "label": "Large box", "sku": "BOX-2000", "lengthMm": 600, "widthMm": 400, "heightMm": 400, "tareWeightG": 900}
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPost, "/api/v2/packs", box, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPost, "/api/v2/packs", map[string]any{"size": 3000, "tareWeightG": -1}, nil))
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, "/api/v2/packs/details", map[string]any{"size": 250, "label": "Small box", "sku": "BOX-250"}, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPut, "/api/v2/packs/details", map[string]any{"size": 42, "label": "Nope"}, nil))
+
+	// Version 1 still lists bare sizes.
+	var sizes []int
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/packs", nil, &sizes))
+	assert.Equal(t, []int{250, 500, 1000, 2000, 5000}, sizes)
+
+	var packs []map[string]any
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/v2/packs", nil, &packs))
+	require.Len(t, packs, 5)
+	assert.Equal(t, map[string]any{"size": 250.0, "unitCost": 0.0, "label": "Small box", "sku": "BOX-250", "lengthMm": 0.0, "widthMm": 0.0, "heightMm": 0.0, "tareWeightG": 0.0, "active": true, "availableFrom": nil, "retiresAt": nil}, packs[0])
+	assert.Equal(t, "Large box", packs[3]["label"])
+	assert.Equal(t, 900.0, packs[3]["tareWeightG"])
+
+	var result struct {
+		Packs []map[string]any `json:"packs"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 2000}, &result))
+	require.Len(t, result.Packs, 1)
+	assert.Equal(t, "BOX-2000", result.Packs[0]["sku"])
+	assert.Equal(t, 600.0, result.Packs[0]["lengthMm"])
+}
+
+func TestAPIDeactivatePackSize(t *testing.T) {
+	srv := newServer(t)
+
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPost, "/api/packs/deactivate", map[string]any{"size": 250}, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPost, "/api/packs/deactivate", map[string]any{"size": 42}, nil))
+
+	var sizes []int
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/packs", nil, &sizes))
+	assert.Equal(t, []int{500, 1000, 5000}, sizes)
+
+	var packs []struct {
+		Size   int  `json:"size"`
+		Active bool `json:"active"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/v2/packs", nil, &packs))
+	require.Len(t, packs, 4)
+	assert.Equal(t, 250, packs[0].Size)
+	assert.False(t, packs[0].Active)
+	assert.True(t, packs[1].Active)
+
+	var result struct {
+		Fulfilled int `json:"fulfilled"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 1}, &result))
+	assert.Equal(t, 500, result.Fulfilled)
+
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPost, "/api/packs/activate", map[string]any{"size": 250}, nil))
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 1}, &result))
+	assert.Equal(t, 250, result.Fulfilled)
+}
+
+func TestAPIReplacePackSizes(t *testing.T) {
+	srv := newServer(t)
+
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, "/api/packs/cost", map[string]any{"size": 500, "unitCost": 150}, nil))
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, "/api/packs", map[string]any{"sizes": []int{300, 500, 900}}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPut, "/api/packs", map[string]any{"sizes": []int{300, 300}}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPut, "/api/packs", map[string]any{"sizes": []int{-1}}, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPut, "/api/packs", map[string]any{"catalog": "missing", "sizes": []int{300}}, nil))
+
+	var packs []struct {
+		Size     int   `json:"size"`
+		UnitCost int64 `json:"unitCost"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/v2/packs", nil, &packs))
+	require.Len(t, packs, 3)
+	assert.Equal(t, 300, packs[0].Size)
+	assert.Equal(t, int64(150), packs[1].UnitCost)
+
+	var versions []struct {
+		Change string `json:"change"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/catalogs/versions", nil, &versions))
+	assert.Equal(t, "replaced pack sizes with 300, 500, 900", versions[0].Change)
+}
+
+// send makes a request with an admin token and a raw body, returning the
+// status and response body.
+func send(t *testing.T, srv *httptest.Server, method, path, contentType, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+jwt.GenerateDevToken())
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	return resp.StatusCode, readBody(t, resp)
+}
+
+func TestAPICatalogImportExport(t *testing.T) {
+	srv := newServer(t)
+
+	status, body := send(t, srv, http.MethodGet, "/api/catalogs/export?format=csv", "", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `size,unit_cost_cents,label,sku,length_mm,width_mm,height_mm,tare_weight_g,active,available_from,retires_at
+250,0,,,0,0,0,0,true,,
+500,0,,,0,0,0,0,true,,
+1000,0,,,0,0,0,0,true,,
+5000,0,,,0,0,0,0,true,,
+`, body)
+
+	var exported []map[string]any
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/catalogs/export", nil, &exported))
+	assert.Len(t, exported, 4)
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodGet, "/api/catalogs/export?catalog=missing", nil, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodGet, "/api/catalogs/export?format=xlsx", nil, nil))
+
+	file := "size,unit_cost_cents,label\n250,120,Small box\n500,0,\n2000,300,\n"
+	var diff struct {
+		DryRun  bool `json:"dryRun"`
+		Applied bool `json:"applied"`
+		Added   []struct {
+			Size int `json:"size"`
+		} `json:"added"`
+		Removed []struct {
+			Size int `json:"size"`
+		} `json:"removed"`
+		Changed []struct {
+			Before struct {
+				UnitCost int64 `json:"unitCost"`
+			} `json:"before"`
+			After struct {
+				UnitCost int64  `json:"unitCost"`
+				Label    string `json:"label"`
+			} `json:"after"`
+		} `json:"changed"`
+		Unchanged []struct {
+			Size int `json:"size"`
+		} `json:"unchanged"`
+	}
+	status, body = send(t, srv, http.MethodPost, "/api/catalogs/import?dryRun=true", "text/csv", file)
+	assert.Equal(t, http.StatusOK, status)
+	require.NoError(t, json.Unmarshal([]byte(body), &diff))
+	assert.True(t, diff.DryRun)
+	assert.False(t, diff.Applied)
+	require.Len(t, diff.Added, 1)
+	assert.Equal(t, 2000, diff.Added[0].Size)
+	assert.Len(t, diff.Removed, 2)
+	require.Len(t, diff.Changed, 1)
+	assert.Equal(t, int64(120), diff.Changed[0].After.UnitCost)
+	assert.Equal(t, "Small box", diff.Changed[0].After.Label)
+	assert.Len(t, diff.Unchanged, 1)
+
+	var sizes []int
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/packs", nil, &sizes))
+	assert.Equal(t, []int{250, 500, 1000, 5000}, sizes)
+
+	status, body = send(t, srv, http.MethodPost, "/api/catalogs/import", "text/csv", file)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"applied":true`)
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/packs", nil, &sizes))
+	assert.Equal(t, []int{250, 500, 2000}, sizes)
+
+	status, _ = send(t, srv, http.MethodPost, "/api/catalogs/import?format=json", "application/json", `[{"size": 250}, {"size": 250}]`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, body = send(t, srv, http.MethodPost, "/api/catalogs/import", "text/csv", "size\nlarge\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, `line 2: invalid size "large"`)
+}
+
+func TestAPIBulkCalculate(t *testing.T) {
+	srv := newServer(t)
+
+	status, body := send(t, srv, http.MethodPost, "/api/orders/bulk", "text/csv", "order_id,quantity\nA-1,251\nA-2,12001\nA-3,none\n")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `order_id,quantity,fulfilled,overage,pack_count,breakdown,error
+A-1,251,500,249,1,1x500,
+A-2,12001,12250,249,5,2x5000 + 2x1000 + 1x250,
+A-3,none,,,,,"invalid quantity ""none"""
+`, body)
+
+	status, body = send(t, srv, http.MethodPost, "/api/orders/bulk?catalog=missing", "text/csv", "order_id,quantity\nA-1,251\n")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "A-1,251,,,,,")
+
+	status, _ = send(t, srv, http.MethodPost, "/api/orders/bulk", "text/csv", "order,qty\nA-1,251\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = send(t, srv, http.MethodPost, "/api/orders/bulk?strategy=nope", "text/csv", "order_id,quantity\nA-1,251\n")
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestAPISchedulePackSize(t *testing.T) {
+	srv := newServer(t)
+	nextYear := time.Now().UTC().AddDate(1, 0, 0).Truncate(time.Second)
+
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPost, "/api/v2/packs", map[string]any{"size": 100, "availableFrom": nextYear}, nil))
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, "/api/v2/packs/schedule", map[string]any{"size": 5000, "retiresAt": nextYear}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPut, "/api/v2/packs/schedule", map[string]any{"size": 500, "retiresAt": "2020-01-01T00:00:00Z"}, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPut, "/api/v2/packs/schedule", map[string]any{"size": 42, "retiresAt": nextYear}, nil))
+
+	// The 100 pack is not available yet.
+	var sizes []int
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/packs", nil, &sizes))
+	assert.Equal(t, []int{250, 500, 1000, 5000}, sizes)
+
+	var packs []struct {
+		Size          int        `json:"size"`
+		AvailableFrom *time.Time `json:"availableFrom"`
+		RetiresAt     *time.Time `json:"retiresAt"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/v2/packs", nil, &packs))
+	require.Len(t, packs, 5)
+	assert.Equal(t, 100, packs[0].Size)
+	require.NotNil(t, packs[0].AvailableFrom)
+	assert.True(t, nextYear.Equal(*packs[0].AvailableFrom))
+	assert.Nil(t, packs[0].RetiresAt)
+
+	var upcoming []struct {
+		Size   int    `json:"size"`
+		Change string `json:"change"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/v2/packs/upcoming", nil, &upcoming))
+	assert.ElementsMatch(t, []struct {
+		Size   int    `json:"size"`
+		Change string `json:"change"`
+	}{{100, "available"}, {5000, "retire"}}, upcoming)
+
+	var result struct {
+		Fulfilled int `json:"fulfilled"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 100}, &result))
+	assert.Equal(t, 250, result.Fulfilled)
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 100, "asOf": nextYear.Format(time.RFC3339)}, &result))
+	assert.Equal(t, 100, result.Fulfilled)
+}
+
+func TestAPIOrderLifecycle(t *testing.T) {
+	srv := newServer(t)
+
+	for _, size := range []int{250, 500, 1000, 5000} {
+		assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, "/api/inventory", map[string]any{"size": size, "onHand": 0}, nil))
+	}
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, "/api/inventory", map[string]any{"size": 500, "onHand": 1}, nil))
+
+	type order struct {
+		ID          int64  `json:"id"`
+		State       string `json:"state"`
+		RequestedBy string `json:"requestedBy"`
+	}
+	var quote order
+	assert.Equal(t, http.StatusCreated, api(t, srv, http.MethodPost, "/api/orders", map[string]any{"quantity": 500}, &quote))
+	assert.Equal(t, order{ID: 1, State: "quoted", RequestedBy: "admin"}, quote)
+
+	var confirmed struct {
+		OrderID int64 `json:"orderId"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 500, "confirm": true}, &confirmed))
+	assert.Equal(t, int64(2), confirmed.OrderID)
+
+	// Direct confirmations are recorded in the order's history.
+	var direct struct {
+		History []struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+			By   string `json:"by"`
+		} `json:"history"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/orders/2", nil, &direct))
+	require.Len(t, direct.History, 1)
+	assert.Equal(t, "", direct.History[0].From)
+	assert.Equal(t, "confirmed", direct.History[0].To)
+	assert.Equal(t, "admin", direct.History[0].By)
+	assert.Equal(t, http.StatusUnprocessableEntity, api(t, srv, http.MethodPost, "/api/calculate", map[string]any{"quantity": 500, "confirm": true}, nil))
+
+	// The quote's pack went to the second order.
+	assert.Equal(t, http.StatusConflict, api(t, srv, http.MethodPost, "/api/orders/1/transitions", map[string]any{"state": "confirmed"}, nil))
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, "/api/inventory", map[string]any{"size": 500, "onHand": 1}, nil))
+
+	var advanced order
+	assert.Equal(t, http.StatusConflict, api(t, srv, http.MethodPost, "/api/orders/1/transitions", map[string]any{"state": "shipped"}, nil))
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodPost, "/api/orders/1/transitions", map[string]any{"state": "confirmed"}, &advanced))
+	assert.Equal(t, "confirmed", advanced.State)
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPost, "/api/orders/1/transitions", map[string]any{"state": "lost"}, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPost, "/api/orders/9/transitions", map[string]any{"state": "confirmed"}, nil))
+
+	var orders []order
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/orders", nil, &orders))
+	assert.Equal(t, []order{{ID: 2, State: "confirmed", RequestedBy: "admin"}, {ID: 1, State: "confirmed", RequestedBy: "admin"}}, orders)
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodGet, "/api/orders/3", nil, nil))
+}
+
+func TestAPIUsers(t *testing.T) {
+	srv := newServer(t)
+
+	var users []struct {
+		ID          int64    `json:"id"`
+		Email       string   `json:"email"`
+		Role        string   `json:"role"`
+		Permissions []string `json:"permissions"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/users", nil, &users))
+	require.Len(t, users, 1)
+	assert.Equal(t, "admin@example.com", users[0].Email)
+	assert.Equal(t, "admin", users[0].Role)
+	assert.Contains(t, users[0].Permissions, "manage_users")
+	admin := users[0].ID
+
+	var added struct {
+		ID    int64  `json:"id"`
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+	assert.Equal(t, http.StatusCreated, api(t, srv, http.MethodPost, "/api/users", map[string]any{"email": "Amy@Example.com", "password": "amy-secret"}, &added))
+	assert.Equal(t, "amy@example.com", added.Email)
+	assert.Equal(t, "viewer", added.Role)
+	assert.Equal(t, http.StatusConflict, api(t, srv, http.MethodPost, "/api/users", map[string]any{"email": "amy@example.com", "password": "amy-secret"}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPost, "/api/users", map[string]any{"email": "bob@example.com", "password": "short"}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPost, "/api/users", map[string]any{"email": "bob@example.com", "password": "bob-secret", "role": "owner"}, nil))
+
+	path := fmt.Sprintf("/api/users/%d", added.ID)
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodPut, path, map[string]any{"password": "new-amy-secret", "role": "admin"}, nil))
+	assert.Equal(t, http.StatusNotFound, api(t, srv, http.MethodPut, "/api/users/99", map[string]any{"role": "admin"}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPut, "/api/users/abc", map[string]any{"role": "admin"}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPut, path, map[string]any{"role": "owner"}, nil))
+
+	assert.Equal(t, http.StatusNoContent, api(t, srv, http.MethodDelete, fmt.Sprintf("/api/users/%d", admin), nil, nil))
+	// Amy is the only admin left.
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodPut, path, map[string]any{"role": "operator"}, nil))
+	assert.Equal(t, http.StatusBadRequest, api(t, srv, http.MethodDelete, path, nil, nil))
+
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/users", nil, &users))
+	require.Len(t, users, 1)
+	assert.Equal(t, "amy@example.com", users[0].Email)
+}
+
+func TestHTMLAdminPages(t *testing.T) {
+	srv := newServer(t)
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar}
+
+	get := func(path string) (int, string) {
+		resp, err := client.Get(srv.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	status, _ := get("/packs")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	resp, err := client.PostForm(srv.URL+"/login", url.Values{"email": {"admin@example.com"}, "password": {"admin-secret"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = client.PostForm(srv.URL+"/packs/add", url.Values{"size": {"42"}, "unit_cost": {"1.50"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	status, body := get("/packs")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "Size: 42")
+	assert.Contains(t, body, "added pack size 42 at 150 cents")
+	assert.Contains(t, body, "admin@example.com")
+
+	resp, err = client.PostForm(srv.URL+"/packs/details", url.Values{"size": {"42"}, "label": {"Tiny tin"}, "sku": {"TIN-42"}, "length_mm": {"80"}, "width_mm": {"80"}, "height_mm": {"40"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	status, body = get("/packs")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "Size: 42 (Tiny tin) &middot; SKU: TIN-42")
+	assert.Contains(t, body, "80 &times; 80 &times; 40 mm")
+
+	resp, err = client.PostForm(srv.URL+"/packs/details", url.Values{"size": {"42"}, "tare_weight_g": {"-5"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = client.PostForm(srv.URL+"/packs/deactivate", url.Values{"size": {"42"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	status, body = get("/packs")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<s>Size: 42</s> <strong>inactive</strong>")
+	assert.Contains(t, body, `action="/packs/activate"`)
+
+	resp, err = client.PostForm(srv.URL+"/packs/activate", url.Values{"size": {"42"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = client.PostForm(srv.URL+"/packs/schedule", url.Values{"size": {"42"}, "retires_at": {"2099-01-01T00:00"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	status, body = get("/packs")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "&middot; Retires 2099-01-01 00:00 UTC")
+	assert.Contains(t, body, "<td>2099-01-01 00:00</td>\n        <td>42</td>\n        <td>retires</td>")
+
+	status, body = get("/packs")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `name="sizes" value="42, 250, 500, 1000, 5000"`)
+
+	resp, err = client.PostForm(srv.URL+"/packs/replace", url.Values{"sizes": {"42, 250 500,1000,5000, 42"}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "pack size 42 is listed more than once")
+
+	resp, err = client.PostForm(srv.URL+"/packs/replace", url.Values{"sizes": {"42, 250 500,1000"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	status, body = get("/packs")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "replaced pack sizes with 42, 250, 500, 1000")
+	assert.NotContains(t, body, "Size: 5000")
+
+	status, body = get("/packs/export?format=csv")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, strings.HasPrefix(body, "size,unit_cost_cents,"))
+
+	var upload bytes.Buffer
+	mw := multipart.NewWriter(&upload)
+	fw, err := mw.CreateFormFile("file", "sizes.csv")
+	require.NoError(t, err)
+	io.WriteString(fw, "size,unit_cost_cents\n42,150\n250,0\n750,90\n")
+	require.NoError(t, mw.Close())
+	resp, err = client.Post(srv.URL+"/packs/import", mw.FormDataContentType(), &upload)
+	require.NoError(t, err)
+	body = readBody(t, resp)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Import preview: sizes.csv")
+	assert.Contains(t, body, "<tr><td>750</td><td>added</td><td>0.90</td></tr>")
+	assert.Contains(t, body, "<tr><td><s>500</s></td><td>removed</td><td>0.00</td></tr>")
+	assert.Contains(t, body, `action="/packs/import/apply"`)
+
+	resp, err = client.PostForm(srv.URL+"/packs/import/apply", url.Values{"format": {"csv"}, "data": {"size,unit_cost_cents\n42,150\n250,0\n750,90\n"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	status, body = get("/packs")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "imported 3 pack sizes: 1 added, 2 removed, 1 changed")
+	assert.Contains(t, body, "Size: 750")
+
+	resp, err = client.PostForm(srv.URL+"/packs/add", url.Values{"size": {"42"}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "pack size 42 already exists")
+
+	status, body = get("/orders/bulk")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `enctype="multipart/form-data"`)
+
+	upload.Reset()
+	mw = multipart.NewWriter(&upload)
+	mw.WriteField("strategy", "min-items")
+	fw, err = mw.CreateFormFile("file", "orders.csv")
+	require.NoError(t, err)
+	io.WriteString(fw, "order_id,quantity\nA-1,42\nA-2,800\n")
+	require.NoError(t, mw.Close())
+	resp, err = client.Post(srv.URL+"/orders/bulk", mw.FormDataContentType(), &upload)
+	require.NoError(t, err)
+	body = readBody(t, resp)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `attachment; filename="pack-results.csv"`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, "order_id,quantity,fulfilled,overage,pack_count,breakdown,error\nA-1,42,42,0,1,1x42,\nA-2,800,834,34,3,1x750 + 2x42,\n", body)
+
+	upload.Reset()
+	mw = multipart.NewWriter(&upload)
+	fw, err = mw.CreateFormFile("file", "orders.csv")
+	require.NoError(t, err)
+	io.WriteString(fw, "quantity\n42\n")
+	require.NoError(t, mw.Close())
+	resp, err = client.Post(srv.URL+"/orders/bulk", mw.FormDataContentType(), &upload)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "the CSV file has no order_id column")
+
+	resp, err = client.PostForm(srv.URL+"/users/add", url.Values{"email": {"clerk@example.com"}, "password": {"clerk-secret"}, "role": {"viewer"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	status, body = get("/users")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<td>clerk@example.com</td>\n        <td>viewer</td>")
+
+	resp, err = client.PostForm(srv.URL+"/users/delete", url.Values{"id": {"1"}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "the last admin cannot be removed or demoted")
+
+	// Viewers can sign in and look at catalogs but not change them.
+	clerkJar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	clerk := &http.Client{Jar: clerkJar}
+	resp, err = clerk.PostForm(srv.URL+"/login", url.Values{"email": {"clerk@example.com"}, "password": {"wrong-password"}})
+	require.NoError(t, err)
+	assert.Contains(t, readBody(t, resp), "Invalid credentials")
+	resp, err = clerk.PostForm(srv.URL+"/login", url.Values{"email": {"clerk@example.com"}, "password": {"clerk-secret"}})
+	require.NoError(t, err)
+	body = readBody(t, resp)
+	assert.Contains(t, body, "Logged in as clerk@example.com")
+	assert.Contains(t, body, "Available Pack Sizes")
+	assert.NotContains(t, body, `action="/packs/add"`)
+	assert.NotContains(t, body, `href="/users"`)
+	resp, err = clerk.Get(srv.URL + "/users")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "Your role does not allow access to this page.")
+	resp, err = clerk.PostForm(srv.URL+"/packs/add", url.Values{"size": {"7"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Promoted to operator, the clerk may change catalogs from the next
+	// login on.
+	resp, err = client.PostForm(srv.URL+"/users/role", url.Values{"id": {"2"}, "role": {"operator"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	resp, err = clerk.PostForm(srv.URL+"/login", url.Values{"email": {"clerk@example.com"}, "password": {"clerk-secret"}})
+	require.NoError(t, err)
+	assert.Contains(t, readBody(t, resp), `action="/packs/add"`)
+	resp, err = clerk.PostForm(srv.URL+"/packs/add", url.Values{"size": {"7"}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = clerk.Get(srv.URL + "/users")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, err = client.PostForm(srv.URL+"/calculate", url.Values{"quantity": {"42"}, "confirm": {"1"}})
+	require.NoError(t, err)
+	body = readBody(t, resp)
+	assert.Contains(t, body, "order #1")
+
+	// Orders confirmed from the page are requested by the logged in user.
+	var confirmed struct {
+		RequestedBy string `json:"requestedBy"`
+	}
+	assert.Equal(t, http.StatusOK, api(t, srv, http.MethodGet, "/api/orders/1", nil, &confirmed))
+	assert.Equal(t, "admin@example.com", confirmed.RequestedBy)
+
+	status, body = get("/orders")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, "<td>confirmed</td>")
+	assert.True(t, strings.Contains(body, `value="packed"`))
+
+	advance := func(id, state string) (int, string) {
+		resp, err := client.PostForm(srv.URL+"/orders/advance", url.Values{"id": {id}, "state": {state}})
+		require.NoError(t, err)
+		return resp.StatusCode, readBody(t, resp)
+	}
+	status, _ = advance("9", "packed")
+	assert.Equal(t, http.StatusNotFound, status)
+	status, body = advance("1", "quoted")
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Contains(t, body, "invalid order state transition")
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
@@ -2,41 +2,94 @@ package auth
 
 import (
 	"net/http"
+	"slices"
 	"strings"
 
+	"pfg/internal/account"
 	"pfg/internal/jwt"
+	"pfg/internal/pack"
 )
 
-func RequireAdminOnly(r *http.Request) bool {
-	isAdmin := false
+// claims returns the private claims of the request's token, taken from the
+// Authorization header or else the admin_token cookie. It is nil without a
+// valid token.
+func claims(r *http.Request) map[string]any {
+	tokenStr := ""
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		tokenStr = strings.TrimPrefix(authHeader, "Bearer ")
+	} else if cookie, err := r.Cookie("admin_token"); err == nil {
+		tokenStr = cookie.Value
+	}
+	if tokenStr == "" {
+		return nil
+	}
 
-	authHeader := r.Header.Get("Authorization")
-	if strings.HasPrefix(authHeader, "Bearer ") {
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		if tok, err := jwt.Auth.Decode(tokenStr); err == nil {
-			claims := tok.PrivateClaims()
-			isAdmin, _ = claims["isAdmin"].(bool)
-		}
+	tok, err := jwt.Auth.Decode(tokenStr)
+	if err != nil {
+		return nil
 	}
+	return tok.PrivateClaims()
+}
 
-	if !isAdmin {
-		if cookie, err := r.Cookie("admin_token"); err == nil {
-			if tok, err := jwt.Auth.Decode(cookie.Value); err == nil {
-				claims := tok.PrivateClaims()
-				isAdmin, _ = claims["isAdmin"].(bool)
+// Permissions returns the permissions granted by the request's token.
+func Permissions(r *http.Request) []account.Permission {
+	// Decoded tokens hold the claim as []any; tokens built in process, as
+	// []account.Permission.
+	switch perms := claims(r)["permissions"].(type) {
+	case []account.Permission:
+		return perms
+	case []any:
+		granted := make([]account.Permission, 0, len(perms))
+		for _, p := range perms {
+			if s, ok := p.(string); ok {
+				granted = append(granted, account.Permission(s))
 			}
 		}
+		return granted
 	}
+	return nil
+}
+
+// Allowed reports whether the request's token grants perm.
+func Allowed(r *http.Request, perm account.Permission) bool {
+	return slices.Contains(Permissions(r), perm)
+}
 
-	return isAdmin
+// Require lets through requests whose token grants perm. The rest are
+// answered by denied, or with 403 Forbidden if denied is nil.
+func Require(perm account.Permission, denied http.HandlerFunc) func(http.Handler) http.Handler {
+	if denied == nil {
+		denied = func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !Allowed(r, perm) {
+				denied(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-func RequireToken(next http.Handler) http.Handler {
+// WithActor records the user behind the request's token in its context, so
+// catalog changes made while serving it are attributed to them.
+func WithActor(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !RequireAdminOnly(r) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+		if actor := actorFromRequest(r); actor != "" {
+			r = r.WithContext(pack.ContextWithActor(r.Context(), actor))
 		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+func actorFromRequest(r *http.Request) string {
+	c := claims(r)
+	if email, ok := c["email"].(string); ok && email != "" {
+		return email
+	}
+	user, _ := c["user"].(string)
+	return user
+}
@@ -0,0 +1,222 @@
+// Package bulk calculates the packs for many orders at once. Orders are read
+// from a CSV file and the results written as another, one row at a time, so
+// files of any length pass through without being held in memory.
+package bulk
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"pfg/internal/pack"
+)
+
+// Filename is the name results are downloaded under.
+const Filename = "pack-results.csv"
+
+// inputColumns are the columns an orders file may have. The catalog column
+// is optional and overrides the catalog given to Run for its row.
+var inputColumns = []string{"order_id", "quantity", "catalog"}
+
+// resultColumns are the columns Run writes. Rows that could not be
+// calculated leave the results blank and say why under error.
+var resultColumns = []string{"order_id", "quantity", "fulfilled", "overage", "pack_count", "breakdown", "error"}
+
+// Summary counts the orders Run went through.
+type Summary struct {
+	Rows   int
+	Failed int
+	// Internal counts the failed rows that were not the order's doing. Their
+	// error column only says "internal error"; Cause keeps the first of
+	// their errors for the log.
+	Internal int
+	Cause    error
+}
+
+// Reader reads orders from a CSV file with a header row naming its columns,
+// in any order.
+type Reader struct {
+	cr    *csv.Reader
+	index map[string]int
+}
+
+// NewReader reads the header of an orders file, so that a file that is not
+// one can be rejected before any results are written.
+func NewReader(r io.Reader) (*Reader, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		// Spreadsheets tend to start their files with a byte order mark.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := false
+		for _, c := range inputColumns {
+			known = known || c == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		index[name] = i
+	}
+	for _, name := range inputColumns[:2] {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("the CSV file has no %s column", name)
+		}
+	}
+	return &Reader{cr: cr, index: index}, nil
+}
+
+// order is one row of an orders file. Quantity is kept as written so
+// rows that fail carry it over unchanged.
+type order struct {
+	id       string
+	quantity string
+	catalog  string
+}
+
+// next reads the next order, returning io.EOF after the last. Malformed
+// rows are returned as a *csv.ParseError, after which reading continues.
+func (r *Reader) next() (order, error) {
+	record, err := r.cr.Read()
+	if err != nil {
+		return order{}, err
+	}
+	field := func(name string) string {
+		if i, ok := r.index[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	return order{id: field("order_id"), quantity: field("quantity"), catalog: field("catalog")}, nil
+}
+
+// Run calculates every order read from r with service and writes a result
+// row for each to w. All calculations share one table cache, so orders
+// against the same pack sizes only fill the solver table once, and one
+// catalog cache, so each catalog and its stock are read once per run. opts
+// apply to every order.
+func Run(ctx context.Context, service *pack.Service, r *Reader, w io.Writer, opts ...pack.CalcOption) (Summary, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(resultColumns); err != nil {
+		return Summary{}, err
+	}
+
+	opts = append(opts[:len(opts):len(opts)], pack.WithTableCache(pack.NewTableCache()), pack.WithCatalogCache(pack.NewCatalogCache()))
+	var sum Summary
+	for {
+		if err := ctx.Err(); err != nil {
+			cw.Flush()
+			return sum, err
+		}
+
+		o, err := r.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var row []string
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			row = failed(order{}, fmt.Sprintf("line %d: %v", parseErr.Line, parseErr.Err))
+		case err != nil:
+			cw.Flush()
+			return sum, err
+		default:
+			var calcErr error
+			if row, calcErr = calculate(ctx, service, o, opts); calcErr == nil {
+				break
+			}
+			if orderError(calcErr) {
+				row = failed(o, calcErr.Error())
+				break
+			}
+			sum.Internal++
+			if sum.Cause == nil {
+				sum.Cause = calcErr
+			}
+			row = failed(o, "internal error")
+		}
+
+		sum.Rows++
+		if row[len(row)-1] != "" {
+			sum.Failed++
+		}
+		if err := cw.Write(row); err != nil {
+			return sum, err
+		}
+	}
+
+	cw.Flush()
+	return sum, cw.Error()
+}
+
+// calculate returns the result row of an order, or the error it failed
+// with.
+func calculate(ctx context.Context, service *pack.Service, o order, opts []pack.CalcOption) ([]string, error) {
+	quantity, err := strconv.Atoi(o.quantity)
+	if err != nil || quantity <= 0 {
+		return nil, fmt.Errorf("%w %q", pack.ErrInvalidQuantity, o.quantity)
+	}
+	if o.catalog != "" {
+		opts = append(opts[:len(opts):len(opts)], pack.WithCatalog(o.catalog))
+	}
+
+	result, err := service.Calculate(ctx, quantity, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		o.id,
+		o.quantity,
+		strconv.Itoa(result.TotalItems),
+		strconv.Itoa(result.Overage()),
+		strconv.Itoa(result.TotalPacks),
+		breakdown(result.Packs),
+		"",
+	}, nil
+}
+
+// orderError reports whether err is down to the order itself, such as a
+// quantity no combination of packs can meet, rather than to the service.
+func orderError(err error) bool {
+	var tooExpensive *pack.TooExpensiveError
+	return errors.As(err, &tooExpensive) ||
+		errors.Is(err, pack.ErrNoExactMatch) || errors.Is(err, pack.ErrInsufficientStock) || errors.Is(err, pack.ErrNoPackSizes) ||
+		errors.Is(err, pack.ErrNotFound) || errors.Is(err, pack.ErrInvalid)
+}
+
+func failed(o order, reason string) []string {
+	return []string{o.id, o.quantity, "", "", "", "", reason}
+}
+
+// breakdown writes the packs of a result largest first, as in
+// "2x1000 + 1x250".
+func breakdown(packs map[int]int) string {
+	sizes := make([]int, 0, len(packs))
+	for size := range packs {
+		sizes = append(sizes, size)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+
+	parts := make([]string, len(sizes))
+	for i, size := range sizes {
+		parts[i] = fmt.Sprintf("%dx%d", packs[size], size)
+	}
+	return strings.Join(parts, " + ")
+}
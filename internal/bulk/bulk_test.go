@@ -0,0 +1,152 @@
+package bulk_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"pfg/internal/bulk"
+	"pfg/internal/memory"
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	service := pack.NewService(memory.NewRepository(pack.PackSize{Size: 250}, pack.PackSize{Size: 500}, pack.PackSize{Size: 1000}))
+
+	input := "\ufeffQuantity,order_id\n" +
+		"2750,A-1\n" +
+		"1, A-2\n" +
+		"lots,A-3\n" +
+		"-5,A-4\n" +
+		"501,A-5\n"
+	r, err := bulk.NewReader(strings.NewReader(input))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	sum, err := bulk.Run(context.Background(), service, r, &out)
+	require.NoError(t, err)
+	assert.Equal(t, bulk.Summary{Rows: 5, Failed: 2}, sum)
+	assert.Equal(t, `order_id,quantity,fulfilled,overage,pack_count,breakdown,error
+A-1,2750,2750,0,4,2x1000 + 1x500 + 1x250,
+A-2,1,250,249,1,1x250,
+A-3,lots,,,,,"invalid quantity ""lots"""
+A-4,-5,,,,,"invalid quantity ""-5"""
+A-5,501,750,249,2,1x500 + 1x250,
+`, out.String())
+}
+
+func TestRunCatalogColumn(t *testing.T) {
+	service := pack.NewService(memory.NewRepository(pack.PackSize{Size: 250}))
+	ctx := context.Background()
+	require.NoError(t, service.AddCatalog(ctx, pack.Catalog{ID: "bulk", Name: "Bulk"}))
+	require.NoError(t, service.AddPack(ctx, "bulk", pack.PackSize{Size: 100}))
+
+	r, err := bulk.NewReader(strings.NewReader("order_id,quantity,catalog\n1,100,\n2,100,bulk\n3,100,missing\n"))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	sum, err := bulk.Run(ctx, service, r, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sum.Failed)
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "1,100,250,150,1,1x250,", lines[1])
+	assert.Equal(t, "2,100,100,0,1,1x100,", lines[2])
+	assert.Contains(t, lines[3], "3,100,,,,,")
+}
+
+// countingRepo counts the reads a calculation makes before solving.
+type countingRepo struct {
+	*memory.Repository
+	reads map[string]int
+}
+
+func (r *countingRepo) GetCatalogVersion(ctx context.Context, catalogID string, version int) (pack.CatalogVersion, error) {
+	r.reads[catalogID]++
+	return r.Repository.GetCatalogVersion(ctx, catalogID, version)
+}
+
+func (r *countingRepo) GetStock(ctx context.Context, catalogID string) (map[int]int, error) {
+	r.reads[catalogID]++
+	return r.Repository.GetStock(ctx, catalogID)
+}
+
+func (r *countingRepo) GetCatalog(ctx context.Context, id string) (pack.Catalog, error) {
+	r.reads[id]++
+	return r.Repository.GetCatalog(ctx, id)
+}
+
+func TestRunReadsEachCatalogOnce(t *testing.T) {
+	repo := &countingRepo{Repository: memory.NewRepository(pack.PackSize{Size: 250}), reads: map[string]int{}}
+	service := pack.NewService(repo)
+	ctx := context.Background()
+	require.NoError(t, service.AddCatalog(ctx, pack.Catalog{ID: "bulk", Name: "Bulk"}))
+	require.NoError(t, service.AddPack(ctx, "bulk", pack.PackSize{Size: 100}))
+	clear(repo.reads)
+
+	input := "order_id,quantity,catalog\n"
+	for i := range 50 {
+		input += fmt.Sprintf("%d,%d,\n%d,%d,bulk\n%d,%d,missing\n", i, i+1, i, i+1, i, i+1)
+	}
+	r, err := bulk.NewReader(strings.NewReader(input))
+	require.NoError(t, err)
+
+	sum, err := bulk.Run(ctx, service, r, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, bulk.Summary{Rows: 150, Failed: 50}, sum)
+	assert.Equal(t, map[string]int{pack.DefaultCatalog: 2, "bulk": 2, "missing": 2}, repo.reads)
+}
+
+// brokenStockRepo fails to read the stock of one catalog, as a database
+// might.
+type brokenStockRepo struct {
+	*memory.Repository
+}
+
+func (r brokenStockRepo) GetStock(ctx context.Context, catalogID string) (map[int]int, error) {
+	if catalogID == "broken" {
+		return nil, errors.New(`pq: relation "pack_inventory" does not exist`)
+	}
+	return r.Repository.GetStock(ctx, catalogID)
+}
+
+// Only errors down to the order itself make it into the results file.
+func TestRunHidesInternalErrors(t *testing.T) {
+	service := pack.NewService(brokenStockRepo{memory.NewRepository(pack.PackSize{Size: 250})})
+	ctx := context.Background()
+	require.NoError(t, service.AddCatalog(ctx, pack.Catalog{ID: "broken", Name: "Broken"}))
+	require.NoError(t, service.AddPack(ctx, "broken", pack.PackSize{Size: 100}))
+
+	r, err := bulk.NewReader(strings.NewReader("order_id,quantity,catalog\n1,100,broken\n2,100,missing\n3,100,\n"))
+	require.NoError(t, err)
+
+	var out strings.Builder
+	sum, err := bulk.Run(ctx, service, r, &out)
+	require.NoError(t, err)
+	assert.Equal(t, 2, sum.Failed)
+	assert.Equal(t, 1, sum.Internal)
+	assert.ErrorContains(t, sum.Cause, "pack_inventory")
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Equal(t, "1,100,,,,,internal error", lines[1])
+	assert.Equal(t, "2,100,,,,,unknown catalog", lines[2])
+	assert.Equal(t, "3,100,250,150,1,1x250,", lines[3])
+}
+
+func TestNewReader(t *testing.T) {
+	for input, msg := range map[string]string{
+		"":                        "empty CSV file",
+		"order_id\nA-1\n":         "the CSV file has no quantity column",
+		"quantity\n250\n":         "the CSV file has no order_id column",
+		"order_id,qty\nA-1,250\n": `unknown CSV column "qty"`,
+	} {
+		_, err := bulk.NewReader(strings.NewReader(input))
+		assert.EqualError(t, err, msg, input)
+	}
+}
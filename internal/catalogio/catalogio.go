@@ -0,0 +1,329 @@
+// Package catalogio reads and writes the pack sizes of a catalog as CSV or
+// JSON files, so they can be kept in spreadsheets and moved between
+// installations.
+package catalogio
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"pfg/internal/pack"
+)
+
+// File formats.
+const (
+	CSV  = "csv"
+	JSON = "json"
+)
+
+// MaxFileSize is the largest file Read should be given; callers limit
+// uploads to it.
+const MaxFileSize = 1 << 20
+
+// ParseFormat returns the format called name, in any case.
+func ParseFormat(name string) (string, error) {
+	switch f := strings.ToLower(name); f {
+	case CSV, JSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q: use %s or %s", name, CSV, JSON)
+}
+
+// ContentType is the media type of files in format.
+func ContentType(format string) string {
+	if format == CSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// Filename is the name an export of catalog in format is saved under.
+func Filename(catalog, format string) string {
+	if catalog == "" {
+		catalog = pack.DefaultCatalog
+	}
+	return "pack-sizes-" + catalog + "." + format
+}
+
+// Write writes packs to w in format.
+func Write(w io.Writer, format string, packs []pack.PackSize) error {
+	if format == CSV {
+		return writeCSV(w, packs)
+	}
+	entries := make([]entry, len(packs))
+	for i, p := range packs {
+		entries[i] = newEntry(p)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(entries)
+}
+
+// Read reads pack sizes in format from r. Fields left out take their zero
+// value, except that sizes are active unless a file says otherwise.
+func Read(r io.Reader, format string) ([]pack.PackSize, error) {
+	if format == CSV {
+		return readCSV(r)
+	}
+	var entries []entry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	packs := make([]pack.PackSize, len(entries))
+	for i, e := range entries {
+		packs[i] = e.packSize()
+	}
+	return packs, nil
+}
+
+// entry is a pack size in a JSON file, in the shape /api/v2/packs uses.
+type entry struct {
+	Size          int        `json:"size"`
+	UnitCost      int64      `json:"unitCost"`
+	Label         string     `json:"label"`
+	SKU           string     `json:"sku"`
+	Length        int        `json:"lengthMm"`
+	Width         int        `json:"widthMm"`
+	Height        int        `json:"heightMm"`
+	TareWeight    int        `json:"tareWeightG"`
+	Active        bool       `json:"active"`
+	AvailableFrom *time.Time `json:"availableFrom"`
+	RetiresAt     *time.Time `json:"retiresAt"`
+}
+
+// UnmarshalJSON makes entries active unless they say otherwise, and
+// rejects fields it does not know as likely typos.
+func (e *entry) UnmarshalJSON(data []byte) error {
+	type plain entry
+	p := plain{Active: true}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return err
+	}
+	*e = entry(p)
+	return nil
+}
+
+func newEntry(p pack.PackSize) entry {
+	e := entry{
+		Size:       p.Size,
+		UnitCost:   p.UnitCost,
+		Label:      p.Label,
+		SKU:        p.SKU,
+		Length:     p.Dimensions.Length,
+		Width:      p.Dimensions.Width,
+		Height:     p.Dimensions.Height,
+		TareWeight: p.TareWeight,
+		Active:     !p.Inactive,
+	}
+	if t := p.Schedule.AvailableFrom; !t.IsZero() {
+		e.AvailableFrom = &t
+	}
+	if t := p.Schedule.RetiresAt; !t.IsZero() {
+		e.RetiresAt = &t
+	}
+	return e
+}
+
+func (e entry) packSize() pack.PackSize {
+	p := pack.PackSize{
+		Size:       e.Size,
+		UnitCost:   e.UnitCost,
+		Label:      e.Label,
+		SKU:        e.SKU,
+		Dimensions: pack.Dimensions{Length: e.Length, Width: e.Width, Height: e.Height},
+		TareWeight: e.TareWeight,
+		Inactive:   !e.Active,
+	}
+	if e.AvailableFrom != nil {
+		p.Schedule.AvailableFrom = *e.AvailableFrom
+	}
+	if e.RetiresAt != nil {
+		p.Schedule.RetiresAt = *e.RetiresAt
+	}
+	return p
+}
+
+// columns are the CSV columns, in the order Write puts them. Times are
+// RFC 3339 and blank when not set. Labels and SKUs that a spreadsheet would
+// run as a formula are written with a leading quote, see quoteText.
+var columns = []string{"size", "unit_cost_cents", "label", "sku", "length_mm", "width_mm", "height_mm", "tare_weight_g", "active", "available_from", "retires_at"}
+
+func writeCSV(w io.Writer, packs []pack.PackSize) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, p := range packs {
+		err := cw.Write([]string{
+			strconv.Itoa(p.Size),
+			strconv.FormatInt(p.UnitCost, 10),
+			quoteText(p.Label),
+			quoteText(p.SKU),
+			strconv.Itoa(p.Dimensions.Length),
+			strconv.Itoa(p.Dimensions.Width),
+			strconv.Itoa(p.Dimensions.Height),
+			strconv.Itoa(p.TareWeight),
+			strconv.FormatBool(!p.Inactive),
+			formatTime(p.Schedule.AvailableFrom),
+			formatTime(p.Schedule.RetiresAt),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// quoteText prefixes text a spreadsheet would take for a formula with a
+// quote, which spreadsheets show as plain text. Text that already starts
+// with a quote gets another, so unquoteText gives back exactly what was
+// written.
+func quoteText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@'", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// unquoteText undoes quoteText.
+func unquoteText(s string) string {
+	return strings.TrimPrefix(s, "'")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// readCSV reads a file with a header row naming its columns, in any order.
+// Only size is required.
+func readCSV(r io.Reader) ([]pack.PackSize, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty CSV file")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	index := map[string]int{}
+	for i, name := range header {
+		// Spreadsheets tend to start their files with a byte order mark.
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := false
+		for _, c := range columns {
+			known = known || c == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("duplicate CSV column %q", name)
+		}
+		index[name] = i
+	}
+	if _, ok := index["size"]; !ok {
+		return nil, errors.New("the CSV file has no size column")
+	}
+
+	packs := []pack.PackSize{}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return packs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+
+		p, err := parseRecord(record, index)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		packs = append(packs, p)
+	}
+}
+
+func parseRecord(record []string, index map[string]int) (pack.PackSize, error) {
+	field := func(name string) string {
+		if i, ok := index[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	p := pack.PackSize{Label: unquoteText(field("label")), SKU: unquoteText(field("sku"))}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{"size", &p.Size},
+		{"length_mm", &p.Dimensions.Length},
+		{"width_mm", &p.Dimensions.Width},
+		{"height_mm", &p.Dimensions.Height},
+		{"tare_weight_g", &p.TareWeight},
+	}
+	for _, f := range ints {
+		v := field(f.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return pack.PackSize{}, fmt.Errorf("invalid %s %q", f.name, v)
+		}
+		*f.dst = n
+	}
+
+	if v := field("unit_cost_cents"); v != "" {
+		cost, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return pack.PackSize{}, fmt.Errorf("invalid unit_cost_cents %q", v)
+		}
+		p.UnitCost = cost
+	}
+
+	if v := field("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return pack.PackSize{}, fmt.Errorf("invalid active %q", v)
+		}
+		p.Inactive = !active
+	}
+
+	times := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"available_from", &p.Schedule.AvailableFrom},
+		{"retires_at", &p.Schedule.RetiresAt},
+	}
+	for _, f := range times {
+		v := field(f.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return pack.PackSize{}, fmt.Errorf("invalid %s %q, expected an RFC 3339 timestamp", f.name, v)
+		}
+		*f.dst = t
+	}
+	return p, nil
+}
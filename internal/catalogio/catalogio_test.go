@@ -0,0 +1,92 @@
+package catalogio_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"pfg/internal/catalogio"
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var packs = []pack.PackSize{
+	{Size: 250, UnitCost: 120, Label: "Small box, flat", SKU: "BOX-250", Dimensions: pack.Dimensions{Length: 200, Width: 150, Height: 100}, TareWeight: 80},
+	{Size: 500, Inactive: true},
+	{Size: 1000, Schedule: pack.Schedule{AvailableFrom: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), RetiresAt: time.Date(2028, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	{Size: 2000, Label: "=HYPERLINK(\"http://example.com\")", SKU: "'quoted"},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{catalogio.CSV, catalogio.JSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, catalogio.Write(&buf, format, packs))
+			read, err := catalogio.Read(&buf, format)
+			require.NoError(t, err)
+			assert.Equal(t, packs, read)
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, catalogio.Write(&buf, catalogio.CSV, packs[:2]))
+	assert.Equal(t, `size,unit_cost_cents,label,sku,length_mm,width_mm,height_mm,tare_weight_g,active,available_from,retires_at
+250,120,"Small box, flat",BOX-250,200,150,100,80,true,,
+500,0,,,0,0,0,0,false,,
+`, buf.String())
+}
+
+// Text a spreadsheet would run as a formula is written as plain text.
+func TestWriteCSVFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	formulas := []pack.PackSize{{Size: 1, Label: "=1+1", SKU: "+1"}, {Size: 2, Label: "-1", SKU: "@SUM(A1)"}, {Size: 3, Label: "'text", SKU: "a=b"}}
+	require.NoError(t, catalogio.Write(&buf, catalogio.CSV, formulas))
+	assert.Equal(t, `size,unit_cost_cents,label,sku,length_mm,width_mm,height_mm,tare_weight_g,active,available_from,retires_at
+1,0,'=1+1,'+1,0,0,0,0,true,,
+2,0,'-1,'@SUM(A1),0,0,0,0,true,,
+3,0,''text,a=b,0,0,0,0,true,,
+`, buf.String())
+}
+
+func TestReadCSV(t *testing.T) {
+	// Columns come in any order, may be left out and may follow a byte
+	// order mark.
+	read, err := catalogio.Read(strings.NewReader("\ufeffSKU, size\nBOX-250, 250\n,500\n"), catalogio.CSV)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 250, SKU: "BOX-250"}, {Size: 500}}, read)
+
+	for input, msg := range map[string]string{
+		"":                                  "empty CSV file",
+		"label\nBox\n":                      "the CSV file has no size column",
+		"size,colour\n250,red\n":            `unknown CSV column "colour"`,
+		"size,label,Size\n250,Box,500\n":    `duplicate CSV column "size"`,
+		"size\n250\nlarge\n":                `line 3: invalid size "large"`,
+		"size,active\n250,maybe":            `line 2: invalid active "maybe"`,
+		"size,retires_at\n250,2027-01-01\n": `line 2: invalid retires_at "2027-01-01", expected an RFC 3339 timestamp`,
+	} {
+		_, err := catalogio.Read(strings.NewReader(input), catalogio.CSV)
+		assert.EqualError(t, err, msg, input)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	read, err := catalogio.Read(strings.NewReader(`[{"size": 250}, {"size": 500, "active": false}]`), catalogio.JSON)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 250}, {Size: 500, Inactive: true}}, read)
+
+	_, err = catalogio.Read(strings.NewReader(`[{"size": 250, "colour": "red"}]`), catalogio.JSON)
+	assert.ErrorContains(t, err, `unknown field "colour"`)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := catalogio.ParseFormat("CSV")
+	assert.NoError(t, err)
+	assert.Equal(t, catalogio.CSV, format)
+	_, err = catalogio.ParseFormat("xlsx")
+	assert.EqualError(t, err, `unknown format "xlsx": use csv or json`)
+}
@@ -7,6 +7,13 @@ import (
 	"time"
 )
 
+// Storage backends.
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+	StorageSQLite   = "sqlite"
+)
+
 type Config struct {
 	Port string
 
@@ -14,6 +21,16 @@ type Config struct {
 
 	JetViewsPath string
 
+	// Storage selects where data is kept: StoragePostgres, StorageMemory
+	// or StorageSQLite.
+	Storage string
+
+	// SQLitePath is the database file used by StorageSQLite.
+	SQLitePath string
+
+	// AutoMigrate applies pending Postgres migrations when the app starts.
+	AutoMigrate bool
+
 	DBHost     string
 	DBPort     string
 	DBUser     string
@@ -24,8 +41,16 @@ type Config struct {
 	JWTSecret string
 	JWTExpiry time.Duration
 
+	// AdminEmail and AdminPassword create the first admin account when
+	// there are no accounts yet. They are ignored afterwards.
 	AdminEmail    string
 	AdminPassword string
+
+	CalcMaxQuantity   int
+	CalcMaxWork       int
+	CalcMaxOrderLines int
+
+	CatalogMaxSizes int
 }
 
 func Load() *Config {
@@ -34,8 +59,8 @@ func Load() *Config {
 	adminEmail := os.Getenv("ADMIN_EMAIL")
 	adminPass := os.Getenv("ADMIN_PASSWORD")
 
-	if adminEmail == "" || adminPass == "" {
-		panic("missing required admin credentials: ADMIN_EMAIL and ADMIN_PASSWORD must be set")
+	if (adminEmail == "") != (adminPass == "") {
+		panic("incomplete admin credentials: ADMIN_EMAIL and ADMIN_PASSWORD must be set together")
 	}
 
 	production, err := strconv.ParseBool(getEnv("PRODUCTION", "false"))
@@ -43,6 +68,38 @@ func Load() *Config {
 		panic(fmt.Sprintf("problem parsing production env variable: %v", err))
 	}
 
+	storage := getEnv("STORAGE", StoragePostgres)
+	switch storage {
+	case StoragePostgres, StorageMemory, StorageSQLite:
+	default:
+		panic(fmt.Sprintf("unknown storage %q: STORAGE must be %s, %s or %s", storage, StoragePostgres, StorageMemory, StorageSQLite))
+	}
+
+	autoMigrate, err := strconv.ParseBool(getEnv("AUTO_MIGRATE", "false"))
+	if err != nil {
+		panic(fmt.Sprintf("problem parsing auto migrate env variable: %v", err))
+	}
+
+	calcMaxQuantity, err := strconv.Atoi(getEnv("CALC_MAX_QUANTITY", "1000000000"))
+	if err != nil {
+		panic(fmt.Sprintf("problem parsing calc max quantity env variable: %v", err))
+	}
+
+	calcMaxWork, err := strconv.Atoi(getEnv("CALC_MAX_WORK", "50000000"))
+	if err != nil {
+		panic(fmt.Sprintf("problem parsing calc max work env variable: %v", err))
+	}
+
+	calcMaxOrderLines, err := strconv.Atoi(getEnv("CALC_MAX_ORDER_LINES", "1000"))
+	if err != nil {
+		panic(fmt.Sprintf("problem parsing calc max order lines env variable: %v", err))
+	}
+
+	catalogMaxSizes, err := strconv.Atoi(getEnv("CATALOG_MAX_SIZES", "100"))
+	if err != nil {
+		panic(fmt.Sprintf("problem parsing catalog max sizes env variable: %v", err))
+	}
+
 	return &Config{
 		Port: getEnv("PORT", "8080"),
 
@@ -50,6 +107,11 @@ func Load() *Config {
 
 		JetViewsPath: getEnv("JET_VIEWS_PATH", "internal/templates"),
 
+		Storage:    storage,
+		SQLitePath: getEnv("SQLITE_PATH", "packaging.db"),
+
+		AutoMigrate: autoMigrate,
+
 		DBHost:     getEnv("DB_HOST", "postgres"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -62,6 +124,12 @@ func Load() *Config {
 
 		AdminEmail:    adminEmail,
 		AdminPassword: adminPass,
+
+		CalcMaxQuantity:   calcMaxQuantity,
+		CalcMaxWork:       calcMaxWork,
+		CalcMaxOrderLines: calcMaxOrderLines,
+
+		CatalogMaxSizes: catalogMaxSizes,
 	}
 }
 
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the Postgres error code for a duplicate key.
+const uniqueViolation = "23505"
+
+func (r *Repository) GetTransitions(ctx context.Context, orderID int64) ([]lifecycle.Transition, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT order_id, seq, from_state, to_state, changed_at, changed_by
+		FROM order_transitions WHERE order_id = $1 ORDER BY seq ASC`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []lifecycle.Transition{}
+	for rows.Next() {
+		var t lifecycle.Transition
+		if err := rows.Scan(&t.OrderID, &t.Seq, &t.From, &t.To, &t.At, &t.By); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+func (r *Repository) GetStates(ctx context.Context, orderIDs []int64) (map[int64]lifecycle.State, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT DISTINCT ON (order_id) order_id, to_state
+		FROM order_transitions WHERE order_id = ANY($1)
+		ORDER BY order_id, seq DESC`, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := map[int64]lifecycle.State{}
+	for rows.Next() {
+		var (
+			id    int64
+			state lifecycle.State
+		)
+		if err := rows.Scan(&id, &state); err != nil {
+			return nil, err
+		}
+		states[id] = state
+	}
+	return states, rows.Err()
+}
+
+func (r *Repository) InsertTransition(ctx context.Context, o pack.Order, t lifecycle.Transition, change lifecycle.StockChange) (lifecycle.Transition, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return lifecycle.Transition{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if t, err = insertTransition(ctx, tx, t); err != nil {
+		return lifecycle.Transition{}, err
+	}
+	if err := changeStock(ctx, tx, o, change); err != nil {
+		return lifecycle.Transition{}, err
+	}
+	return t, tx.Commit(ctx)
+}
+
+func (r *Repository) CreateOrder(ctx context.Context, o pack.Order, t lifecycle.Transition, change lifecycle.StockChange) (pack.Order, lifecycle.Transition, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return pack.Order{}, lifecycle.Transition{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := changeStock(ctx, tx, o, change); err != nil {
+		return pack.Order{}, lifecycle.Transition{}, err
+	}
+	if o, err = insertOrder(ctx, tx, o); err != nil {
+		return pack.Order{}, lifecycle.Transition{}, err
+	}
+	t.OrderID = o.ID
+	if t, err = insertTransition(ctx, tx, t); err != nil {
+		return pack.Order{}, lifecycle.Transition{}, err
+	}
+	return o, t, tx.Commit(ctx)
+}
+
+// insertTransition records t and returns it with At set.
+func insertTransition(ctx context.Context, tx pgx.Tx, t lifecycle.Transition) (lifecycle.Transition, error) {
+	err := tx.QueryRow(ctx, `
+		INSERT INTO order_transitions (order_id, seq, from_state, to_state, changed_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING changed_at`,
+		t.OrderID, t.Seq, t.From, t.To, t.By,
+	).Scan(&t.At)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return lifecycle.Transition{}, lifecycle.ErrStaleState
+	}
+	if err != nil {
+		return lifecycle.Transition{}, err
+	}
+	return t, nil
+}
+
+// changeStock reserves or releases the packs of o as change says.
+func changeStock(ctx context.Context, tx pgx.Tx, o pack.Order, change lifecycle.StockChange) error {
+	switch change {
+	case lifecycle.ReserveStock:
+		return reserveStock(ctx, tx, o.Catalog, o.Packs)
+	case lifecycle.ReleaseStock:
+		return releaseStock(ctx, tx, o.Catalog, o.Packs)
+	}
+	return nil
+}
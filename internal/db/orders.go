@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pfg/internal/pack"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// orderPack is how a line of an order's breakdown is stored.
+type orderPack struct {
+	Size  int `json:"size"`
+	Count int `json:"count"`
+}
+
+func (r *Repository) InsertOrder(ctx context.Context, o pack.Order, reserve bool) (pack.Order, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return pack.Order{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	if reserve {
+		if err := reserveStock(ctx, tx, o.Catalog, o.Packs); err != nil {
+			return pack.Order{}, err
+		}
+	}
+	if o, err = insertOrder(ctx, tx, o); err != nil {
+		return pack.Order{}, err
+	}
+	return o, tx.Commit(ctx)
+}
+
+// insertOrder stores o and returns it with its ID and CreatedAt set.
+func insertOrder(ctx context.Context, tx pgx.Tx, o pack.Order) (pack.Order, error) {
+	snapshot, err := pack.MarshalSnapshot(o.Sizes)
+	if err != nil {
+		return pack.Order{}, err
+	}
+
+	packs := make([]orderPack, 0, len(o.Packs))
+	for size, count := range o.Packs {
+		packs = append(packs, orderPack{Size: size, Count: count})
+	}
+	breakdown, err := json.Marshal(packs)
+	if err != nil {
+		return pack.Order{}, err
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO pack_orders (requested_by, catalog_id, catalog_version, sizes, strategy, requested, total_items, total_packs, total_cost_cents, packs)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at`,
+		o.RequestedBy, o.Catalog, o.CatalogVersion, snapshot, o.Strategy, o.Requested, o.TotalItems, o.TotalPacks, o.TotalCost, breakdown,
+	).Scan(&o.ID, &o.CreatedAt)
+	if err != nil {
+		return pack.Order{}, err
+	}
+	return o, nil
+}
+
+const orderColumns = `id, created_at, requested_by, catalog_id, catalog_version, sizes, strategy, requested, total_items, total_packs, total_cost_cents, packs`
+
+func scanOrder(row pgx.Row) (pack.Order, error) {
+	var (
+		o                   pack.Order
+		snapshot, breakdown []byte
+	)
+	err := row.Scan(&o.ID, &o.CreatedAt, &o.RequestedBy, &o.Catalog, &o.CatalogVersion, &snapshot,
+		&o.Strategy, &o.Requested, &o.TotalItems, &o.TotalPacks, &o.TotalCost, &breakdown)
+	if err != nil {
+		return pack.Order{}, err
+	}
+
+	if o.Sizes, err = pack.UnmarshalSnapshot(snapshot); err != nil {
+		return pack.Order{}, err
+	}
+
+	var packs []orderPack
+	if err := json.Unmarshal(breakdown, &packs); err != nil {
+		return pack.Order{}, err
+	}
+	o.Packs = make(map[int]int, len(packs))
+	for _, p := range packs {
+		o.Packs[p.Size] = p.Count
+	}
+	return o, nil
+}
+
+func (r *Repository) GetOrder(ctx context.Context, id int64) (pack.Order, error) {
+	o, err := scanOrder(r.pool.QueryRow(ctx, `SELECT `+orderColumns+` FROM pack_orders WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pack.Order{}, pack.ErrUnknownOrder
+	}
+	return o, err
+}
+
+func (r *Repository) GetOrders(ctx context.Context, f pack.OrderFilter) ([]pack.Order, error) {
+	var (
+		where []string
+		args  []any
+	)
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Catalog != "" {
+		add("catalog_id = $%d", f.Catalog)
+	}
+	if f.RequestedBy != "" {
+		add("requested_by = $%d", f.RequestedBy)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < $%d", f.To)
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM pack_orders`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC LIMIT ` + strconv.Itoa(f.Limit)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []pack.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
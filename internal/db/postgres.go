@@ -3,7 +3,12 @@ package db
 import (
 	"context"
 	"errors"
+	"sort"
+	"time"
 
+	"pfg/internal/pack"
+
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -15,36 +20,305 @@ func NewRepository(conn Conn) *Repository {
 	return &Repository{pool: conn.Pool()}
 }
 
-func (r *Repository) GetPackSizes(ctx context.Context) ([]int, error) {
-	rows, err := r.pool.Query(ctx, `SELECT size FROM pack_sizes ORDER BY size ASC`)
+func (r *Repository) GetCatalogs(ctx context.Context) ([]pack.Catalog, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, name FROM pack_catalogs ORDER BY id ASC`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	sizes := []int{}
+	catalogs := []pack.Catalog{}
 	for rows.Next() {
-		var size int
-		if err := rows.Scan(&size); err != nil {
+		var c pack.Catalog
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
 			return nil, err
 		}
-		sizes = append(sizes, size)
+		catalogs = append(catalogs, c)
 	}
-	return sizes, nil
+	return catalogs, rows.Err()
 }
 
-func (r *Repository) InsertPackSize(ctx context.Context, size int) error {
-	_, err := r.pool.Exec(ctx, `INSERT INTO pack_sizes (size) VALUES ($1) ON CONFLICT DO NOTHING`, size)
-	return err
+func (r *Repository) GetCatalog(ctx context.Context, id string) (pack.Catalog, error) {
+	c := pack.Catalog{ID: id}
+	err := r.pool.QueryRow(ctx, `SELECT name FROM pack_catalogs WHERE id = $1`, id).Scan(&c.Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pack.Catalog{}, pack.ErrUnknownCatalog
+	}
+	return c, err
+}
+
+func (r *Repository) InsertCatalog(ctx context.Context, c pack.Catalog, change pack.Change) error {
+	return r.changeCatalog(ctx, c.ID, change, func(tx pgx.Tx) (bool, error) {
+		cmd, err := tx.Exec(ctx, `INSERT INTO pack_catalogs (id, name) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`, c.ID, c.Name)
+		if err != nil {
+			return false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return false, pack.ErrDuplicateCatalog
+		}
+		return true, nil
+	})
 }
 
-func (r *Repository) DeletePackSize(ctx context.Context, size int) error {
-	cmd, err := r.pool.Exec(ctx, `DELETE FROM pack_sizes WHERE size = $1`, size)
+func (r *Repository) DeleteCatalog(ctx context.Context, id string) error {
+	cmd, err := r.pool.Exec(ctx, `DELETE FROM pack_catalogs WHERE id = $1`, id)
 	if err != nil {
 		return err
 	}
 	if cmd.RowsAffected() == 0 {
-		return errors.New("pack size not found")
+		return pack.ErrUnknownCatalog
+	}
+	return nil
+}
+
+func (r *Repository) GetPackSizes(ctx context.Context, catalogID string) ([]pack.PackSize, error) {
+	return packSizes(ctx, r.pool, catalogID)
+}
+
+// querier is satisfied by both *pgxpool.Pool and pgx.Tx.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func packSizes(ctx context.Context, q querier, catalogID string) ([]pack.PackSize, error) {
+	rows, err := q.Query(ctx, `
+		SELECT size, unit_cost_cents, label, sku, length_mm, width_mm, height_mm, tare_weight_g, NOT active, available_from, retires_at
+		FROM pack_sizes WHERE catalog_id = $1 ORDER BY size ASC`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	packs := []pack.PackSize{}
+	for rows.Next() {
+		var (
+			p                        pack.PackSize
+			availableFrom, retiresAt *time.Time
+		)
+		err := rows.Scan(&p.Size, &p.UnitCost, &p.Label, &p.SKU, &p.Dimensions.Length, &p.Dimensions.Width, &p.Dimensions.Height, &p.TareWeight, &p.Inactive, &availableFrom, &retiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if availableFrom != nil {
+			p.Schedule.AvailableFrom = *availableFrom
+		}
+		if retiresAt != nil {
+			p.Schedule.RetiresAt = *retiresAt
+		}
+		packs = append(packs, p)
+	}
+	return packs, rows.Err()
+}
+
+// insertPackSize inserts a pack size given the arguments from
+// packSizeArgs.
+const insertPackSize = `
+	INSERT INTO pack_sizes (catalog_id, size, unit_cost_cents, label, sku, length_mm, width_mm, height_mm, tare_weight_g, active, available_from, retires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+func packSizeArgs(catalogID string, p pack.PackSize) []any {
+	return []any{catalogID, p.Size, p.UnitCost, p.Label, p.SKU, p.Dimensions.Length, p.Dimensions.Width, p.Dimensions.Height, p.TareWeight, !p.Inactive,
+		nullTime(p.Schedule.AvailableFrom), nullTime(p.Schedule.RetiresAt)}
+}
+
+func (r *Repository) InsertPackSize(ctx context.Context, catalogID string, p pack.PackSize, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx pgx.Tx) (bool, error) {
+		cmd, err := tx.Exec(ctx, insertPackSize+` ON CONFLICT (catalog_id, size) DO NOTHING`, packSizeArgs(catalogID, p)...)
+		if err != nil {
+			return false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return false, pack.ErrDuplicatePackSize
+		}
+		return true, nil
+	})
+}
+
+func (r *Repository) UpdatePackCost(ctx context.Context, catalogID string, size int, unitCost int64, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx pgx.Tx) (bool, error) {
+		cmd, err := tx.Exec(ctx, `UPDATE pack_sizes SET unit_cost_cents = $3 WHERE catalog_id = $1 AND size = $2`, catalogID, size, unitCost)
+		if err != nil {
+			return false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return false, pack.ErrUnknownPackSize
+		}
+		return true, nil
+	})
+}
+
+func (r *Repository) UpdatePackDetails(ctx context.Context, catalogID string, p pack.PackSize, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx pgx.Tx) (bool, error) {
+		cmd, err := tx.Exec(ctx, `
+			UPDATE pack_sizes SET label = $3, sku = $4, length_mm = $5, width_mm = $6, height_mm = $7, tare_weight_g = $8
+			WHERE catalog_id = $1 AND size = $2`,
+			catalogID, p.Size, p.Label, p.SKU, p.Dimensions.Length, p.Dimensions.Width, p.Dimensions.Height, p.TareWeight)
+		if err != nil {
+			return false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return false, pack.ErrUnknownPackSize
+		}
+		return true, nil
+	})
+}
+
+func (r *Repository) SetPackActive(ctx context.Context, catalogID string, size int, active bool, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx pgx.Tx) (bool, error) {
+		cmd, err := tx.Exec(ctx, `UPDATE pack_sizes SET active = $3 WHERE catalog_id = $1 AND size = $2`, catalogID, size, active)
+		if err != nil {
+			return false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return false, pack.ErrUnknownPackSize
+		}
+		return true, nil
+	})
+}
+
+func (r *Repository) UpdatePackSchedule(ctx context.Context, catalogID string, size int, s pack.Schedule, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx pgx.Tx) (bool, error) {
+		cmd, err := tx.Exec(ctx, `UPDATE pack_sizes SET available_from = $3, retires_at = $4 WHERE catalog_id = $1 AND size = $2`,
+			catalogID, size, nullTime(s.AvailableFrom), nullTime(s.RetiresAt))
+		if err != nil {
+			return false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return false, pack.ErrUnknownPackSize
+		}
+		return true, nil
+	})
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func (r *Repository) DeletePackSize(ctx context.Context, catalogID string, size int, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx pgx.Tx) (bool, error) {
+		cmd, err := tx.Exec(ctx, `DELETE FROM pack_sizes WHERE catalog_id = $1 AND size = $2`, catalogID, size)
+		if err != nil {
+			return false, err
+		}
+		if cmd.RowsAffected() == 0 {
+			return false, pack.ErrUnknownPackSize
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM pack_inventory WHERE catalog_id = $1 AND size = $2`, catalogID, size); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// dropStaleStock deletes the stock kept for sizes a catalog no longer has.
+const dropStaleStock = `
+	DELETE FROM pack_inventory WHERE catalog_id = $1
+	AND size NOT IN (SELECT size FROM pack_sizes WHERE catalog_id = $1)`
+
+func (r *Repository) ReplacePackSizes(ctx context.Context, catalogID string, packs []pack.PackSize, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx pgx.Tx) (bool, error) {
+		if _, err := tx.Exec(ctx, `DELETE FROM pack_sizes WHERE catalog_id = $1`, catalogID); err != nil {
+			return false, err
+		}
+		for _, p := range packs {
+			if _, err := tx.Exec(ctx, insertPackSize, packSizeArgs(catalogID, p)...); err != nil {
+				return false, err
+			}
+		}
+		if _, err := tx.Exec(ctx, dropStaleStock, catalogID); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+func (r *Repository) GetStock(ctx context.Context, catalogID string) (map[int]int, error) {
+	rows, err := r.pool.Query(ctx, `SELECT size, on_hand FROM pack_inventory WHERE catalog_id = $1`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := map[int]int{}
+	for rows.Next() {
+		var size, onHand int
+		if err := rows.Scan(&size, &onHand); err != nil {
+			return nil, err
+		}
+		stock[size] = onHand
+	}
+	return stock, rows.Err()
+}
+
+func (r *Repository) SetStock(ctx context.Context, catalogID string, size int, onHand int) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO pack_inventory (catalog_id, size, on_hand) VALUES ($1, $2, $3)
+		ON CONFLICT (catalog_id, size) DO UPDATE SET on_hand = EXCLUDED.on_hand`, catalogID, size, onHand)
+	return err
+}
+
+func (r *Repository) DeleteStock(ctx context.Context, catalogID string, size int) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM pack_inventory WHERE catalog_id = $1 AND size = $2`, catalogID, size)
+	return err
+}
+
+func (r *Repository) ReserveStock(ctx context.Context, catalogID string, packs map[int]int) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := reserveStock(ctx, tx, catalogID, packs); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func reserveStock(ctx context.Context, tx pgx.Tx, catalogID string, packs map[int]int) error {
+	// Lock rows in a fixed order so concurrent reservations cannot deadlock.
+	sizes := make([]int, 0, len(packs))
+	for size := range packs {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	for _, size := range sizes {
+		count := packs[size]
+		var onHand int
+		err := tx.QueryRow(ctx, `SELECT on_hand FROM pack_inventory WHERE catalog_id = $1 AND size = $2 FOR UPDATE`, catalogID, size).Scan(&onHand)
+		if errors.Is(err, pgx.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if onHand < count {
+			return pack.ErrInsufficientStock
+		}
+		if _, err := tx.Exec(ctx, `UPDATE pack_inventory SET on_hand = on_hand - $3 WHERE catalog_id = $1 AND size = $2`, catalogID, size, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseStock puts packs back into the stock of the sizes that track it.
+func releaseStock(ctx context.Context, tx pgx.Tx, catalogID string, packs map[int]int) error {
+	sizes := make([]int, 0, len(packs))
+	for size := range packs {
+		sizes = append(sizes, size)
+	}
+	sort.Ints(sizes)
+
+	for _, size := range sizes {
+		if _, err := tx.Exec(ctx, `UPDATE pack_inventory SET on_hand = on_hand + $3 WHERE catalog_id = $1 AND size = $2`, catalogID, size, packs[size]); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -0,0 +1,87 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pfg/infra/atlas"
+	"pfg/internal/account"
+	"pfg/internal/account/accounttest"
+	"pfg/internal/db"
+	"pfg/internal/migrate"
+	"pfg/internal/pack"
+	"pfg/internal/pack/packtest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// schemas numbers the schemas created by this test run.
+var schemas atomic.Int64
+
+// newRepository migrates a fresh schema on the server at url and drops it
+// when the test ends.
+func newRepository(t *testing.T, url string) *db.Repository {
+	t.Helper()
+	ctx := context.Background()
+
+	admin, err := db.Connect(url)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("pfg_test_%d_%d", time.Now().UnixNano(), schemas.Add(1))
+	_, err = admin.Pool().Exec(ctx, `CREATE SCHEMA `+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		admin.Pool().Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+	})
+
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	conn, err := db.Connect(url + sep + "search_path=" + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	migrator, err := migrate.New(conn.Pool(), atlas.Migrations, migrate.WithRevisionsSchema(schema))
+	require.NoError(t, err)
+	_, err = migrator.Up(ctx)
+	require.NoError(t, err)
+
+	return db.NewRepository(conn)
+}
+
+// databaseURL returns the server at TEST_DATABASE_URL, such as the throwaway
+// one started by make test-postgres. Without it the test is skipped, except
+// when CI is set: there it fails, so the Postgres tests cannot quietly stop
+// running.
+func databaseURL(t *testing.T) string {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_DATABASE_URL must be set when CI is")
+		}
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	return url
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	url := databaseURL(t)
+	packtest.RunRepositoryTests(t, func(t *testing.T) pack.Repository {
+		return newRepository(t, url)
+	})
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	url := databaseURL(t)
+	accounttest.RunRepositoryTests(t, func(t *testing.T) account.Repository {
+		return newRepository(t, url)
+	})
+}
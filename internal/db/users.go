@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"pfg/internal/account"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const userColumns = `id, email, password_hash, role, created_at`
+
+func scanUser(row pgx.Row) (account.User, error) {
+	var u account.User
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role, &u.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return account.User{}, account.ErrUnknownUser
+	}
+	return u, err
+}
+
+func (r *Repository) GetUsers(ctx context.Context) ([]account.User, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+userColumns+` FROM users ORDER BY email ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []account.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *Repository) GetUser(ctx context.Context, id int64) (account.User, error) {
+	return scanUser(r.pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+}
+
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (account.User, error) {
+	return scanUser(r.pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1`, email))
+}
+
+func (r *Repository) InsertUser(ctx context.Context, u account.User) (account.User, error) {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO users (email, password_hash, role) VALUES ($1, $2, $3)
+		RETURNING id, created_at`,
+		u.Email, u.PasswordHash, u.Role,
+	).Scan(&u.ID, &u.CreatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return account.User{}, account.ErrDuplicateUser
+	}
+	if err != nil {
+		return account.User{}, err
+	}
+	return u, nil
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	cmd, err := r.pool.Exec(ctx, `UPDATE users SET password_hash = $2 WHERE id = $1`, id, hash)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return account.ErrUnknownUser
+	}
+	return nil
+}
+
+func (r *Repository) UpdateRole(ctx context.Context, id int64, role account.Role) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if role != account.RoleAdmin {
+		if err := keepAnAdmin(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	cmd, err := tx.Exec(ctx, `UPDATE users SET role = $2 WHERE id = $1`, id, role)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return account.ErrUnknownUser
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, id int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := keepAnAdmin(ctx, tx, id); err != nil {
+		return err
+	}
+	cmd, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if cmd.RowsAffected() == 0 {
+		return account.ErrUnknownUser
+	}
+	return tx.Commit(ctx)
+}
+
+// keepAnAdmin fails with account.ErrLastAdmin if the user with the given ID
+// is the only admin. It locks the admin rows until tx ends, so concurrent
+// removals and demotions take turns instead of each counting on another
+// admin to stay.
+func keepAnAdmin(ctx context.Context, tx pgx.Tx, id int64) error {
+	rows, err := tx.Query(ctx, `SELECT id FROM users WHERE role = $1 ORDER BY id FOR UPDATE`, account.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	admins, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return err
+	}
+	if len(admins) == 1 && admins[0] == id {
+		return account.ErrLastAdmin
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pfg/internal/pack"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// changeCatalog runs apply in a transaction and, if it reports a change,
+// records the catalog's pack sizes as a new version. Versions of a catalog
+// are numbered under a lock on the catalog row so concurrent changes cannot
+// claim the same number.
+func (r *Repository) changeCatalog(ctx context.Context, catalogID string, change pack.Change, apply func(tx pgx.Tx) (bool, error)) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	changed, err := apply(tx)
+	if err != nil || !changed {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM pack_catalogs WHERE id = $1 FOR UPDATE`, catalogID); err != nil {
+		return err
+	}
+
+	packs, err := packSizes(ctx, tx, catalogID)
+	if err != nil {
+		return err
+	}
+	snapshot, err := pack.MarshalSnapshot(packs)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO pack_catalog_versions (catalog_id, version, changed_by, change, sizes)
+		SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4
+		FROM pack_catalog_versions WHERE catalog_id = $1`,
+		catalogID, change.By, change.Description, snapshot)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+const versionColumns = `catalog_id, version, changed_at, changed_by, change, sizes`
+
+func scanVersion(row pgx.Row) (pack.CatalogVersion, error) {
+	var (
+		v        pack.CatalogVersion
+		snapshot []byte
+	)
+	if err := row.Scan(&v.Catalog, &v.Version, &v.ChangedAt, &v.ChangedBy, &v.Change, &snapshot); err != nil {
+		return pack.CatalogVersion{}, err
+	}
+
+	var err error
+	v.Sizes, err = pack.UnmarshalSnapshot(snapshot)
+	return v, err
+}
+
+func (r *Repository) GetCatalogVersions(ctx context.Context, catalogID string) ([]pack.CatalogVersion, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+versionColumns+` FROM pack_catalog_versions WHERE catalog_id = $1 ORDER BY version DESC`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []pack.CatalogVersion{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func (r *Repository) GetCatalogVersion(ctx context.Context, catalogID string, version int) (pack.CatalogVersion, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+versionColumns+` FROM pack_catalog_versions
+		WHERE catalog_id = $1 AND ($2 = 0 OR version = $2)
+		ORDER BY version DESC LIMIT 1`, catalogID, version)
+	v, err := scanVersion(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pack.CatalogVersion{}, pack.ErrUnknownVersion
+	}
+	return v, err
+}
+
+func (r *Repository) GetCatalogVersionAt(ctx context.Context, catalogID string, at time.Time) (pack.CatalogVersion, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT `+versionColumns+` FROM pack_catalog_versions
+		WHERE catalog_id = $1 AND changed_at <= $2
+		ORDER BY version DESC LIMIT 1`, catalogID, at)
+	v, err := scanVersion(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pack.CatalogVersion{}, pack.ErrUnknownVersion
+	}
+	return v, err
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"pfg/internal/bulk"
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// CalculateBulk calculates packs for every order in a CSV body with
+// order_id and quantity columns and streams back a CSV of the results as
+// each row is done. Rows that fail say why in their error column; the
+// request as a whole only fails when the file or its options are invalid.
+func (h *Handler) CalculateBulk(w http.ResponseWriter, r *http.Request) {
+	strategy, catalog := r.URL.Query().Get("strategy"), r.URL.Query().Get("catalog")
+	if _, err := pack.LookupStrategy(strategy); err != nil {
+		h.logger.Warn("Unknown strategy for CalculateBulk", zap.String("strategy", strategy))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orders, err := bulk.NewReader(r.Body)
+	if err != nil {
+		h.logger.Warn("Invalid orders file", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", bulk.Filename))
+	sum, err := bulk.Run(r.Context(), h.service, orders, w, pack.WithStrategy(strategy), pack.WithCatalog(catalog))
+	if err != nil {
+		// The results have started streaming, so the status is already sent.
+		h.logger.Error("Bulk calculation aborted", zap.Int("rows", sum.Rows), zap.Error(err))
+		return
+	}
+
+	if sum.Internal > 0 {
+		h.logger.Error("Bulk rows failed unexpectedly", zap.Int("rows", sum.Internal), zap.Error(sum.Cause))
+	}
+	h.logger.Info("Bulk calculation completed", zap.Int("rows", sum.Rows), zap.Int("failed", sum.Failed))
+}
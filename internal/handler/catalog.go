@@ -0,0 +1,230 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pfg/internal/catalogio"
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+type catalogEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (h *Handler) ListCatalogs(w http.ResponseWriter, r *http.Request) {
+	catalogs, err := h.service.ListCatalogs(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list catalogs", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]catalogEntry, len(catalogs))
+	for i, c := range catalogs {
+		entries[i] = catalogEntry{ID: c.ID, Name: c.Name}
+	}
+
+	h.logger.Info("Catalogs listed", zap.Int("count", len(entries)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *Handler) AddCatalog(w http.ResponseWriter, r *http.Request) {
+	var data catalogEntry
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.ID == "" {
+		h.logger.Warn("Invalid catalog input", zap.Error(err))
+		http.Error(w, "Invalid catalog", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.AddCatalog(r.Context(), pack.Catalog{ID: data.ID, Name: data.Name}); err != nil {
+		h.writeError(w, "Failed to add catalog", err, zap.String("id", data.ID))
+		return
+	}
+
+	h.logger.Info("Catalog added", zap.String("id", data.ID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteCatalog(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		h.logger.Warn("Missing id in delete catalog request")
+		http.Error(w, "Invalid catalog", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.RemoveCatalog(r.Context(), id); err != nil {
+		h.writeError(w, "Failed to delete catalog", err, zap.String("id", id))
+		return
+	}
+
+	h.logger.Info("Catalog deleted", zap.String("id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type catalogVersionEntry struct {
+	Version   int         `json:"version"`
+	ChangedAt time.Time   `json:"changedAt"`
+	ChangedBy string      `json:"changedBy"`
+	Change    string      `json:"change"`
+	Sizes     []sizeEntry `json:"sizes"`
+}
+
+type sizeEntry struct {
+	Size     int   `json:"size"`
+	UnitCost int64 `json:"unitCost"`
+	Inactive bool  `json:"inactive,omitempty"`
+}
+
+func (h *Handler) ListCatalogVersions(w http.ResponseWriter, r *http.Request) {
+	catalog := r.URL.Query().Get("catalog")
+	versions, err := h.service.CatalogHistory(r.Context(), catalog)
+	if err != nil {
+		h.writeError(w, "Failed to list catalog versions", err, zap.String("catalog", catalog))
+		return
+	}
+
+	entries := make([]catalogVersionEntry, len(versions))
+	for i, v := range versions {
+		entries[i] = catalogVersionEntry{
+			Version:   v.Version,
+			ChangedAt: v.ChangedAt,
+			ChangedBy: v.ChangedBy,
+			Change:    v.Change,
+			Sizes:     make([]sizeEntry, len(v.Sizes)),
+		}
+		for j, p := range v.Sizes {
+			entries[i].Sizes[j] = sizeEntry{Size: p.Size, UnitCost: p.UnitCost, Inactive: p.Inactive}
+		}
+	}
+
+	h.logger.Info("Catalog versions listed", zap.String("catalog", catalog), zap.Int("count", len(entries)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// ExportCatalog sends every pack size of a catalog as a CSV or JSON file,
+// JSON unless format says otherwise.
+func (h *Handler) ExportCatalog(w http.ResponseWriter, r *http.Request) {
+	catalog := r.URL.Query().Get("catalog")
+	format := catalogio.JSON
+	if name := r.URL.Query().Get("format"); name != "" {
+		var err error
+		if format, err = catalogio.ParseFormat(name); err != nil {
+			h.logger.Warn("Invalid export format", zap.String("format", name), zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	packs, err := h.service.ExportPacks(r.Context(), catalog)
+	if err != nil {
+		h.writeError(w, "Failed to export catalog", err, zap.String("catalog", catalog))
+		return
+	}
+
+	h.logger.Info("Catalog exported", zap.String("catalog", catalog), zap.String("format", format), zap.Int("count", len(packs)))
+	w.Header().Set("Content-Type", catalogio.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", catalogio.Filename(catalog, format)))
+	if err := catalogio.Write(w, format, packs); err != nil {
+		h.logger.Error("Failed to write catalog export", zap.Error(err))
+	}
+}
+
+type packChangeEntry struct {
+	Before packSizeEntry `json:"before"`
+	After  packSizeEntry `json:"after"`
+}
+
+type importResponse struct {
+	DryRun    bool              `json:"dryRun"`
+	Applied   bool              `json:"applied"`
+	Added     []packSizeEntry   `json:"added"`
+	Removed   []packSizeEntry   `json:"removed"`
+	Changed   []packChangeEntry `json:"changed"`
+	Unchanged []packSizeEntry   `json:"unchanged"`
+}
+
+func newImportResponse(diff pack.CatalogDiff, dryRun bool) importResponse {
+	entries := func(packs []pack.PackSize) []packSizeEntry {
+		out := make([]packSizeEntry, len(packs))
+		for i, p := range packs {
+			out[i] = newPackSizeEntry(p)
+		}
+		return out
+	}
+	resp := importResponse{
+		DryRun:    dryRun,
+		Applied:   !dryRun && diff.HasChanges(),
+		Added:     entries(diff.Added),
+		Removed:   entries(diff.Removed),
+		Changed:   make([]packChangeEntry, len(diff.Changed)),
+		Unchanged: entries(diff.Unchanged),
+	}
+	for i, c := range diff.Changed {
+		resp.Changed[i] = packChangeEntry{Before: newPackSizeEntry(c.Before), After: newPackSizeEntry(c.After)}
+	}
+	return resp
+}
+
+// ImportCatalog makes the pack sizes in the request body, a CSV or JSON
+// file, the whole set of a catalog. The format comes from the format
+// parameter or else the Content-Type. With dryRun set nothing is changed
+// and the response only shows what would be.
+func (h *Handler) ImportCatalog(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	catalog := q.Get("catalog")
+
+	format := catalogio.JSON
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		format = catalogio.CSV
+	}
+	if name := q.Get("format"); name != "" {
+		var err error
+		if format, err = catalogio.ParseFormat(name); err != nil {
+			h.logger.Warn("Invalid import format", zap.String("format", name), zap.Error(err))
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var dryRun bool
+	if v := q.Get("dryRun"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			h.logger.Warn("Invalid dryRun for import", zap.String("dryRun", v), zap.Error(err))
+			http.Error(w, "Invalid dryRun", http.StatusBadRequest)
+			return
+		}
+	}
+
+	packs, err := catalogio.Read(http.MaxBytesReader(w, r.Body, catalogio.MaxFileSize), format)
+	if err != nil {
+		h.logger.Warn("Invalid catalog file", zap.String("format", format), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var diff pack.CatalogDiff
+	if dryRun {
+		diff, err = h.service.PreviewImport(r.Context(), catalog, packs)
+	} else {
+		diff, err = h.service.ImportPacks(r.Context(), catalog, packs)
+	}
+	if err != nil {
+		h.writeError(w, "Failed to import catalog", err, zap.String("catalog", catalog))
+		return
+	}
+
+	resp := newImportResponse(diff, dryRun)
+	h.logger.Info("Catalog imported", zap.String("catalog", catalog), zap.Bool("dryRun", dryRun), zap.Bool("applied", resp.Applied))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"pfg/internal/account"
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// errorStatus maps the kinds of pack and account errors to HTTP status
+// codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, pack.ErrInvalid), errors.Is(err, account.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, pack.ErrNotFound), errors.Is(err, account.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, pack.ErrDuplicate), errors.Is(err, account.ErrDuplicate):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError logs a failed request, as a warning when the client is to
+// blame, and sends err with the status for its kind. Errors that are not
+// the client's doing are answered with msg alone; their details are only
+// logged.
+func (h *Handler) writeError(w http.ResponseWriter, msg string, err error, fields ...zap.Field) {
+	status := errorStatus(err)
+	fields = append(fields, zap.Error(err))
+	if status == http.StatusInternalServerError {
+		h.logger.Error(msg, fields...)
+		http.Error(w, msg, status)
+		return
+	}
+	h.logger.Warn(msg, fields...)
+	http.Error(w, err.Error(), status)
+}
+
+// writeCalculateError logs a failed calculation, as a warning when the
+// request is to blame, and answers with the status and message for err.
+func (h *Handler) writeCalculateError(w http.ResponseWriter, msg string, err error, fields ...zap.Field) {
+	status, body := calculateError(err)
+	fields = append(fields, zap.Error(err))
+	if status >= http.StatusInternalServerError {
+		h.logger.Error(msg, fields...)
+	} else {
+		h.logger.Warn(msg, fields...)
+	}
+	http.Error(w, body, status)
+}
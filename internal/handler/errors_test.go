@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pfg/internal/account"
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestCalculateError(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		status int
+		msg    string
+	}{
+		{context.Canceled, http.StatusServiceUnavailable, "The calculation was cancelled"},
+		{fmt.Errorf("filling table: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, "The calculation took too long"},
+		{&pack.TooExpensiveError{Field: "work", Value: 2, Limit: 1}, http.StatusUnprocessableEntity, "calculation too expensive: work 2 exceeds limit 1"},
+		{pack.ErrInsufficientStock, http.StatusUnprocessableEntity, "not enough packs in stock"},
+		{pack.ErrNoPackSizes, http.StatusUnprocessableEntity, "no pack sizes available"},
+		{pack.ErrUnknownCatalog, http.StatusNotFound, "unknown catalog"},
+		{pack.ErrUnknownStrategy, http.StatusBadRequest, "unknown strategy"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "Failed to calculate packs"},
+	} {
+		status, msg := calculateError(tt.err)
+		assert.Equal(t, tt.status, status, tt.err.Error())
+		assert.Equal(t, tt.msg, msg, tt.err.Error())
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		status int
+	}{
+		{pack.ErrUnknownPackSize, http.StatusNotFound},
+		{pack.ErrDuplicateCatalog, http.StatusConflict},
+		{account.ErrUnknownUser, http.StatusNotFound},
+		{account.ErrDuplicateUser, http.StatusConflict},
+		{account.ErrLastAdmin, http.StatusBadRequest},
+		{errors.New("connection refused"), http.StatusInternalServerError},
+	} {
+		assert.Equal(t, tt.status, errorStatus(tt.err), tt.err.Error())
+	}
+}
+
+// Internal errors are logged but not shown to the client.
+func TestWriteError(t *testing.T) {
+	h := &Handler{logger: zap.NewNop()}
+
+	rec := httptest.NewRecorder()
+	h.writeError(rec, "Failed to list orders", errors.New(`pq: relation "pack_orders" does not exist`))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "Failed to list orders\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	h.writeError(rec, "Failed to list stock", pack.ErrUnknownCatalog)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "unknown catalog\n", rec.Body.String())
+}
@@ -3,30 +3,51 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
+	"pfg/internal/account"
+	"pfg/internal/auth"
+	"pfg/internal/lifecycle"
 	"pfg/internal/pack"
 
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	service *pack.Service
-	logger  *zap.Logger
+	service  *pack.Service
+	orders   *lifecycle.Service
+	accounts *account.Service
+	logger   *zap.Logger
 }
 
-func NewHandler(service *pack.Service, logger *zap.Logger) *Handler {
-	return &Handler{service: service, logger: logger}
+func NewHandler(service *pack.Service, orders *lifecycle.Service, accounts *account.Service, logger *zap.Logger) *Handler {
+	return &Handler{service: service, orders: orders, accounts: accounts, logger: logger}
 }
 
 type orderRequest struct {
-	Quantity int `json:"quantity"`
+	Quantity int    `json:"quantity"`
+	Strategy string `json:"strategy,omitempty"`
+	Catalog  string `json:"catalog,omitempty"`
+	// Confirm takes the resulting packs out of stock.
+	Confirm bool `json:"confirm,omitempty"`
+	// Alternatives asks for the N best distinct combinations.
+	Alternatives int `json:"alternatives,omitempty"`
+	// Explain asks for a trace of how the result was chosen.
+	Explain bool `json:"explain,omitempty"`
+	// Version and AsOf run the calculation against an earlier version of
+	// the catalog, by number or by RFC 3339 timestamp.
+	Version int    `json:"version,omitempty"`
+	AsOf    string `json:"asOf,omitempty"`
 }
 
 type packEntry struct {
-	Size  int `json:"size"`
-	Count int `json:"count"`
+	Size  int   `json:"size"`
+	Count int   `json:"count"`
+	Cost  int64 `json:"cost"`
+	packDetails
 }
 
 type packResponse struct {
@@ -34,7 +55,37 @@ type packResponse struct {
 	Fulfilled   int         `json:"fulfilled"`
 	Overpacked  int         `json:"overpacked"`
 	TotalPacks  int         `json:"totalPacks"`
+	Strategy    string      `json:"strategy"`
+	Catalog     string      `json:"catalog"`
+	Version     int         `json:"catalogVersion"`
+	TotalCost   int64       `json:"totalCost"`
+	Confirmed   bool        `json:"confirmed"`
+	OrderID     int64       `json:"orderId,omitempty"`
 	PackDetails []packEntry `json:"packs"`
+
+	Alternatives []packResponse       `json:"alternatives,omitempty"`
+	Explanation  *explanationResponse `json:"explanation,omitempty"`
+}
+
+type totalTrace struct {
+	Items     int    `json:"items"`
+	Reachable bool   `json:"reachable"`
+	Packs     int    `json:"packs,omitempty"`
+	Cost      int64  `json:"cost,omitempty"`
+	Status    string `json:"status"`
+}
+
+type explanationResponse struct {
+	Summary    []string       `json:"summary"`
+	Unit       int            `json:"unit"`
+	Folded     int            `json:"folded"`
+	FoldSize   int            `json:"foldSize,omitempty"`
+	SearchFrom int            `json:"searchFrom"`
+	SearchTo   int            `json:"searchTo"`
+	Totals     []totalTrace   `json:"totals"`
+	Truncated  bool           `json:"truncated"`
+	Ties       []packResponse `json:"ties"`
+	TieBreak   string         `json:"tieBreak"`
 }
 
 func (h *Handler) CalculatePacks(w http.ResponseWriter, r *http.Request) {
@@ -45,38 +96,134 @@ func (h *Handler) CalculatePacks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	result, err := h.service.Calculate(r.Context(), req.Quantity)
+	var asOf time.Time
+	if req.AsOf != "" {
+		var err error
+		if asOf, err = time.Parse(time.RFC3339, req.AsOf); err != nil {
+			h.logger.Warn("Invalid asOf for CalculatePacks", zap.String("asOf", req.AsOf), zap.Error(err))
+			http.Error(w, "Invalid asOf, expected an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Version < 0 {
+		h.logger.Warn("Invalid version for CalculatePacks", zap.Int("version", req.Version))
+		http.Error(w, "Invalid version", http.StatusBadRequest)
+		return
+	}
+	if req.Confirm && !auth.Allowed(r, account.PermManageOrders) {
+		h.logger.Warn("Confirm requested without permission to manage orders")
+		http.Error(w, "Confirming orders needs the manage_orders permission", http.StatusForbidden)
+		return
+	}
+	if req.Confirm && (req.Version > 0 || !asOf.IsZero()) {
+		h.logger.Warn("Confirm requested for a historical calculation")
+		http.Error(w, "Historical calculations cannot be confirmed", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.Calculate(r.Context(), req.Quantity, pack.WithStrategy(req.Strategy), pack.WithCatalog(req.Catalog), pack.WithVersion(req.Version), pack.WithAsOf(asOf), pack.WithAlternatives(req.Alternatives), pack.WithExplain(req.Explain))
 	if err != nil {
-		h.logger.Error("Failed to calculate packs", zap.Error(err), zap.Int("quantity", req.Quantity))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writeCalculateError(w, "Failed to calculate packs", err, zap.Int("quantity", req.Quantity))
 		return
 	}
 
+	resp := newPackResponse(result)
+	if req.Confirm {
+		order, err := h.orders.Confirm(r.Context(), result)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, pack.ErrInsufficientStock) {
+				status = http.StatusConflict
+			}
+			h.logger.Warn("Failed to confirm order", zap.Error(err), zap.Int("quantity", req.Quantity))
+			http.Error(w, err.Error(), status)
+			return
+		}
+		resp.Confirmed, resp.OrderID = true, order.ID
+	}
+
+	h.logger.Info("Pack calculation completed", zap.Int("quantity", req.Quantity), zap.Any("response", resp))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newPackResponse(result pack.PackResult) packResponse {
 	resp := packResponse{
-		Requested:   req.Quantity,
+		Requested:   result.Requested,
 		Fulfilled:   result.TotalItems,
-		Overpacked:  result.TotalItems - req.Quantity,
+		Overpacked:  result.TotalItems - result.Requested,
 		TotalPacks:  result.TotalPacks,
+		Strategy:    result.Strategy,
+		Catalog:     result.Catalog,
+		Version:     result.CatalogVersion,
+		TotalCost:   result.TotalCost,
 		PackDetails: []packEntry{},
 	}
 
 	for size, count := range result.Packs {
-		resp.PackDetails = append(resp.PackDetails, packEntry{Size: size, Count: count})
+		resp.PackDetails = append(resp.PackDetails, packEntry{Size: size, Count: count, Cost: result.LineCosts[size], packDetails: newPackDetails(result.Details[size])})
 	}
 
-	h.logger.Info("Pack calculation completed", zap.Int("quantity", req.Quantity), zap.Any("response", resp))
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp)
+	for _, alt := range result.Alternatives {
+		resp.Alternatives = append(resp.Alternatives, newPackResponse(alt))
+	}
+
+	if e := result.Explanation; e != nil {
+		resp.Explanation = &explanationResponse{
+			Summary:    e.Summary,
+			Unit:       e.Unit,
+			Folded:     e.Folded,
+			FoldSize:   e.FoldSize,
+			SearchFrom: e.SearchFrom,
+			SearchTo:   e.SearchTo,
+			Totals:     make([]totalTrace, len(e.Totals)),
+			Truncated:  e.Truncated,
+			Ties:       []packResponse{},
+			TieBreak:   e.TieBreak,
+		}
+		for i, tr := range e.Totals {
+			resp.Explanation.Totals[i] = totalTrace{Items: tr.Items, Reachable: tr.Reachable, Packs: tr.Packs, Cost: tr.Cost, Status: tr.Status}
+		}
+		for _, tie := range e.Ties {
+			resp.Explanation.Ties = append(resp.Explanation.Ties, newPackResponse(tie))
+		}
+	}
+	return resp
+}
+
+// calculateError maps calculation errors to HTTP status codes and the
+// message to answer with. Errors that are not the request's doing get a
+// generic message; their details are only logged.
+func calculateError(err error) (int, string) {
+	var tooExpensive *pack.TooExpensiveError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable, "The calculation was cancelled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "The calculation took too long"
+	case errors.As(err, &tooExpensive), errors.Is(err, pack.ErrNoExactMatch), errors.Is(err, pack.ErrInsufficientStock), errors.Is(err, pack.ErrNoPackSizes):
+		return http.StatusUnprocessableEntity, err.Error()
+	case errors.Is(err, pack.ErrNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, pack.ErrInvalid):
+		return http.StatusBadRequest, err.Error()
+	}
+	return http.StatusInternalServerError, "Failed to calculate packs"
 }
 
 func (h *Handler) ListPackSizes(w http.ResponseWriter, r *http.Request) {
-	sizes, err := h.service.ListPacks(r.Context())
+	// Inactive and scheduled sizes are listed by ListPackSizesV2 only.
+	packs, err := h.service.AvailablePacks(r.Context(), r.URL.Query().Get("catalog"))
 	if err != nil {
-		h.logger.Error("Failed to list pack sizes", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		h.writeError(w, "Failed to list pack sizes", err)
 		return
 	}
 
+	sizes := make([]int, len(packs))
+	for i, p := range packs {
+		sizes[i] = p.Size
+	}
+
 	h.logger.Info("Pack sizes listed", zap.Int("count", len(sizes)))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(sizes)
@@ -84,16 +231,17 @@ func (h *Handler) ListPackSizes(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) AddPackSize(w http.ResponseWriter, r *http.Request) {
 	var data struct {
-		Size int `json:"size"`
+		Catalog  string `json:"catalog"`
+		Size     int    `json:"size"`
+		UnitCost int64  `json:"unitCost"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 {
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 || data.UnitCost < 0 {
 		h.logger.Warn("Invalid pack size input", zap.Error(err))
 		http.Error(w, "Invalid size", http.StatusBadRequest)
 		return
 	}
-	if err := h.service.AddPack(r.Context(), data.Size); err != nil {
-		h.logger.Error("Failed to add pack size", zap.Int("size", data.Size), zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.AddPack(r.Context(), data.Catalog, pack.PackSize{Size: data.Size, UnitCost: data.UnitCost}); err != nil {
+		h.writeError(w, "Failed to add pack size", err, zap.String("catalog", data.Catalog), zap.Int("size", data.Size))
 		return
 	}
 
@@ -101,6 +249,47 @@ func (h *Handler) AddPackSize(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// ReplacePackSizes swaps the whole set of pack sizes of a catalog at once.
+// Sizes that stay keep their cost and metadata.
+func (h *Handler) ReplacePackSizes(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Catalog string `json:"catalog"`
+		Sizes   []int  `json:"sizes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.logger.Warn("Invalid pack sizes input", zap.Error(err))
+		http.Error(w, "Invalid sizes", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.ReplacePacks(r.Context(), data.Catalog, data.Sizes); err != nil {
+		h.writeError(w, "Failed to replace pack sizes", err, zap.String("catalog", data.Catalog), zap.Ints("sizes", data.Sizes))
+		return
+	}
+
+	h.logger.Info("Pack sizes replaced", zap.Ints("sizes", data.Sizes))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UpdatePackCost(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Catalog  string `json:"catalog"`
+		Size     int    `json:"size"`
+		UnitCost int64  `json:"unitCost"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 || data.UnitCost < 0 {
+		h.logger.Warn("Invalid pack cost input", zap.Error(err))
+		http.Error(w, "Invalid size or cost", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetPackCost(r.Context(), data.Catalog, data.Size, data.UnitCost); err != nil {
+		h.writeError(w, "Failed to update pack cost", err, zap.Int("size", data.Size))
+		return
+	}
+
+	h.logger.Info("Pack cost updated", zap.Int("size", data.Size), zap.Int64("unitCost", data.UnitCost))
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) DeletePackSize(w http.ResponseWriter, r *http.Request) {
 	sizeStr := r.URL.Query().Get("size")
 	size, err := strconv.Atoi(sizeStr)
@@ -109,9 +298,8 @@ func (h *Handler) DeletePackSize(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid size", http.StatusBadRequest)
 		return
 	}
-	if err := h.service.RemovePack(context.Background(), size); err != nil {
-		h.logger.Error("Failed to delete pack size", zap.Int("size", size), zap.Error(err))
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := h.service.RemovePack(r.Context(), r.URL.Query().Get("catalog"), size); err != nil {
+		h.writeError(w, "Failed to delete pack size", err, zap.Int("size", size))
 		return
 	}
 
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+type stockEntry struct {
+	Catalog string `json:"catalog,omitempty"`
+	Size    int    `json:"size"`
+	OnHand  int    `json:"onHand"`
+}
+
+func (h *Handler) ListStock(w http.ResponseWriter, r *http.Request) {
+	stock, err := h.service.ListStock(r.Context(), r.URL.Query().Get("catalog"))
+	if err != nil {
+		h.writeError(w, "Failed to list stock", err)
+		return
+	}
+
+	entries := make([]stockEntry, 0, len(stock))
+	for size, onHand := range stock {
+		entries = append(entries, stockEntry{Size: size, OnHand: onHand})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Size < entries[j].Size })
+
+	h.logger.Info("Stock listed", zap.Int("count", len(entries)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *Handler) SetStock(w http.ResponseWriter, r *http.Request) {
+	var data stockEntry
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 || data.OnHand < 0 {
+		h.logger.Warn("Invalid stock input", zap.Error(err))
+		http.Error(w, "Invalid size or stock level", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetStock(r.Context(), data.Catalog, data.Size, data.OnHand); err != nil {
+		h.writeError(w, "Failed to set stock", err, zap.Int("size", data.Size))
+		return
+	}
+
+	h.logger.Info("Stock set", zap.Int("size", data.Size), zap.Int("onHand", data.OnHand))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UntrackStock(w http.ResponseWriter, r *http.Request) {
+	sizeStr := r.URL.Query().Get("size")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size <= 0 {
+		h.logger.Warn("Invalid size in untrack stock request", zap.String("raw", sizeStr), zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.UntrackStock(r.Context(), r.URL.Query().Get("catalog"), size); err != nil {
+		h.writeError(w, "Failed to untrack stock", err, zap.Int("size", size))
+		return
+	}
+
+	h.logger.Info("Stock untracked", zap.Int("size", size))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// maxOrderRequestSize caps the body of an order calculation. Lines are
+// counted against the limits once decoded; this keeps the decoding itself
+// bounded.
+const maxOrderRequestSize = 1 << 20
+
+type orderLineRequest struct {
+	Reference string `json:"reference"`
+	Catalog   string `json:"catalog,omitempty"`
+	Quantity  int    `json:"quantity"`
+}
+
+type multiOrderRequest struct {
+	Strategy string             `json:"strategy,omitempty"`
+	Catalog  string             `json:"catalog,omitempty"`
+	Lines    []orderLineRequest `json:"lines"`
+}
+
+// orderLineResponse carries either the breakdown of a line or the reason it
+// failed, along with the HTTP status the line would have got on its own.
+type orderLineResponse struct {
+	Reference string `json:"reference"`
+	*packResponse
+	Error  string `json:"error,omitempty"`
+	Status int    `json:"status"`
+}
+
+type orderTotals struct {
+	Requested  int   `json:"requested"`
+	Fulfilled  int   `json:"fulfilled"`
+	Overpacked int   `json:"overpacked"`
+	TotalPacks int   `json:"totalPacks"`
+	TotalCost  int64 `json:"totalCost"`
+}
+
+type multiOrderResponse struct {
+	Lines  []orderLineResponse `json:"lines"`
+	Totals orderTotals         `json:"totals"`
+	Failed int                 `json:"failed"`
+}
+
+// CalculateOrder calculates packs for every line of an order. Lines that
+// fail are reported individually and left out of the totals; the request as
+// a whole only fails when it is malformed.
+func (h *Handler) CalculateOrder(w http.ResponseWriter, r *http.Request) {
+	var req multiOrderRequest
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderRequestSize)).Decode(&req)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.logger.Warn("Request too large for CalculateOrder", zap.Int64("limit", tooLarge.Limit))
+		http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil || len(req.Lines) == 0 {
+		h.logger.Warn("Invalid request for CalculateOrder", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := pack.LookupStrategy(req.Strategy); err != nil {
+		h.logger.Warn("Unknown strategy for CalculateOrder", zap.String("strategy", req.Strategy))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	lines := make([]pack.OrderLine, len(req.Lines))
+	for i, l := range req.Lines {
+		lines[i] = pack.OrderLine{Reference: l.Reference, Catalog: l.Catalog, Quantity: l.Quantity}
+	}
+
+	order, err := h.service.CalculateOrder(r.Context(), lines, pack.WithStrategy(req.Strategy), pack.WithCatalog(req.Catalog))
+	if err != nil {
+		h.writeCalculateError(w, "Order rejected", err, zap.Int("lines", len(lines)))
+		return
+	}
+
+	resp := multiOrderResponse{
+		Lines: make([]orderLineResponse, len(order.Lines)),
+		Totals: orderTotals{
+			Requested:  order.Requested,
+			Fulfilled:  order.TotalItems,
+			Overpacked: order.Overage(),
+			TotalPacks: order.TotalPacks,
+			TotalCost:  order.TotalCost,
+		},
+		Failed: order.Failed,
+	}
+
+	for i, line := range order.Lines {
+		resp.Lines[i].Reference = line.Line.Reference
+		if line.Err != nil {
+			resp.Lines[i].Status, resp.Lines[i].Error = calculateError(line.Err)
+			h.logger.Warn("Order line rejected", zap.String("reference", line.Line.Reference), zap.Int("quantity", line.Line.Quantity), zap.Error(line.Err))
+			continue
+		}
+		lineResp := newPackResponse(line.Result)
+		resp.Lines[i].packResponse = &lineResp
+		resp.Lines[i].Status = http.StatusOK
+	}
+
+	h.logger.Info("Order calculation completed", zap.Int("lines", len(lines)), zap.Int("failed", order.Failed))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,241 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// packDetails is the metadata of a pack size as it appears in calculation
+// and order responses, left out where not recorded.
+type packDetails struct {
+	Label      string `json:"label,omitempty"`
+	SKU        string `json:"sku,omitempty"`
+	Length     int    `json:"lengthMm,omitempty"`
+	Width      int    `json:"widthMm,omitempty"`
+	Height     int    `json:"heightMm,omitempty"`
+	TareWeight int    `json:"tareWeightG,omitempty"`
+}
+
+func newPackDetails(p pack.PackSize) packDetails {
+	return packDetails{
+		Label:      p.Label,
+		SKU:        p.SKU,
+		Length:     p.Dimensions.Length,
+		Width:      p.Dimensions.Width,
+		Height:     p.Dimensions.Height,
+		TareWeight: p.TareWeight,
+	}
+}
+
+// packSizeEntry is a pack size with its metadata, as served and accepted
+// by /api/v2/packs.
+type packSizeEntry struct {
+	Catalog    string `json:"catalog,omitempty"`
+	Size       int    `json:"size"`
+	UnitCost   int64  `json:"unitCost"`
+	Label      string `json:"label"`
+	SKU        string `json:"sku"`
+	Length     int    `json:"lengthMm"`
+	Width      int    `json:"widthMm"`
+	Height     int    `json:"heightMm"`
+	TareWeight int    `json:"tareWeightG"`
+	Active     bool   `json:"active"`
+
+	AvailableFrom *time.Time `json:"availableFrom"`
+	RetiresAt     *time.Time `json:"retiresAt"`
+}
+
+func newPackSizeEntry(p pack.PackSize) packSizeEntry {
+	return packSizeEntry{
+		Size:       p.Size,
+		UnitCost:   p.UnitCost,
+		Label:      p.Label,
+		SKU:        p.SKU,
+		Length:     p.Dimensions.Length,
+		Width:      p.Dimensions.Width,
+		Height:     p.Dimensions.Height,
+		TareWeight: p.TareWeight,
+		Active:     !p.Inactive,
+
+		AvailableFrom: optionalTime(p.Schedule.AvailableFrom),
+		RetiresAt:     optionalTime(p.Schedule.RetiresAt),
+	}
+}
+
+func (e packSizeEntry) packSize() pack.PackSize {
+	return pack.PackSize{
+		Size:       e.Size,
+		UnitCost:   e.UnitCost,
+		Label:      e.Label,
+		SKU:        e.SKU,
+		Dimensions: pack.Dimensions{Length: e.Length, Width: e.Width, Height: e.Height},
+		TareWeight: e.TareWeight,
+		Inactive:   !e.Active,
+		Schedule:   e.schedule(),
+	}
+}
+
+func (e packSizeEntry) schedule() pack.Schedule {
+	var s pack.Schedule
+	if e.AvailableFrom != nil {
+		s.AvailableFrom = *e.AvailableFrom
+	}
+	if e.RetiresAt != nil {
+		s.RetiresAt = *e.RetiresAt
+	}
+	return s
+}
+
+// optionalTime gives nil for the zero time, which JSON shows as null.
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// ListPackSizesV2 lists every pack size of a catalog, active or not, as
+// objects with their cost, metadata and schedule, where ListPackSizes only
+// gives the sizes in use right now.
+func (h *Handler) ListPackSizesV2(w http.ResponseWriter, r *http.Request) {
+	packs, err := h.service.ListPacks(r.Context(), r.URL.Query().Get("catalog"))
+	if err != nil {
+		h.writeError(w, "Failed to list pack sizes", err)
+		return
+	}
+
+	entries := make([]packSizeEntry, len(packs))
+	for i, p := range packs {
+		entries[i] = newPackSizeEntry(p)
+	}
+
+	h.logger.Info("Pack sizes listed", zap.Int("count", len(entries)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AddPackSizeV2 adds a pack size together with its metadata. It is active
+// unless the request says otherwise.
+func (h *Handler) AddPackSizeV2(w http.ResponseWriter, r *http.Request) {
+	data := packSizeEntry{Active: true}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 {
+		h.logger.Warn("Invalid pack size input", zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.AddPack(r.Context(), data.Catalog, data.packSize()); err != nil {
+		h.writeError(w, "Failed to add pack size", err, zap.String("catalog", data.Catalog), zap.Int("size", data.Size))
+		return
+	}
+
+	h.logger.Info("Pack size added", zap.Int("size", data.Size))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdatePackDetails replaces the label, SKU, dimensions and tare weight of
+// a pack size. Its cost, schedule and whether it is active are left alone.
+func (h *Handler) UpdatePackDetails(w http.ResponseWriter, r *http.Request) {
+	var data packSizeEntry
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 {
+		h.logger.Warn("Invalid pack details input", zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SetPackDetails(r.Context(), data.Catalog, data.packSize()); err != nil {
+		h.writeError(w, "Failed to update pack details", err, zap.String("catalog", data.Catalog), zap.Int("size", data.Size))
+		return
+	}
+
+	h.logger.Info("Pack details updated", zap.Int("size", data.Size))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type packSizeRef struct {
+	Catalog string `json:"catalog"`
+	Size    int    `json:"size"`
+}
+
+func (h *Handler) ActivatePackSize(w http.ResponseWriter, r *http.Request) {
+	var data packSizeRef
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 {
+		h.logger.Warn("Invalid pack size to activate", zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.ActivatePack(r.Context(), data.Catalog, data.Size); err != nil {
+		h.writeError(w, "Failed to activate pack size", err, zap.String("catalog", data.Catalog), zap.Int("size", data.Size))
+		return
+	}
+
+	h.logger.Info("Pack size activated", zap.Int("size", data.Size))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeactivatePackSize(w http.ResponseWriter, r *http.Request) {
+	var data packSizeRef
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 {
+		h.logger.Warn("Invalid pack size to deactivate", zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.DeactivatePack(r.Context(), data.Catalog, data.Size); err != nil {
+		h.writeError(w, "Failed to deactivate pack size", err, zap.String("catalog", data.Catalog), zap.Int("size", data.Size))
+		return
+	}
+
+	h.logger.Info("Pack size deactivated", zap.Int("size", data.Size))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SchedulePackSize sets when a pack size becomes available and when it
+// retires. Times left out or null are open ended, so an empty schedule
+// clears it.
+func (h *Handler) SchedulePackSize(w http.ResponseWriter, r *http.Request) {
+	var data packSizeEntry
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data.Size <= 0 {
+		h.logger.Warn("Invalid pack schedule input", zap.Error(err))
+		http.Error(w, "Invalid size or schedule", http.StatusBadRequest)
+		return
+	}
+	if err := h.service.SchedulePack(r.Context(), data.Catalog, data.Size, data.schedule()); err != nil {
+		h.writeError(w, "Failed to schedule pack size", err, zap.String("catalog", data.Catalog), zap.Int("size", data.Size))
+		return
+	}
+
+	h.logger.Info("Pack size scheduled", zap.Int("size", data.Size))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type upcomingChange struct {
+	At     time.Time `json:"at"`
+	Size   int       `json:"size"`
+	Change string    `json:"change"` // "available" or "retire"
+}
+
+// ListUpcomingChanges lists the scheduled changes to a catalog that lie
+// ahead, soonest first.
+func (h *Handler) ListUpcomingChanges(w http.ResponseWriter, r *http.Request) {
+	changes, err := h.service.UpcomingChanges(r.Context(), r.URL.Query().Get("catalog"))
+	if err != nil {
+		h.logger.Error("Failed to list upcoming changes", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]upcomingChange, len(changes))
+	for i, c := range changes {
+		entries[i] = upcomingChange{At: c.At, Size: c.Size, Change: "retire"}
+		if c.Available {
+			entries[i].Change = "available"
+		}
+	}
+
+	h.logger.Info("Upcoming changes listed", zap.Int("count", len(entries)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
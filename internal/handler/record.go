@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type orderRecordResponse struct {
+	ID             int64       `json:"id"`
+	CreatedAt      time.Time   `json:"createdAt"`
+	RequestedBy    string      `json:"requestedBy"`
+	Catalog        string      `json:"catalog"`
+	CatalogVersion int         `json:"catalogVersion"`
+	CatalogSizes   []sizeEntry `json:"catalogSizes"`
+	Strategy       string      `json:"strategy"`
+	Requested      int         `json:"requested"`
+	Fulfilled      int         `json:"fulfilled"`
+	Overpacked     int         `json:"overpacked"`
+	TotalPacks     int         `json:"totalPacks"`
+	TotalCost      int64       `json:"totalCost"`
+	PackDetails    []packEntry `json:"packs"`
+
+	State   string               `json:"state"`
+	History []transitionResponse `json:"history,omitempty"`
+}
+
+type transitionResponse struct {
+	From string    `json:"from,omitempty"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+	By   string    `json:"by"`
+}
+
+func newOrderRecordResponse(st lifecycle.Status) orderRecordResponse {
+	o := st.Order
+	resp := orderRecordResponse{
+		ID:             o.ID,
+		CreatedAt:      o.CreatedAt,
+		RequestedBy:    o.RequestedBy,
+		Catalog:        o.Catalog,
+		CatalogVersion: o.CatalogVersion,
+		CatalogSizes:   make([]sizeEntry, len(o.Sizes)),
+		Strategy:       o.Strategy,
+		Requested:      o.Requested,
+		Fulfilled:      o.TotalItems,
+		Overpacked:     o.Overage(),
+		TotalPacks:     o.TotalPacks,
+		TotalCost:      o.TotalCost,
+		PackDetails:    []packEntry{},
+		State:          string(st.State),
+	}
+
+	sizes := map[int]pack.PackSize{}
+	for i, p := range o.Sizes {
+		resp.CatalogSizes[i] = sizeEntry{Size: p.Size, UnitCost: p.UnitCost, Inactive: p.Inactive}
+		sizes[p.Size] = p
+	}
+	for size, count := range o.Packs {
+		p := sizes[size]
+		resp.PackDetails = append(resp.PackDetails, packEntry{Size: size, Count: count, Cost: int64(count) * p.UnitCost, packDetails: newPackDetails(p)})
+	}
+	for _, t := range st.History {
+		resp.History = append(resp.History, transitionResponse{From: string(t.From), To: string(t.To), At: t.At, By: t.By})
+	}
+	return resp
+}
+
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.orders.Status(r.Context(), id)
+	if err != nil {
+		h.writeError(w, "Failed to get order", err, zap.Int64("id", id))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newOrderRecordResponse(order))
+}
+
+// ListOrders lists confirmed orders, newest first, filtered by the catalog,
+// requestedBy, from and to (RFC 3339) and limit query parameters.
+func (h *Handler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := pack.OrderFilter{Catalog: q.Get("catalog"), RequestedBy: q.Get("requestedBy")}
+
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := q.Get(param.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			h.logger.Warn("Invalid time filter for ListOrders", zap.String(param.name, raw), zap.Error(err))
+			http.Error(w, "Invalid "+param.name+", expected an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*param.dst = t
+	}
+
+	if raw := q.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			h.logger.Warn("Invalid limit for ListOrders", zap.String("raw", raw), zap.Error(err))
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	orders, err := h.orders.List(r.Context(), filter)
+	if err != nil {
+		h.writeError(w, "Failed to list orders", err)
+		return
+	}
+
+	resp := make([]orderRecordResponse, len(orders))
+	for i, o := range orders {
+		resp[i] = newOrderRecordResponse(o)
+	}
+
+	h.logger.Info("Orders listed", zap.Int("count", len(resp)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// parseOrderID reads the order ID from the URL, writing a 400 response when
+// it is not valid.
+func (h *Handler) parseOrderID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		h.logger.Warn("Invalid order id", zap.String("raw", idStr), zap.Error(err))
+		http.Error(w, "Invalid order id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// QuoteOrder calculates packs and stores the result as a quoted order
+// without touching stock.
+func (h *Handler) QuoteOrder(w http.ResponseWriter, r *http.Request) {
+	var req orderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Quantity <= 0 {
+		h.logger.Warn("Invalid request for QuoteOrder", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.orders.Quote(r.Context(), req.Quantity, pack.WithStrategy(req.Strategy), pack.WithCatalog(req.Catalog))
+	if err != nil {
+		h.writeCalculateError(w, "Failed to quote order", err, zap.Int("quantity", req.Quantity))
+		return
+	}
+
+	h.logger.Info("Order quoted", zap.Int64("id", st.ID), zap.Int("quantity", req.Quantity))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newOrderRecordResponse(st))
+}
+
+// AdvanceOrder moves an order to the state given in the request body.
+func (h *Handler) AdvanceOrder(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseOrderID(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Warn("Invalid request for AdvanceOrder", zap.Error(err))
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	to, err := lifecycle.ParseState(req.State)
+	if err != nil {
+		h.logger.Warn("Unknown order state", zap.String("state", req.State))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	st, err := h.orders.Advance(r.Context(), id, to)
+	switch {
+	case errors.Is(err, pack.ErrUnknownOrder):
+		h.logger.Warn("Order to advance not found", zap.Int64("id", id))
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, lifecycle.ErrInvalidTransition), errors.Is(err, lifecycle.ErrStaleState), errors.Is(err, pack.ErrInsufficientStock):
+		h.logger.Warn("Order transition rejected", zap.Int64("id", id), zap.String("to", req.State), zap.Error(err))
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		h.writeError(w, "Failed to advance order", err, zap.Int64("id", id), zap.String("to", req.State))
+		return
+	}
+
+	h.logger.Info("Order advanced", zap.Int64("id", id), zap.String("state", req.State))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newOrderRecordResponse(st))
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pfg/internal/account"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// userEntry is a user account as served by /api/users. Password hashes
+// never leave the server.
+type userEntry struct {
+	ID          int64                `json:"id"`
+	Email       string               `json:"email"`
+	Role        account.Role         `json:"role"`
+	Permissions []account.Permission `json:"permissions"`
+	CreatedAt   time.Time            `json:"createdAt"`
+}
+
+func newUserEntry(u account.User) userEntry {
+	return userEntry{ID: u.ID, Email: u.Email, Role: u.Role, Permissions: u.Role.Permissions(), CreatedAt: u.CreatedAt}
+}
+
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.accounts.ListUsers(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to list users", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]userEntry, len(users))
+	for i, u := range users {
+		entries[i] = newUserEntry(u)
+	}
+
+	h.logger.Info("Users listed", zap.Int("count", len(entries)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// AddUser adds a user account, as a viewer unless the request gives
+// another role.
+func (h *Handler) AddUser(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Email    string       `json:"email"`
+		Password string       `json:"password"`
+		Role     account.Role `json:"role"`
+	}{Role: account.RoleViewer}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.logger.Warn("Invalid user input", zap.Error(err))
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+	u, err := h.accounts.AddUser(r.Context(), data.Email, data.Password, data.Role)
+	if err != nil {
+		h.writeError(w, "Failed to add user", err, zap.String("email", data.Email))
+		return
+	}
+
+	h.logger.Info("User added", zap.Int64("id", u.ID), zap.String("email", u.Email), zap.String("role", string(u.Role)))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newUserEntry(u))
+}
+
+// UpdateUser sets a new password, a new role, or both. Fields left out are
+// not changed.
+func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var data struct {
+		Password string       `json:"password"`
+		Role     account.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		h.logger.Warn("Invalid user update", zap.Error(err))
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+	if data.Password != "" {
+		if err := h.accounts.SetPassword(r.Context(), id, data.Password); err != nil {
+			h.writeError(w, "Failed to set password", err, zap.Int64("id", id))
+			return
+		}
+	}
+	if data.Role != "" {
+		if err := h.accounts.SetRole(r.Context(), id, data.Role); err != nil {
+			h.writeError(w, "Failed to change role", err, zap.Int64("id", id), zap.String("role", string(data.Role)))
+			return
+		}
+	}
+
+	h.logger.Info("User updated", zap.Int64("id", id), zap.Bool("password", data.Password != ""))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.accounts.RemoveUser(r.Context(), id); err != nil {
+		h.writeError(w, "Failed to delete user", err, zap.Int64("id", id))
+		return
+	}
+
+	h.logger.Info("User deleted", zap.Int64("id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) parseUserID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		h.logger.Warn("Invalid user id", zap.String("raw", idStr), zap.Error(err))
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,120 @@
+package html
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"pfg/internal/bulk"
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+func (h *HTMLHandler) RenderBulkForm(w http.ResponseWriter, r *http.Request) {
+	h.renderBulkForm(w, r, "")
+}
+
+func (h *HTMLHandler) renderBulkForm(w http.ResponseWriter, r *http.Request, errMsg string) {
+	catalogs, err := h.service.ListCatalogs(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to load catalogs", zap.Error(err))
+		http.Error(w, "Failed to load catalogs", http.StatusInternalServerError)
+		return
+	}
+
+	can, email := sessionFromCookie(r)
+	err = h.templates.ExecuteTemplate(w, "bulk.html", map[string]interface{}{
+		"catalogs":   catalogs,
+		"strategies": pack.Strategies(),
+		"strategy":   pack.DefaultStrategy,
+		"error":      errMsg,
+		"Path":       r.URL.Path,
+		"Can":        can,
+		"UserEmail":  email,
+	})
+	if err != nil {
+		h.logger.Error("Failed to render bulk page", zap.Error(err))
+		http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+	}
+}
+
+// HandleBulkCalculate calculates every order in an uploaded CSV file and
+// sends the results back as a download. The upload is read part by part
+// rather than parsed as a whole, so the catalog and strategy fields must
+// come before the file, as they do on the form, and the file streams
+// through without being stored.
+func (h *HTMLHandler) HandleBulkCalculate(w http.ResponseWriter, r *http.Request) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		h.logger.Warn("Invalid form on BulkCalculate", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	var catalog, strategy string
+	var file *multipart.Part
+	for file == nil {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			h.logger.Warn("Invalid form on BulkCalculate", zap.Error(err))
+			http.Error(w, "Invalid form", http.StatusBadRequest)
+			return
+		}
+		switch part.FormName() {
+		case "file":
+			file = part
+		case "catalog", "strategy":
+			value, err := io.ReadAll(io.LimitReader(part, 256))
+			if err != nil {
+				h.logger.Warn("Invalid form on BulkCalculate", zap.Error(err))
+				http.Error(w, "Invalid form", http.StatusBadRequest)
+				return
+			}
+			if part.FormName() == "catalog" {
+				catalog = string(value)
+			} else {
+				strategy = string(value)
+			}
+		}
+	}
+	if file == nil {
+		h.logger.Warn("Missing orders file")
+		w.WriteHeader(http.StatusBadRequest)
+		h.renderBulkForm(w, r, "Please choose a CSV file of orders.")
+		return
+	}
+
+	if _, err := pack.LookupStrategy(strategy); err != nil {
+		h.logger.Warn("Unknown strategy for BulkCalculate", zap.String("strategy", strategy))
+		w.WriteHeader(http.StatusBadRequest)
+		h.renderBulkForm(w, r, "Please choose one of the listed strategies.")
+		return
+	}
+
+	orders, err := bulk.NewReader(file)
+	if err != nil {
+		h.logger.Warn("Invalid orders file", zap.String("file", file.FileName()), zap.Error(err))
+		w.WriteHeader(http.StatusBadRequest)
+		h.renderBulkForm(w, r, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", bulk.Filename))
+	sum, err := bulk.Run(r.Context(), h.service, orders, w, pack.WithStrategy(strategy), pack.WithCatalog(catalog))
+	if err != nil {
+		h.logger.Error("Bulk calculation aborted", zap.String("file", file.FileName()), zap.Int("rows", sum.Rows), zap.Error(err))
+		return
+	}
+
+	if sum.Internal > 0 {
+		h.logger.Error("Bulk rows failed unexpectedly", zap.String("file", file.FileName()), zap.Int("rows", sum.Internal), zap.Error(sum.Cause))
+	}
+	h.logger.Info("Bulk calculation completed", zap.String("file", file.FileName()), zap.Int("rows", sum.Rows), zap.Int("failed", sum.Failed))
+}
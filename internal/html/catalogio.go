@@ -0,0 +1,132 @@
+package html
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"pfg/internal/catalogio"
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// importPreview is an uploaded catalog file waiting to be applied. The file
+// travels back with the confirmation so nothing is kept between requests.
+type importPreview struct {
+	File   string
+	Format string
+	Data   string
+	Diff   pack.CatalogDiff
+}
+
+func (h *HTMLHandler) HandleExportCatalog(w http.ResponseWriter, r *http.Request) {
+	catalog := formCatalog(r)
+	format, err := catalogio.ParseFormat(r.FormValue("format"))
+	if err != nil {
+		h.logger.Warn("Invalid export format", zap.String("format", r.FormValue("format")), zap.Error(err))
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	packs, err := h.service.ExportPacks(r.Context(), catalog)
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to export catalog", err, zap.String("catalog", catalog))
+		return
+	}
+
+	h.logger.Info("Catalog exported", zap.String("catalog", catalog), zap.String("format", format))
+	w.Header().Set("Content-Type", catalogio.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", catalogio.Filename(catalog, format)))
+	if err := catalogio.Write(w, format, packs); err != nil {
+		h.logger.Error("Failed to write catalog export", zap.Error(err))
+	}
+}
+
+// HandlePreviewImport reads an uploaded CSV or JSON file, told apart by its
+// extension, and shows how importing it would change the catalog.
+func (h *HTMLHandler) HandlePreviewImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, catalogio.MaxFileSize+1<<10)
+	if err := r.ParseMultipartForm(catalogio.MaxFileSize); err != nil {
+		h.logger.Warn("Invalid form on PreviewImport", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.logger.Warn("Missing catalog file", zap.Error(err))
+		http.Error(w, "Missing file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	format, err := catalogio.ParseFormat(strings.TrimPrefix(filepath.Ext(header.Filename), "."))
+	if err != nil {
+		h.logger.Warn("Invalid catalog file type", zap.String("file", header.Filename), zap.Error(err))
+		http.Error(w, "Upload a .csv or .json file", http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.logger.Warn("Failed to read catalog file", zap.Error(err))
+		http.Error(w, "Invalid file", http.StatusBadRequest)
+		return
+	}
+
+	packs, err := catalogio.Read(bytes.NewReader(data), format)
+	if err != nil {
+		h.rejectFile(w, r, err, zap.String("file", header.Filename))
+		return
+	}
+
+	preview := &importPreview{File: header.Filename, Format: format, Data: string(data)}
+	preview.Diff, err = h.service.PreviewImport(r.Context(), formCatalog(r), packs)
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to preview import", err, zap.String("file", header.Filename))
+		return
+	}
+
+	h.logger.Info("Catalog import previewed", zap.String("file", header.Filename), zap.Bool("changes", preview.Diff.HasChanges()))
+	h.renderPackPage(w, r, "", preview)
+}
+
+// HandleApplyImport imports the file confirmed on the preview.
+func (h *HTMLHandler) HandleApplyImport(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on ApplyImport", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	format, err := catalogio.ParseFormat(r.FormValue("format"))
+	if err != nil {
+		h.logger.Warn("Invalid import format", zap.String("format", r.FormValue("format")), zap.Error(err))
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	packs, err := catalogio.Read(strings.NewReader(r.FormValue("data")), format)
+	if err != nil {
+		h.rejectFile(w, r, err)
+		return
+	}
+	if _, err := h.service.ImportPacks(r.Context(), formCatalog(r), packs); err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to import catalog", err)
+		return
+	}
+
+	h.logger.Info("Catalog imported", zap.String("catalog", formCatalog(r)), zap.Int("count", len(packs)))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+// rejectFile shows why a catalog file could not be read on the pack page.
+func (h *HTMLHandler) rejectFile(w http.ResponseWriter, r *http.Request, err error, fields ...zap.Field) {
+	h.logger.Warn("Invalid catalog file", append(fields, zap.Error(err))...)
+	w.WriteHeader(http.StatusBadRequest)
+	h.renderPackList(w, r, err.Error())
+}
@@ -0,0 +1,87 @@
+package html
+
+import (
+	"net/http"
+	"net/url"
+
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// formCatalog returns the catalog named in the request, or the default one.
+func formCatalog(r *http.Request) string {
+	if id := r.FormValue("catalog"); id != "" {
+		return id
+	}
+	return pack.DefaultCatalog
+}
+
+// packsURL returns the pack management page of a catalog.
+func packsURL(catalog string) string {
+	if catalog == pack.DefaultCatalog {
+		return "/packs"
+	}
+	return "/packs?catalog=" + url.QueryEscape(catalog)
+}
+
+func (h *HTMLHandler) RenderCatalogList(w http.ResponseWriter, r *http.Request) {
+	h.renderCatalogList(w, r, "")
+}
+
+func (h *HTMLHandler) renderCatalogList(w http.ResponseWriter, r *http.Request, errMsg string) {
+	catalogs, err := h.service.ListCatalogs(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to load catalogs", zap.Error(err))
+		http.Error(w, "Failed to load catalogs", http.StatusInternalServerError)
+		return
+	}
+
+	can, email := sessionFromCookie(r)
+	err = h.templates.ExecuteTemplate(w, "catalogs.html", map[string]interface{}{
+		"catalogs":  catalogs,
+		"default":   pack.DefaultCatalog,
+		"error":     errMsg,
+		"Path":      r.URL.Path,
+		"Can":       can,
+		"UserEmail": email,
+	})
+	if err != nil {
+		h.logger.Error("Failed to render catalogs page", zap.Error(err))
+		http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTMLHandler) HandleAddCatalog(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on AddCatalog", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	c := pack.Catalog{ID: r.FormValue("id"), Name: r.FormValue("name")}
+	if err := h.service.AddCatalog(r.Context(), c); err != nil {
+		h.rejectForm(w, r, h.renderCatalogList, "Failed to add catalog", err, zap.String("id", c.ID))
+		return
+	}
+
+	h.logger.Info("Catalog added", zap.String("id", c.ID))
+	http.Redirect(w, r, "/catalogs", http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleDeleteCatalog(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on DeleteCatalog", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	id := r.FormValue("id")
+	if err := h.service.RemoveCatalog(r.Context(), id); err != nil {
+		h.rejectForm(w, r, h.renderCatalogList, "Failed to delete catalog", err, zap.String("id", id))
+		return
+	}
+
+	h.logger.Info("Catalog deleted", zap.String("id", id))
+	http.Redirect(w, r, "/catalogs", http.StatusSeeOther)
+}
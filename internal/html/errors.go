@@ -0,0 +1,41 @@
+package html
+
+import (
+	"errors"
+	"net/http"
+
+	"pfg/internal/account"
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// errorStatus maps the kinds of pack and account errors to HTTP status
+// codes.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, pack.ErrInvalid), errors.Is(err, account.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, pack.ErrNotFound), errors.Is(err, account.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, pack.ErrDuplicate), errors.Is(err, account.ErrDuplicate):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// rejectForm shows why a form was rejected by re-rendering its page with
+// the error and the status for its kind. Errors that are not the user's
+// doing fail the request instead.
+func (h *HTMLHandler) rejectForm(w http.ResponseWriter, r *http.Request, render func(w http.ResponseWriter, r *http.Request, errMsg string), msg string, err error, fields ...zap.Field) {
+	status := errorStatus(err)
+	fields = append(fields, zap.Error(err))
+	if status == http.StatusInternalServerError {
+		h.logger.Error(msg, fields...)
+		http.Error(w, msg, status)
+		return
+	}
+	h.logger.Warn(msg, fields...)
+	w.WriteHeader(status)
+	render(w, r, err.Error())
+}
@@ -1,13 +1,21 @@
 package html
 
 import (
+	"errors"
+	"fmt"
 	"html/template"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
+	"pfg/internal/account"
+	"pfg/internal/auth"
 	"pfg/internal/config"
 	"pfg/internal/jwt"
+	"pfg/internal/lifecycle"
 	"pfg/internal/pack"
 
 	"github.com/go-chi/jwtauth/v5"
@@ -16,6 +24,8 @@ import (
 
 type HTMLHandler struct {
 	service   *pack.Service
+	orders    *lifecycle.Service
+	accounts  *account.Service
 	templates *template.Template
 	config    *config.Config
 	logger    *zap.Logger
@@ -23,12 +33,16 @@ type HTMLHandler struct {
 
 func NewHTMLHandler(
 	service *pack.Service,
+	orders *lifecycle.Service,
+	accounts *account.Service,
 	templates *template.Template,
 	config *config.Config,
 	logger *zap.Logger,
 ) *HTMLHandler {
 	return &HTMLHandler{
 		service:   service,
+		orders:    orders,
+		accounts:  accounts,
 		templates: templates,
 		config:    config,
 		logger:    logger,
@@ -36,11 +50,11 @@ func NewHTMLHandler(
 }
 
 func (h *HTMLHandler) RenderWelcomePage(w http.ResponseWriter, r *http.Request) {
-	isAdmin, email := adminInfoFromCookie(r)
+	can, email := sessionFromCookie(r)
 	err := h.templates.ExecuteTemplate(w, "index.html", map[string]interface{}{
-		"Path":       r.URL.Path,
-		"IsLoggedIn": isAdmin,
-		"UserEmail":  email,
+		"Path":      r.URL.Path,
+		"Can":       can,
+		"UserEmail": email,
 	})
 	if err != nil {
 		h.logger.Error("Failed to render welcome page", zap.Error(err))
@@ -49,19 +63,65 @@ func (h *HTMLHandler) RenderWelcomePage(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *HTMLHandler) RenderPackList(w http.ResponseWriter, r *http.Request) {
-	sizes, err := h.service.ListPacks(r.Context())
+	h.renderPackList(w, r, "")
+}
+
+func (h *HTMLHandler) renderPackList(w http.ResponseWriter, r *http.Request, errMsg string) {
+	h.renderPackPage(w, r, errMsg, nil)
+}
+
+// renderPackPage renders the pack management page, with the preview of an
+// uploaded catalog file if there is one.
+func (h *HTMLHandler) renderPackPage(w http.ResponseWriter, r *http.Request, errMsg string, preview *importPreview) {
+	catalog := formCatalog(r)
+	catalogs, err := h.service.ListCatalogs(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to load catalogs", zap.Error(err))
+		http.Error(w, "Failed to load catalogs", http.StatusInternalServerError)
+		return
+	}
+
+	sizes, err := h.service.ListPacks(r.Context(), catalog)
 	if err != nil {
 		h.logger.Error("Failed to load packs", zap.Error(err))
 		http.Error(w, "Failed to load packs", http.StatusInternalServerError)
 		return
 	}
 
-	isAdmin, email := adminInfoFromCookie(r)
+	stock, err := h.service.ListStock(r.Context(), catalog)
+	if err != nil {
+		h.logger.Error("Failed to load stock", zap.Error(err))
+		http.Error(w, "Failed to load stock", http.StatusInternalServerError)
+		return
+	}
+
+	history, err := h.service.CatalogHistory(r.Context(), catalog)
+	if err != nil {
+		h.logger.Error("Failed to load catalog history", zap.Error(err))
+		http.Error(w, "Failed to load catalog history", http.StatusInternalServerError)
+		return
+	}
+
+	upcoming, err := h.service.UpcomingChanges(r.Context(), catalog)
+	if err != nil {
+		h.logger.Error("Failed to load upcoming changes", zap.Error(err))
+		http.Error(w, "Failed to load upcoming changes", http.StatusInternalServerError)
+		return
+	}
+
+	can, email := sessionFromCookie(r)
 	err = h.templates.ExecuteTemplate(w, "packs.html", map[string]interface{}{
-		"packs":      sizes,
-		"Path":       r.URL.Path,
-		"IsLoggedIn": isAdmin,
-		"UserEmail":  email,
+		"packs":     sizes,
+		"stock":     stock,
+		"history":   history,
+		"upcoming":  upcoming,
+		"preview":   preview,
+		"catalog":   catalog,
+		"catalogs":  catalogs,
+		"error":     errMsg,
+		"Path":      r.URL.Path,
+		"Can":       can,
+		"UserEmail": email,
 	})
 
 	if err != nil {
@@ -85,23 +145,175 @@ func (h *HTMLHandler) HandleAddPack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.AddPack(r.Context(), size)
+	unitCost, err := parseCents(r.FormValue("unit_cost"))
 	if err != nil {
-		h.logger.Warn("Duplicate or failed pack add", zap.Int("size", size), zap.Error(err))
-		sizes, _ := h.service.ListPacks(r.Context())
-		isAdmin, email := adminInfoFromCookie(r)
-		h.templates.ExecuteTemplate(w, "packs.html", map[string]interface{}{
-			"packs":      sizes,
-			"Path":       r.URL.Path,
-			"error":      err.Error(),
-			"IsLoggedIn": isAdmin,
-			"UserEmail":  email,
-		})
+		h.logger.Warn("Invalid unit cost value", zap.String("input", r.FormValue("unit_cost")), zap.Error(err))
+		http.Error(w, "Invalid unit cost", http.StatusBadRequest)
+		return
+	}
+
+	p, err := parseDetails(r)
+	if err != nil {
+		h.logger.Warn("Invalid pack details", zap.Error(err))
+		http.Error(w, "Invalid pack details", http.StatusBadRequest)
+		return
+	}
+	p.Size, p.UnitCost = size, unitCost
+
+	if p.Schedule, err = parseSchedule(r); err != nil {
+		h.logger.Warn("Invalid pack schedule", zap.Error(err))
+		http.Error(w, "Invalid schedule", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.AddPack(r.Context(), formCatalog(r), p)
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to add pack", err, zap.Int("size", size))
 		return
 	}
 
 	h.logger.Info("Pack added", zap.Int("size", size))
-	http.Redirect(w, r, "/packs", http.StatusSeeOther)
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+// HandleReplacePacks takes the whole set of pack sizes as one list,
+// separated by commas or spaces.
+func (h *HTMLHandler) HandleReplacePacks(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on ReplacePacks", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	fields := strings.FieldsFunc(r.FormValue("sizes"), func(c rune) bool { return c == ',' || unicode.IsSpace(c) })
+	sizes := make([]int, len(fields))
+	for i, f := range fields {
+		size, err := strconv.Atoi(f)
+		if err != nil {
+			h.logger.Warn("Invalid pack size value", zap.String("input", f), zap.Error(err))
+			http.Error(w, "Invalid size", http.StatusBadRequest)
+			return
+		}
+		sizes[i] = size
+	}
+
+	err := h.service.ReplacePacks(r.Context(), formCatalog(r), sizes)
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to replace pack sizes", err, zap.Ints("sizes", sizes))
+		return
+	}
+
+	h.logger.Info("Pack sizes replaced", zap.Ints("sizes", sizes))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleUpdatePackCost(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on UpdatePackCost", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	sizeStr := r.FormValue("size")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size <= 0 {
+		h.logger.Warn("Invalid pack size for cost update", zap.String("input", sizeStr), zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+
+	unitCost, err := parseCents(r.FormValue("unit_cost"))
+	if err != nil {
+		h.logger.Warn("Invalid unit cost value", zap.String("input", r.FormValue("unit_cost")), zap.Error(err))
+		http.Error(w, "Invalid unit cost", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.SetPackCost(r.Context(), formCatalog(r), size, unitCost)
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to update pack cost", err, zap.Int("size", size))
+		return
+	}
+
+	h.logger.Info("Pack cost updated", zap.Int("size", size), zap.Int64("unitCost", unitCost))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleUpdatePackDetails(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on UpdatePackDetails", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	sizeStr := r.FormValue("size")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size <= 0 {
+		h.logger.Warn("Invalid pack size for details update", zap.String("input", sizeStr), zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+
+	p, err := parseDetails(r)
+	if err != nil {
+		h.logger.Warn("Invalid pack details", zap.Error(err))
+		http.Error(w, "Invalid pack details", http.StatusBadRequest)
+		return
+	}
+	p.Size = size
+
+	err = h.service.SetPackDetails(r.Context(), formCatalog(r), p)
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to update pack details", err, zap.Int("size", size))
+		return
+	}
+
+	h.logger.Info("Pack details updated", zap.Int("size", size))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleSetStock(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on SetStock", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	sizeStr := r.FormValue("size")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size <= 0 {
+		h.logger.Warn("Invalid pack size for stock update", zap.String("input", sizeStr), zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+
+	// An empty value stops tracking stock for the size.
+	onHandStr := r.FormValue("on_hand")
+	if onHandStr == "" {
+		if err := h.service.UntrackStock(r.Context(), formCatalog(r), size); err != nil {
+			h.logger.Error("Failed to untrack stock", zap.Int("size", size), zap.Error(err))
+			http.Error(w, "Failed to update stock", http.StatusInternalServerError)
+			return
+		}
+		h.logger.Info("Stock untracked", zap.Int("size", size))
+		http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+		return
+	}
+
+	onHand, err := strconv.Atoi(onHandStr)
+	if err != nil || onHand < 0 {
+		h.logger.Warn("Invalid stock level", zap.String("input", onHandStr), zap.Error(err))
+		http.Error(w, "Invalid stock level", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.SetStock(r.Context(), formCatalog(r), size, onHand); err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to set stock", err, zap.Int("size", size))
+		return
+	}
+
+	h.logger.Info("Stock set", zap.Int("size", size), zap.Int("onHand", onHand))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
 }
 
 func (h *HTMLHandler) HandleDeletePack(w http.ResponseWriter, r *http.Request) {
@@ -119,19 +331,103 @@ func (h *HTMLHandler) HandleDeletePack(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.service.RemovePack(r.Context(), size)
+	err = h.service.RemovePack(r.Context(), formCatalog(r), size)
 	if err != nil {
-		h.logger.Error("Failed to delete pack", zap.Int("size", size), zap.Error(err))
-		http.Error(w, "Failed to delete pack", http.StatusInternalServerError)
+		h.rejectForm(w, r, h.renderPackList, "Failed to delete pack", err, zap.Int("size", size))
 		return
 	}
 
 	h.logger.Info("Pack deleted", zap.Int("size", size))
-	http.Redirect(w, r, "/packs", http.StatusSeeOther)
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleSchedulePack(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on SchedulePack", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	sizeStr := r.FormValue("size")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size <= 0 {
+		h.logger.Warn("Invalid pack size to schedule", zap.String("input", sizeStr), zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+
+	sched, err := parseSchedule(r)
+	if err != nil {
+		h.logger.Warn("Invalid pack schedule", zap.Error(err))
+		http.Error(w, "Invalid schedule", http.StatusBadRequest)
+		return
+	}
+
+	err = h.service.SchedulePack(r.Context(), formCatalog(r), size, sched)
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to schedule pack", err, zap.Int("size", size))
+		return
+	}
+
+	h.logger.Info("Pack scheduled", zap.Int("size", size))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleActivatePack(w http.ResponseWriter, r *http.Request) {
+	h.setPackActive(w, r, true)
+}
+
+func (h *HTMLHandler) HandleDeactivatePack(w http.ResponseWriter, r *http.Request) {
+	h.setPackActive(w, r, false)
+}
+
+func (h *HTMLHandler) setPackActive(w http.ResponseWriter, r *http.Request, active bool) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on SetPackActive", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	sizeStr := r.FormValue("size")
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size <= 0 {
+		h.logger.Warn("Invalid pack size to activate or deactivate", zap.String("input", sizeStr), zap.Error(err))
+		http.Error(w, "Invalid size", http.StatusBadRequest)
+		return
+	}
+
+	if active {
+		err = h.service.ActivatePack(r.Context(), formCatalog(r), size)
+	} else {
+		err = h.service.DeactivatePack(r.Context(), formCatalog(r), size)
+	}
+	if err != nil {
+		h.rejectForm(w, r, h.renderPackList, "Failed to change pack activation", err, zap.Int("size", size), zap.Bool("active", active))
+		return
+	}
+
+	h.logger.Info("Pack activation changed", zap.Int("size", size), zap.Bool("active", active))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+// calculateForm holds the values submitted on the calculate page so they
+// survive a round trip.
+type calculateForm struct {
+	Quantity     int
+	Strategy     string
+	Catalog      string
+	Confirm      bool
+	Version      int
+	Alternatives int
+	Explain      bool
 }
 
 func (h *HTMLHandler) RenderCalculateForm(w http.ResponseWriter, r *http.Request) {
-	var result *pack.PackResult
+	var (
+		result *pack.PackResult
+		order  *pack.Order
+	)
+	form := calculateForm{Strategy: pack.DefaultStrategy, Catalog: pack.DefaultCatalog}
 
 	if r.Method == http.MethodPost {
 		if err := r.ParseForm(); err != nil {
@@ -147,23 +443,88 @@ func (h *HTMLHandler) RenderCalculateForm(w http.ResponseWriter, r *http.Request
 			http.Error(w, "Invalid quantity", http.StatusBadRequest)
 			return
 		}
-
-		val, err := h.service.Calculate(r.Context(), qty)
+		form.Quantity = qty
+		if strategy := r.FormValue("strategy"); strategy != "" {
+			form.Strategy = strategy
+		}
+		form.Catalog = formCatalog(r)
+		if altStr := r.FormValue("alternatives"); altStr != "" {
+			alternatives, err := strconv.Atoi(altStr)
+			if err != nil {
+				h.logger.Warn("Invalid alternatives input", zap.String("input", altStr), zap.Error(err))
+				http.Error(w, "Invalid alternatives", http.StatusBadRequest)
+				return
+			}
+			form.Alternatives = alternatives
+		}
+		if versionStr := r.FormValue("version"); versionStr != "" {
+			version, err := strconv.Atoi(versionStr)
+			if err != nil || version < 0 {
+				h.logger.Warn("Invalid version input", zap.String("input", versionStr), zap.Error(err))
+				http.Error(w, "Invalid version", http.StatusBadRequest)
+				return
+			}
+			form.Version = version
+		}
+		form.Explain = r.FormValue("explain") != ""
+		// Only users who manage orders may take packs out of stock, and
+		// only against the current catalog.
+		form.Confirm = auth.Allowed(r, account.PermManageOrders) && form.Version == 0 && r.FormValue("confirm") != ""
+
+		val, err := h.service.Calculate(r.Context(), qty, pack.WithStrategy(form.Strategy), pack.WithCatalog(form.Catalog), pack.WithVersion(form.Version), pack.WithAlternatives(form.Alternatives), pack.WithExplain(form.Explain))
+		if msg, status := calculateErrorMessage(err); msg != "" {
+			h.logger.Warn("Calculation rejected", zap.Int("qty", qty), zap.Error(err))
+			w.WriteHeader(status)
+			h.renderCalculatePage(w, r, form, nil, nil, msg)
+			return
+		}
 		if err != nil {
 			h.logger.Error("Failed to calculate", zap.Int("qty", qty), zap.Error(err))
 			http.Error(w, "Failed to calculate packs", http.StatusInternalServerError)
 			return
 		}
 
+		if form.Confirm {
+			confirmed, err := h.orders.Confirm(r.Context(), val)
+			if errors.Is(err, pack.ErrInsufficientStock) {
+				h.logger.Warn("Stock changed before reservation", zap.Int("qty", qty), zap.Error(err))
+				w.WriteHeader(http.StatusConflict)
+				h.renderCalculatePage(w, r, form, nil, nil, "Stock changed while calculating. Please try again.")
+				return
+			} else if err != nil {
+				h.logger.Error("Failed to confirm order", zap.Int("qty", qty), zap.Error(err))
+				http.Error(w, "Failed to confirm order", http.StatusInternalServerError)
+				return
+			}
+			order = &confirmed.Order
+		}
+
 		result = &val
 		h.logger.Info("HTML pack calculation completed", zap.Int("quantity", qty), zap.Any("result", val))
 	}
 
-	isAdmin, email := adminInfoFromCookie(r)
-	err := h.templates.ExecuteTemplate(w, "calculate.html", map[string]interface{}{
+	h.renderCalculatePage(w, r, form, result, order, "")
+}
+
+func (h *HTMLHandler) renderCalculatePage(w http.ResponseWriter, r *http.Request, form calculateForm, result *pack.PackResult, order *pack.Order, errMsg string) {
+	catalogs, err := h.service.ListCatalogs(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to load catalogs", zap.Error(err))
+		http.Error(w, "Failed to load catalogs", http.StatusInternalServerError)
+		return
+	}
+
+	can, email := sessionFromCookie(r)
+	err = h.templates.ExecuteTemplate(w, "calculate.html", map[string]interface{}{
 		"result":     result,
+		"order":      order,
+		"form":       form,
+		"strategies": pack.Strategies(),
+		"catalogs":   catalogs,
+		"maxAlts":    pack.MaxAlternatives,
+		"error":      errMsg,
 		"Path":       r.URL.Path,
-		"IsLoggedIn": isAdmin,
+		"Can":        can,
 		"UserEmail":  email,
 	})
 	if err != nil {
@@ -172,10 +533,46 @@ func (h *HTMLHandler) RenderCalculateForm(w http.ResponseWriter, r *http.Request
 	}
 }
 
+// calculateErrorMessage turns calculation errors the user can act on into a
+// message for the calculate page and the status to send with it. Other
+// errors yield an empty message.
+func calculateErrorMessage(err error) (string, int) {
+	var tooExpensive *pack.TooExpensiveError
+	switch {
+	case errors.As(err, &tooExpensive) && tooExpensive.Field == "quantity":
+		return fmt.Sprintf("Quantity %d is too large. The maximum allowed quantity is %d.", tooExpensive.Value, tooExpensive.Limit), http.StatusUnprocessableEntity
+	case errors.As(err, &tooExpensive):
+		return "This calculation is too expensive with the current pack sizes. Please try a smaller quantity.", http.StatusUnprocessableEntity
+	case errors.Is(err, pack.ErrInsufficientStock):
+		return "There are not enough packs in stock to fulfil this quantity.", http.StatusUnprocessableEntity
+	case errors.Is(err, pack.ErrNoExactMatch):
+		return "No combination of the available pack sizes matches this quantity exactly.", http.StatusUnprocessableEntity
+	case errors.Is(err, pack.ErrNoPackSizes):
+		return "This catalog has no pack sizes to calculate with.", http.StatusUnprocessableEntity
+	case errors.Is(err, pack.ErrUnknownCatalog):
+		return "Please choose one of the listed catalogs.", http.StatusNotFound
+	case errors.Is(err, pack.ErrUnknownVersion):
+		return "This catalog has no such version.", http.StatusNotFound
+	case errors.Is(err, pack.ErrUnknownStrategy):
+		return "Please choose one of the listed strategies.", http.StatusBadRequest
+	case errors.Is(err, pack.ErrInvalidAlternatives):
+		return fmt.Sprintf("Please ask for between 0 and %d alternatives.", pack.MaxAlternatives), http.StatusBadRequest
+	}
+	return "", 0
+}
+
+// RenderUnauthorized answers requests for pages the user may not see: with
+// 401 Unauthorized when they are not logged in, and 403 Forbidden when
+// their role lacks the permission.
 func (h *HTMLHandler) RenderUnauthorized(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Unauthorized access attempt", zap.String("path", r.URL.Path))
 
-	w.WriteHeader(http.StatusUnauthorized)
+	can, email := sessionFromCookie(r)
+	if email != "" {
+		w.WriteHeader(http.StatusForbidden)
+	} else {
+		w.WriteHeader(http.StatusUnauthorized)
+	}
 
 	t := h.templates.Lookup("unauthorized.html")
 	if t == nil {
@@ -185,9 +582,9 @@ func (h *HTMLHandler) RenderUnauthorized(w http.ResponseWriter, r *http.Request)
 	}
 
 	err := t.Execute(w, map[string]any{
-		"Path":       r.URL.Path,
-		"IsLoggedIn": false,
-		"UserEmail":  "",
+		"Path":      r.URL.Path,
+		"Can":       can,
+		"UserEmail": email,
 	})
 	if err != nil {
 		h.logger.Error("Failed to render unauthorized page", zap.Error(err))
@@ -196,11 +593,11 @@ func (h *HTMLHandler) RenderUnauthorized(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *HTMLHandler) RenderLoginForm(w http.ResponseWriter, r *http.Request) {
-	isAdmin, email := adminInfoFromCookie(r)
+	can, email := sessionFromCookie(r)
 	h.templates.ExecuteTemplate(w, "login.html", map[string]any{
-		"Path":       r.URL.Path,
-		"IsLoggedIn": isAdmin,
-		"UserEmail":  email,
+		"Path":      r.URL.Path,
+		"Can":       can,
+		"UserEmail": email,
 	})
 }
 
@@ -208,22 +605,28 @@ func (h *HTMLHandler) HandleLoginPost(w http.ResponseWriter, r *http.Request) {
 	email := r.FormValue("email")
 	pass := r.FormValue("password")
 
-	if email != h.config.AdminEmail || pass != h.config.AdminPassword {
-		h.logger.Warn("Login failed", zap.String("email", email))
-		isAdmin, _ := adminInfoFromCookie(r)
+	user, err := h.accounts.Authenticate(r.Context(), email, pass)
+	if err != nil {
+		if errors.Is(err, account.ErrInvalidCredentials) {
+			h.logger.Warn("Login failed", zap.String("email", email))
+		} else {
+			h.logger.Error("Failed to authenticate", zap.String("email", email), zap.Error(err))
+		}
+		can, current := sessionFromCookie(r)
 		h.templates.ExecuteTemplate(w, "login.html", map[string]any{
-			"Error":      "Invalid credentials",
-			"Path":       r.URL.Path,
-			"IsLoggedIn": isAdmin,
-			"UserEmail":  email,
+			"Error":     "Invalid credentials",
+			"Path":      r.URL.Path,
+			"Can":       can,
+			"UserEmail": current,
 		})
 		return
 	}
 
 	_, token, _ := jwt.Auth.Encode(map[string]any{
-		"email":   email,
-		"isAdmin": true,
-		"exp":     jwtauth.ExpireIn(30 * time.Minute),
+		"email":       user.Email,
+		"role":        user.Role,
+		"permissions": user.Role.Permissions(),
+		"exp":         jwtauth.ExpireIn(30 * time.Minute),
 	})
 
 	http.SetCookie(w, &http.Cookie{
@@ -233,23 +636,103 @@ func (h *HTMLHandler) HandleLoginPost(w http.ResponseWriter, r *http.Request) {
 		HttpOnly: true,
 	})
 
-	h.logger.Info("Login successful", zap.String("email", email))
-	http.Redirect(w, r, "/packs", http.StatusSeeOther)
+	h.logger.Info("Login successful", zap.String("email", user.Email), zap.String("role", string(user.Role)))
+	http.Redirect(w, r, packsURL(formCatalog(r)), http.StatusSeeOther)
+}
+
+// parseCents parses a non-negative decimal amount such as "12.5" into cents.
+// An empty string is zero.
+func parseCents(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	return int64(math.Round(f * 100)), nil
+}
+
+// parseDetails reads the label, SKU, dimensions in millimetres and tare
+// weight in grams of a pack size from a form. Empty numbers are zero.
+func parseDetails(r *http.Request) (pack.PackSize, error) {
+	p := pack.PackSize{
+		Label: strings.TrimSpace(r.FormValue("label")),
+		SKU:   strings.TrimSpace(r.FormValue("sku")),
+	}
+	fields := []struct {
+		name string
+		dst  *int
+	}{
+		{"length_mm", &p.Dimensions.Length},
+		{"width_mm", &p.Dimensions.Width},
+		{"height_mm", &p.Dimensions.Height},
+		{"tare_weight_g", &p.TareWeight},
+	}
+	for _, f := range fields {
+		v := r.FormValue(f.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return pack.PackSize{}, fmt.Errorf("invalid %s %q", f.name, v)
+		}
+		*f.dst = n
+	}
+	return p, nil
 }
 
-func adminInfoFromCookie(r *http.Request) (isAdmin bool, email string) {
+// scheduleLayout is the value format of datetime-local inputs. The page
+// shows and takes schedule times in UTC.
+const scheduleLayout = "2006-01-02T15:04"
+
+// parseSchedule reads the available_from and retires_at form fields. Blank
+// fields leave that end of the schedule open.
+func parseSchedule(r *http.Request) (pack.Schedule, error) {
+	var s pack.Schedule
+	fields := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"available_from", &s.AvailableFrom},
+		{"retires_at", &s.RetiresAt},
+	}
+	for _, f := range fields {
+		v := r.FormValue(f.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(scheduleLayout, v)
+		if err != nil {
+			return pack.Schedule{}, fmt.Errorf("invalid %s %q", f.name, v)
+		}
+		*f.dst = t
+	}
+	return s, nil
+}
+
+// sessionFromCookie returns the permissions and email of the logged in
+// user. Permissions are keyed by name, so templates check them as in
+// {{if .Can.manage_users}}.
+func sessionFromCookie(r *http.Request) (can map[string]bool, email string) {
+	can = map[string]bool{}
 	cookie, err := r.Cookie("admin_token")
 	if err != nil {
-		return false, ""
+		return can, ""
 	}
 
 	token, err := jwt.Auth.Decode(cookie.Value)
 	if err != nil {
-		return false, ""
+		return can, ""
 	}
 
-	claims := token.PrivateClaims()
-	admin, _ := claims["isAdmin"].(bool)
-	emailStr, _ := claims["email"].(string)
-	return admin, emailStr
+	for _, perm := range auth.Permissions(r) {
+		can[string(perm)] = true
+	}
+	emailStr, _ := token.PrivateClaims()["email"].(string)
+	return can, emailStr
 }
@@ -0,0 +1,124 @@
+package html
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+
+	"go.uber.org/zap"
+)
+
+// dateLayout is the format of date inputs.
+const dateLayout = "2006-01-02"
+
+func (h *HTMLHandler) RenderOrderList(w http.ResponseWriter, r *http.Request) {
+	h.renderOrderList(w, r, "")
+}
+
+func (h *HTMLHandler) renderOrderList(w http.ResponseWriter, r *http.Request, errMsg string) {
+	filter := pack.OrderFilter{Catalog: r.FormValue("catalog"), RequestedBy: r.FormValue("requested_by")}
+
+	// Both dates are inclusive, so the range ends at midnight after To.
+	if from := r.FormValue("from"); from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			errMsg = "Please enter dates as YYYY-MM-DD."
+		}
+		filter.From = t
+	}
+	if to := r.FormValue("to"); to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			errMsg = "Please enter dates as YYYY-MM-DD."
+		} else {
+			filter.To = t.AddDate(0, 0, 1)
+		}
+	}
+
+	var orders []lifecycle.Status
+	if errMsg == "" {
+		var err error
+		if orders, err = h.orders.List(r.Context(), filter); err != nil {
+			h.logger.Error("Failed to load orders", zap.Error(err))
+			http.Error(w, "Failed to load orders", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		h.logger.Warn("Invalid order filter", zap.String("from", r.FormValue("from")), zap.String("to", r.FormValue("to")))
+	}
+
+	catalogs, err := h.service.ListCatalogs(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to load catalogs", zap.Error(err))
+		http.Error(w, "Failed to load catalogs", http.StatusInternalServerError)
+		return
+	}
+
+	can, email := sessionFromCookie(r)
+	err = h.templates.ExecuteTemplate(w, "orders.html", map[string]interface{}{
+		"orders":    orders,
+		"catalogs":  catalogs,
+		"filter":    filter,
+		"from":      r.FormValue("from"),
+		"to":        r.FormValue("to"),
+		"error":     errMsg,
+		"Path":      r.URL.Path,
+		"Can":       can,
+		"UserEmail": email,
+	})
+	if err != nil {
+		h.logger.Error("Failed to render orders page", zap.Error(err))
+		http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTMLHandler) HandleAdvanceOrder(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on AdvanceOrder", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	idStr := r.FormValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		h.logger.Warn("Invalid order id", zap.String("input", idStr), zap.Error(err))
+		http.Error(w, "Invalid order id", http.StatusBadRequest)
+		return
+	}
+	to, err := lifecycle.ParseState(r.FormValue("state"))
+	if err != nil {
+		h.logger.Warn("Unknown order state", zap.String("input", r.FormValue("state")))
+		http.Error(w, "Invalid state", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.orders.Advance(r.Context(), id, to); err != nil {
+		fields := []zap.Field{zap.Int64("id", id), zap.String("to", string(to))}
+		var msg string
+		switch {
+		case errors.Is(err, pack.ErrInsufficientStock):
+			msg = "There are not enough packs in stock to confirm this order."
+		case errors.Is(err, lifecycle.ErrStaleState):
+			msg = "The order changed while you were looking at it. Please try again."
+		case errors.Is(err, lifecycle.ErrInvalidTransition):
+			msg = err.Error()
+		default:
+			// Unknown orders are shown as not found; anything else fails
+			// the request without its details.
+			h.rejectForm(w, r, h.renderOrderList, "Failed to advance order", err, fields...)
+			return
+		}
+		h.logger.Warn("Order transition rejected", append(fields, zap.Error(err))...)
+		w.WriteHeader(http.StatusConflict)
+		h.renderOrderList(w, r, msg)
+		return
+	}
+
+	h.logger.Info("Order advanced", zap.Int64("id", id), zap.String("state", string(to)))
+	http.Redirect(w, r, "/orders", http.StatusSeeOther)
+}
@@ -2,9 +2,11 @@ package html
 
 import (
 	"embed"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"net/http"
+	"time"
 )
 
 //go:embed templates/*.html
@@ -13,7 +15,23 @@ var embeddedFiles embed.FS
 
 func ParseTemplates() (*template.Template, error) {
 	tmpl := template.New("").Funcs(template.FuncMap{
-		"sub": func(a, b int) int { return a - b },
+		"sub":   func(a, b int) int { return a - b },
+		"money": func(cents int64) string { return fmt.Sprintf("%d.%02d", cents/100, cents%100) },
+		// onHand returns the stock level for size, or nil when it is untracked.
+		"onHand": func(stock map[int]int, size int) *int {
+			if n, ok := stock[size]; ok {
+				return &n
+			}
+			return nil
+		},
+		// scheduleTime formats a schedule time in UTC for a datetime-local
+		// input, or as blank when it is not set.
+		"scheduleTime": func(t time.Time) string {
+			if t.IsZero() {
+				return ""
+			}
+			return t.UTC().Format(scheduleLayout)
+		},
 	})
 	return tmpl.ParseFS(embeddedFiles, "templates/*.html")
 }
@@ -0,0 +1,114 @@
+package html
+
+import (
+	"net/http"
+	"strconv"
+
+	"pfg/internal/account"
+
+	"go.uber.org/zap"
+)
+
+func (h *HTMLHandler) RenderUserList(w http.ResponseWriter, r *http.Request) {
+	h.renderUserList(w, r, "")
+}
+
+func (h *HTMLHandler) renderUserList(w http.ResponseWriter, r *http.Request, errMsg string) {
+	users, err := h.accounts.ListUsers(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to load users", zap.Error(err))
+		http.Error(w, "Failed to load users", http.StatusInternalServerError)
+		return
+	}
+
+	can, email := sessionFromCookie(r)
+	err = h.templates.ExecuteTemplate(w, "users.html", map[string]interface{}{
+		"users":     users,
+		"roles":     account.Roles(),
+		"error":     errMsg,
+		"Path":      r.URL.Path,
+		"Can":       can,
+		"UserEmail": email,
+	})
+	if err != nil {
+		h.logger.Error("Failed to render users page", zap.Error(err))
+		http.Error(w, "Template rendering failed", http.StatusInternalServerError)
+	}
+}
+
+func (h *HTMLHandler) HandleAddUser(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on AddUser", zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	email, role := r.FormValue("email"), account.Role(r.FormValue("role"))
+	u, err := h.accounts.AddUser(r.Context(), email, r.FormValue("password"), role)
+	if err != nil {
+		h.rejectForm(w, r, h.renderUserList, "Failed to add user", err, zap.String("email", email))
+		return
+	}
+
+	h.logger.Info("User added", zap.Int64("id", u.ID), zap.String("email", u.Email), zap.String("role", string(u.Role)))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleSetPassword(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseUserForm(w, r, "SetPassword")
+	if !ok {
+		return
+	}
+	if err := h.accounts.SetPassword(r.Context(), id, r.FormValue("password")); err != nil {
+		h.rejectForm(w, r, h.renderUserList, "Failed to set password", err, zap.Int64("id", id))
+		return
+	}
+
+	h.logger.Info("Password changed", zap.Int64("id", id))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleSetRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseUserForm(w, r, "SetRole")
+	if !ok {
+		return
+	}
+	role := account.Role(r.FormValue("role"))
+	if err := h.accounts.SetRole(r.Context(), id, role); err != nil {
+		h.rejectForm(w, r, h.renderUserList, "Failed to change role", err, zap.Int64("id", id))
+		return
+	}
+
+	h.logger.Info("Role changed", zap.Int64("id", id), zap.String("role", string(role)))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+func (h *HTMLHandler) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseUserForm(w, r, "DeleteUser")
+	if !ok {
+		return
+	}
+	if err := h.accounts.RemoveUser(r.Context(), id); err != nil {
+		h.rejectForm(w, r, h.renderUserList, "Failed to delete user", err, zap.Int64("id", id))
+		return
+	}
+
+	h.logger.Info("User deleted", zap.Int64("id", id))
+	http.Redirect(w, r, "/users", http.StatusSeeOther)
+}
+
+// parseUserForm parses a form naming a user by its id field.
+func (h *HTMLHandler) parseUserForm(w http.ResponseWriter, r *http.Request, action string) (int64, bool) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Warn("Invalid form on "+action, zap.Error(err))
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return 0, false
+	}
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		h.logger.Warn("Invalid user id", zap.String("raw", r.FormValue("id")), zap.Error(err))
+		http.Error(w, "Invalid user id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
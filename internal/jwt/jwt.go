@@ -3,6 +3,8 @@ package jwt
 import (
 	"time"
 
+	"pfg/internal/account"
+
 	"github.com/go-chi/jwtauth/v5"
 )
 
@@ -19,9 +21,10 @@ func GenerateDevToken() string {
 	}
 
 	_, tokenStr, _ := Auth.Encode(map[string]interface{}{
-		"user":    "admin",
-		"isAdmin": true,
-		"exp":     jwtauth.ExpireIn(30 * time.Minute),
+		"user":        "admin",
+		"role":        account.RoleAdmin,
+		"permissions": account.RoleAdmin.Permissions(),
+		"exp":         jwtauth.ExpireIn(30 * time.Minute),
 	})
 	return tokenStr
 }
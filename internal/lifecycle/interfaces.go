@@ -0,0 +1,27 @@
+package lifecycle
+
+import (
+	"context"
+
+	"pfg/internal/pack"
+)
+
+// Repository stores the state history of orders kept by a pack.Repository.
+type Repository interface {
+	// GetTransitions returns every transition of an order, oldest first.
+	GetTransitions(ctx context.Context, orderID int64) ([]Transition, error)
+	// GetStates returns the latest state of each of the given orders that
+	// has any transitions.
+	GetStates(ctx context.Context, orderIDs []int64) (map[int64]State, error)
+	// InsertTransition records t and returns it with At set. It fails with
+	// ErrStaleState if the order already has a transition numbered t.Seq.
+	// The order's packs are reserved or released as change says in the
+	// same transaction; reserving fails with pack.ErrInsufficientStock.
+	// Only sizes with tracked stock are affected.
+	InsertTransition(ctx context.Context, o pack.Order, t Transition, change StockChange) (Transition, error)
+	// CreateOrder stores a new order together with its first transition,
+	// changing stock as InsertTransition does, all in one transaction. It
+	// returns the order with its ID and CreatedAt set and t with its
+	// OrderID and At.
+	CreateOrder(ctx context.Context, o pack.Order, t Transition, change StockChange) (pack.Order, Transition, error)
+}
@@ -0,0 +1,228 @@
+// Package lifecycle moves stored orders through their states, from quote to
+// shipment or cancellation, keeping a history of every transition.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pfg/internal/pack"
+)
+
+// State is a stage in the life of an order.
+type State string
+
+const (
+	Quoted    State = "quoted"    // calculated and stored, stock untouched
+	Confirmed State = "confirmed" // packs taken out of stock
+	Packed    State = "packed"
+	Shipped   State = "shipped"
+	Cancelled State = "cancelled"
+)
+
+var (
+	ErrUnknownState      = errors.New("unknown order state")
+	ErrInvalidTransition = errors.New("invalid order state transition")
+	// ErrStaleState is returned when an order changed state while a
+	// transition was being made.
+	ErrStaleState = errors.New("order state changed concurrently")
+)
+
+// next lists the states each state may move to. Shipped and cancelled
+// orders are final.
+var next = map[State][]State{
+	Quoted:    {Confirmed, Cancelled},
+	Confirmed: {Packed, Cancelled},
+	Packed:    {Shipped, Cancelled},
+}
+
+// ParseState returns the State named s.
+func ParseState(s string) (State, error) {
+	switch st := State(s); st {
+	case Quoted, Confirmed, Packed, Shipped, Cancelled:
+		return st, nil
+	}
+	return "", ErrUnknownState
+}
+
+// Next returns the states an order in st may move to.
+func (st State) Next() []State {
+	return next[st]
+}
+
+// CanMoveTo reports whether an order in st may move to to.
+func (st State) CanMoveTo(to State) bool {
+	for _, s := range next[st] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StockChange is what recording a transition does to the stock of the
+// order's packs.
+type StockChange int
+
+const (
+	KeepStock    StockChange = iota
+	ReserveStock             // take the packs out of stock
+	ReleaseStock             // put reserved packs back into stock
+)
+
+// stockChange returns what moving an order from one state to another does
+// to its packs: confirming reserves them, and cancelling an order that has
+// them reserved gives them back.
+func stockChange(from, to State) StockChange {
+	switch {
+	case to == Confirmed:
+		return ReserveStock
+	case to == Cancelled && (from == Confirmed || from == Packed):
+		return ReleaseStock
+	}
+	return KeepStock
+}
+
+// Transition is a recorded change of an order's state.
+type Transition struct {
+	OrderID int64
+	Seq     int   // position in the order's history, starting at 1
+	From    State // empty for the transition that created the order
+	To      State
+	At      time.Time
+	By      string
+}
+
+// Status is an order together with its current state and, when loaded
+// individually, its history.
+type Status struct {
+	pack.Order
+	State   State
+	History []Transition // oldest first
+}
+
+// initialState is the state of an order without recorded transitions.
+// Orders confirmed directly by a calculation before their first state was
+// recorded with them have their packs reserved and no history until they
+// move on.
+const initialState = Confirmed
+
+type Service struct {
+	packs *pack.Service
+	repo  Repository
+}
+
+func NewService(packs *pack.Service, repo Repository) *Service {
+	return &Service{packs: packs, repo: repo}
+}
+
+// Quote calculates packs for quantity and stores the result as an order in
+// the quoted state, without taking anything out of stock.
+func (s *Service) Quote(ctx context.Context, quantity int, opts ...pack.CalcOption) (Status, error) {
+	result, err := s.packs.Calculate(ctx, quantity, opts...)
+	if err != nil {
+		return Status{}, err
+	}
+	return s.create(ctx, result, Quoted)
+}
+
+// Confirm takes the packs of a calculation out of stock and stores it as a
+// confirmed order on behalf of the user in ctx. It fails with
+// pack.ErrInsufficientStock, storing nothing, if stock ran out since the
+// calculation was made.
+func (s *Service) Confirm(ctx context.Context, result pack.PackResult) (Status, error) {
+	return s.create(ctx, result, Confirmed)
+}
+
+// create stores result as an order whose history starts in state.
+func (s *Service) create(ctx context.Context, result pack.PackResult, state State) (Status, error) {
+	order, err := s.packs.NewOrder(ctx, result)
+	if err != nil {
+		return Status{}, err
+	}
+
+	t := Transition{Seq: 1, To: state, By: order.RequestedBy}
+	if order, t, err = s.repo.CreateOrder(ctx, order, t, stockChange("", state)); err != nil {
+		return Status{}, err
+	}
+	return Status{Order: order, State: state, History: []Transition{t}}, nil
+}
+
+// Status returns an order with its current state and history.
+func (s *Service) Status(ctx context.Context, id int64) (Status, error) {
+	order, err := s.packs.GetOrder(ctx, id)
+	if err != nil {
+		return Status{}, err
+	}
+	history, err := s.repo.GetTransitions(ctx, id)
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{Order: order, State: current(history), History: history}, nil
+}
+
+// List returns the orders matching f with their current states, newest
+// first.
+func (s *Service) List(ctx context.Context, f pack.OrderFilter) ([]Status, error) {
+	orders, err := s.packs.ListOrders(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	states, err := s.repo.GetStates(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, len(orders))
+	for i, o := range orders {
+		st, ok := states[o.ID]
+		if !ok {
+			st = initialState
+		}
+		out[i] = Status{Order: o, State: st}
+	}
+	return out, nil
+}
+
+// Advance moves an order to the state to on behalf of the user in ctx.
+// Confirming a quote takes its packs out of stock, failing with
+// pack.ErrInsufficientStock if there are not enough left, and cancelling a
+// confirmed or packed order puts them back.
+func (s *Service) Advance(ctx context.Context, id int64, to State) (Status, error) {
+	st, err := s.Status(ctx, id)
+	if err != nil {
+		return Status{}, err
+	}
+	if !st.State.CanMoveTo(to) {
+		return Status{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, st.State, to)
+	}
+
+	t := Transition{
+		OrderID: id,
+		Seq:     len(st.History) + 1,
+		From:    st.State,
+		To:      to,
+		By:      pack.ActorFromContext(ctx),
+	}
+	if t, err = s.repo.InsertTransition(ctx, st.Order, t, stockChange(st.State, to)); err != nil {
+		return Status{}, err
+	}
+
+	st.State = to
+	st.History = append(st.History, t)
+	return st, nil
+}
+
+func current(history []Transition) State {
+	if len(history) == 0 {
+		return initialState
+	}
+	return history[len(history)-1].To
+}
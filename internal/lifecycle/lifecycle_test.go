@@ -0,0 +1,180 @@
+package lifecycle_test
+
+import (
+	"context"
+	"testing"
+
+	"pfg/internal/lifecycle"
+	"pfg/internal/memory"
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newService returns a service over a catalog of 250 and 500 packs, with
+// stock tracked for the sizes in stock.
+func newService(t *testing.T, stock map[int]int) (*lifecycle.Service, *memory.Repository) {
+	repo := memory.NewRepository(pack.PackSize{Size: 250}, pack.PackSize{Size: 500})
+	for size, n := range stock {
+		require.NoError(t, repo.SetStock(context.Background(), pack.DefaultCatalog, size, n))
+	}
+	return lifecycle.NewService(pack.NewService(repo), repo), repo
+}
+
+func onHand(t *testing.T, repo *memory.Repository, size int) int {
+	stock, err := repo.GetStock(context.Background(), pack.DefaultCatalog)
+	require.NoError(t, err)
+	return stock[size]
+}
+
+func TestStateTransitions(t *testing.T) {
+	assert.True(t, lifecycle.Quoted.CanMoveTo(lifecycle.Confirmed))
+	assert.True(t, lifecycle.Packed.CanMoveTo(lifecycle.Cancelled))
+	assert.False(t, lifecycle.Quoted.CanMoveTo(lifecycle.Shipped))
+	assert.False(t, lifecycle.Confirmed.CanMoveTo(lifecycle.Quoted))
+	assert.Empty(t, lifecycle.Shipped.Next())
+	assert.Empty(t, lifecycle.Cancelled.Next())
+
+	st, err := lifecycle.ParseState("packed")
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle.Packed, st)
+	_, err = lifecycle.ParseState("lost")
+	assert.ErrorIs(t, err, lifecycle.ErrUnknownState)
+}
+
+func TestOrderLifecycle(t *testing.T) {
+	service, repo := newService(t, map[int]int{500: 1})
+	alice := pack.ContextWithActor(context.Background(), "alice")
+	bob := pack.ContextWithActor(context.Background(), "bob")
+
+	quote, err := service.Quote(alice, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle.Quoted, quote.State)
+	assert.Equal(t, 1, onHand(t, repo, 500), "quotes leave stock alone")
+
+	_, err = service.Advance(bob, quote.ID, lifecycle.Shipped)
+	assert.ErrorIs(t, err, lifecycle.ErrInvalidTransition)
+
+	st, err := service.Advance(bob, quote.ID, lifecycle.Confirmed)
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle.Confirmed, st.State)
+	assert.Equal(t, 0, onHand(t, repo, 500), "confirming reserves stock")
+
+	for _, to := range []lifecycle.State{lifecycle.Packed, lifecycle.Shipped} {
+		_, err = service.Advance(bob, quote.ID, to)
+		assert.NoError(t, err)
+	}
+
+	st, err = service.Status(context.Background(), quote.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle.Shipped, st.State)
+	var path []lifecycle.State
+	var by []string
+	for _, tr := range st.History {
+		path = append(path, tr.To)
+		by = append(by, tr.By)
+	}
+	assert.Equal(t, []lifecycle.State{lifecycle.Quoted, lifecycle.Confirmed, lifecycle.Packed, lifecycle.Shipped}, path)
+	assert.Equal(t, []string{"alice", "bob", "bob", "bob"}, by)
+
+	_, err = service.Advance(bob, quote.ID, lifecycle.Cancelled)
+	assert.ErrorIs(t, err, lifecycle.ErrInvalidTransition)
+
+	_, err = service.Advance(bob, 99, lifecycle.Confirmed)
+	assert.ErrorIs(t, err, pack.ErrUnknownOrder)
+}
+
+func TestConfirmQuoteWithoutStock(t *testing.T) {
+	service, _ := newService(t, map[int]int{500: 1})
+
+	first, err := service.Quote(context.Background(), 500)
+	assert.NoError(t, err)
+	second, err := service.Quote(context.Background(), 500)
+	assert.NoError(t, err)
+
+	_, err = service.Advance(context.Background(), first.ID, lifecycle.Confirmed)
+	assert.NoError(t, err)
+	_, err = service.Advance(context.Background(), second.ID, lifecycle.Confirmed)
+	assert.ErrorIs(t, err, pack.ErrInsufficientStock)
+
+	st, err := service.Status(context.Background(), second.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle.Quoted, st.State)
+}
+
+func TestCancelReleasesStock(t *testing.T) {
+	service, repo := newService(t, map[int]int{250: 2, 500: 1})
+	ctx := context.Background()
+
+	var ids []int64
+	for range 3 {
+		quote, err := service.Quote(ctx, 500)
+		require.NoError(t, err)
+		ids = append(ids, quote.ID)
+	}
+
+	_, err := service.Advance(ctx, ids[0], lifecycle.Confirmed)
+	require.NoError(t, err)
+	_, err = service.Advance(ctx, ids[1], lifecycle.Cancelled)
+	require.NoError(t, err)
+	assert.Equal(t, 0, onHand(t, repo, 500), "cancelling a quote releases nothing")
+
+	_, err = service.Advance(ctx, ids[0], lifecycle.Cancelled)
+	require.NoError(t, err)
+	assert.Equal(t, 1, onHand(t, repo, 500), "cancelling a confirmed order releases its packs")
+
+	_, err = service.Advance(ctx, ids[2], lifecycle.Confirmed)
+	require.NoError(t, err)
+	_, err = service.Advance(ctx, ids[2], lifecycle.Packed)
+	require.NoError(t, err)
+	assert.Equal(t, 0, onHand(t, repo, 500))
+	_, err = service.Advance(ctx, ids[2], lifecycle.Cancelled)
+	require.NoError(t, err)
+	assert.Equal(t, 1, onHand(t, repo, 500), "cancelling a packed order releases its packs")
+	assert.Equal(t, 2, onHand(t, repo, 250))
+}
+
+func TestConfirm(t *testing.T) {
+	service, repo := newService(t, map[int]int{500: 1})
+	alice := pack.ContextWithActor(context.Background(), "alice")
+
+	quote, err := service.Quote(alice, 500)
+	require.NoError(t, err)
+
+	st, err := service.Confirm(alice, pack.PackResult{Requested: 500, TotalItems: 500, TotalPacks: 1, Packs: map[int]int{500: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, lifecycle.Confirmed, st.State)
+	assert.Equal(t, 0, onHand(t, repo, 500), "confirming reserves stock")
+
+	stored, err := service.Status(context.Background(), st.ID)
+	require.NoError(t, err)
+	assert.Equal(t, lifecycle.Confirmed, stored.State)
+	require.Len(t, stored.History, 1)
+	assert.Equal(t, lifecycle.Transition{OrderID: st.ID, Seq: 1, To: lifecycle.Confirmed, At: stored.History[0].At, By: "alice"}, stored.History[0])
+
+	_, err = service.Confirm(alice, pack.PackResult{Requested: 500, TotalItems: 500, TotalPacks: 1, Packs: map[int]int{500: 1}})
+	assert.ErrorIs(t, err, pack.ErrInsufficientStock)
+	list, err := service.List(context.Background(), pack.OrderFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 2, "a failed confirmation stores nothing")
+	assert.Equal(t, []int64{st.ID, quote.ID}, []int64{list[0].ID, list[1].ID})
+}
+
+// Orders stored without any recorded state count as confirmed.
+func TestOrdersWithoutHistory(t *testing.T) {
+	service, repo := newService(t, nil)
+
+	_, err := repo.InsertOrder(context.Background(), pack.Order{Catalog: pack.DefaultCatalog, Packs: map[int]int{250: 1}}, false)
+	require.NoError(t, err)
+
+	list, err := service.List(context.Background(), pack.OrderFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, lifecycle.Confirmed, list[0].State)
+
+	st, err := service.Advance(context.Background(), list[0].ID, lifecycle.Packed)
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle.Packed, st.State)
+	assert.Equal(t, lifecycle.Confirmed, st.History[0].From)
+}
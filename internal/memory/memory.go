@@ -0,0 +1,364 @@
+// Package memory keeps pack catalogs, stock, orders and user accounts in
+// process memory. It needs no database, which suits demos, local
+// development and tests; everything is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"pfg/internal/account"
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+)
+
+var (
+	_ pack.Repository      = (*Repository)(nil)
+	_ lifecycle.Repository = (*Repository)(nil)
+	_ account.Repository   = (*Repository)(nil)
+)
+
+type catalog struct {
+	name     string
+	packs    map[int]pack.PackSize // by size
+	stock    map[int]int
+	versions []pack.CatalogVersion // oldest first
+}
+
+// Repository implements pack.Repository, lifecycle.Repository and
+// account.Repository. It is safe for concurrent use.
+type Repository struct {
+	mu          sync.RWMutex
+	catalogs    map[string]*catalog
+	orders      []pack.Order // indexed by ID-1
+	transitions map[int64][]lifecycle.Transition
+	users       map[int64]account.User
+	lastUserID  int64
+	now         func() time.Time
+}
+
+// NewRepository returns a repository holding the default catalog with the
+// given pack sizes.
+func NewRepository(sizes ...pack.PackSize) *Repository {
+	r := &Repository{catalogs: map[string]*catalog{}, transitions: map[int64][]lifecycle.Transition{}, users: map[int64]account.User{}, now: time.Now}
+
+	c := &catalog{name: "Default", packs: map[int]pack.PackSize{}, stock: map[int]int{}}
+	for _, p := range sizes {
+		c.packs[p.Size] = p
+	}
+	r.catalogs[pack.DefaultCatalog] = c
+	r.record(pack.DefaultCatalog, pack.Change{By: "system", Description: "initial version"})
+	return r
+}
+
+// SetClock makes the repository stamp the versions, orders, transitions
+// and users it records from now rather than the current time.
+func (r *Repository) SetClock(now func() time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.now = now
+}
+
+// record appends the current pack sizes of a catalog as a new version.
+// The caller must hold the write lock.
+func (r *Repository) record(id string, change pack.Change) {
+	c := r.catalogs[id]
+	c.versions = append(c.versions, pack.CatalogVersion{
+		Catalog:   id,
+		Version:   len(c.versions) + 1,
+		ChangedAt: r.now(),
+		ChangedBy: change.By,
+		Change:    change.Description,
+		Sizes:     c.sizes(),
+	})
+}
+
+func (c *catalog) sizes() []pack.PackSize {
+	packs := make([]pack.PackSize, 0, len(c.packs))
+	for _, size := range slices.Sorted(maps.Keys(c.packs)) {
+		packs = append(packs, c.packs[size])
+	}
+	return packs
+}
+
+func (r *Repository) GetCatalogs(ctx context.Context) ([]pack.Catalog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	catalogs := make([]pack.Catalog, 0, len(r.catalogs))
+	for id, c := range r.catalogs {
+		catalogs = append(catalogs, pack.Catalog{ID: id, Name: c.name})
+	}
+	sort.Slice(catalogs, func(i, j int) bool { return catalogs[i].ID < catalogs[j].ID })
+	return catalogs, nil
+}
+
+func (r *Repository) GetCatalog(ctx context.Context, id string) (pack.Catalog, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.catalogs[id]
+	if !ok {
+		return pack.Catalog{}, pack.ErrUnknownCatalog
+	}
+	return pack.Catalog{ID: id, Name: c.name}, nil
+}
+
+func (r *Repository) InsertCatalog(ctx context.Context, c pack.Catalog, change pack.Change) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.catalogs[c.ID]; ok {
+		return pack.ErrDuplicateCatalog
+	}
+	r.catalogs[c.ID] = &catalog{name: c.Name, packs: map[int]pack.PackSize{}, stock: map[int]int{}}
+	r.record(c.ID, change)
+	return nil
+}
+
+func (r *Repository) DeleteCatalog(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.catalogs[id]; !ok {
+		return pack.ErrUnknownCatalog
+	}
+	delete(r.catalogs, id)
+	return nil
+}
+
+func (r *Repository) GetPackSizes(ctx context.Context, catalogID string) ([]pack.PackSize, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.catalogs[catalogID]
+	if !ok {
+		return []pack.PackSize{}, nil
+	}
+	return c.sizes(), nil
+}
+
+// change applies mutate to a catalog and, if it reports a change, records
+// a new version.
+func (r *Repository) change(catalogID string, change pack.Change, mutate func(c *catalog) (bool, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.catalogs[catalogID]
+	if !ok {
+		return pack.ErrUnknownCatalog
+	}
+	changed, err := mutate(c)
+	if err != nil || !changed {
+		return err
+	}
+	r.record(catalogID, change)
+	return nil
+}
+
+func (r *Repository) InsertPackSize(ctx context.Context, catalogID string, p pack.PackSize, change pack.Change) error {
+	return r.change(catalogID, change, func(c *catalog) (bool, error) {
+		if _, ok := c.packs[p.Size]; ok {
+			return false, pack.ErrDuplicatePackSize
+		}
+		c.packs[p.Size] = p
+		return true, nil
+	})
+}
+
+func (r *Repository) UpdatePackCost(ctx context.Context, catalogID string, size int, unitCost int64, change pack.Change) error {
+	return r.change(catalogID, change, func(c *catalog) (bool, error) {
+		p, ok := c.packs[size]
+		if !ok {
+			return false, pack.ErrUnknownPackSize
+		}
+		p.UnitCost = unitCost
+		c.packs[size] = p
+		return true, nil
+	})
+}
+
+func (r *Repository) UpdatePackDetails(ctx context.Context, catalogID string, p pack.PackSize, change pack.Change) error {
+	return r.change(catalogID, change, func(c *catalog) (bool, error) {
+		existing, ok := c.packs[p.Size]
+		if !ok {
+			return false, pack.ErrUnknownPackSize
+		}
+		p.UnitCost, p.Inactive, p.Schedule = existing.UnitCost, existing.Inactive, existing.Schedule
+		c.packs[p.Size] = p
+		return true, nil
+	})
+}
+
+func (r *Repository) SetPackActive(ctx context.Context, catalogID string, size int, active bool, change pack.Change) error {
+	return r.change(catalogID, change, func(c *catalog) (bool, error) {
+		p, ok := c.packs[size]
+		if !ok {
+			return false, pack.ErrUnknownPackSize
+		}
+		p.Inactive = !active
+		c.packs[size] = p
+		return true, nil
+	})
+}
+
+func (r *Repository) UpdatePackSchedule(ctx context.Context, catalogID string, size int, s pack.Schedule, change pack.Change) error {
+	return r.change(catalogID, change, func(c *catalog) (bool, error) {
+		p, ok := c.packs[size]
+		if !ok {
+			return false, pack.ErrUnknownPackSize
+		}
+		p.Schedule = s
+		c.packs[size] = p
+		return true, nil
+	})
+}
+
+func (r *Repository) DeletePackSize(ctx context.Context, catalogID string, size int, change pack.Change) error {
+	return r.change(catalogID, change, func(c *catalog) (bool, error) {
+		if _, ok := c.packs[size]; !ok {
+			return false, pack.ErrUnknownPackSize
+		}
+		delete(c.packs, size)
+		delete(c.stock, size)
+		return true, nil
+	})
+}
+
+func (r *Repository) ReplacePackSizes(ctx context.Context, catalogID string, packs []pack.PackSize, change pack.Change) error {
+	return r.change(catalogID, change, func(c *catalog) (bool, error) {
+		c.packs = make(map[int]pack.PackSize, len(packs))
+		for _, p := range packs {
+			c.packs[p.Size] = p
+		}
+		for size := range c.stock {
+			if _, ok := c.packs[size]; !ok {
+				delete(c.stock, size)
+			}
+		}
+		return true, nil
+	})
+}
+
+func (r *Repository) GetCatalogVersions(ctx context.Context, catalogID string) ([]pack.CatalogVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	versions := []pack.CatalogVersion{}
+	if c, ok := r.catalogs[catalogID]; ok {
+		for i := len(c.versions) - 1; i >= 0; i-- {
+			versions = append(versions, copyVersion(c.versions[i]))
+		}
+	}
+	return versions, nil
+}
+
+func (r *Repository) GetCatalogVersion(ctx context.Context, catalogID string, version int) (pack.CatalogVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.catalogs[catalogID]
+	if !ok || version < 0 || version > len(c.versions) {
+		return pack.CatalogVersion{}, pack.ErrUnknownVersion
+	}
+	if version == 0 {
+		version = len(c.versions)
+	}
+	return copyVersion(c.versions[version-1]), nil
+}
+
+func (r *Repository) GetCatalogVersionAt(ctx context.Context, catalogID string, at time.Time) (pack.CatalogVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if c, ok := r.catalogs[catalogID]; ok {
+		for i := len(c.versions) - 1; i >= 0; i-- {
+			if !c.versions[i].ChangedAt.After(at) {
+				return copyVersion(c.versions[i]), nil
+			}
+		}
+	}
+	return pack.CatalogVersion{}, pack.ErrUnknownVersion
+}
+
+func copyVersion(v pack.CatalogVersion) pack.CatalogVersion {
+	v.Sizes = slices.Clone(v.Sizes)
+	return v
+}
+
+func (r *Repository) GetStock(ctx context.Context, catalogID string) (map[int]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if c, ok := r.catalogs[catalogID]; ok {
+		return maps.Clone(c.stock), nil
+	}
+	return map[int]int{}, nil
+}
+
+func (r *Repository) SetStock(ctx context.Context, catalogID string, size int, onHand int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.catalogs[catalogID]
+	if !ok {
+		return pack.ErrUnknownCatalog
+	}
+	c.stock[size] = onHand
+	return nil
+}
+
+func (r *Repository) DeleteStock(ctx context.Context, catalogID string, size int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, ok := r.catalogs[catalogID]; ok {
+		delete(c.stock, size)
+	}
+	return nil
+}
+
+func (r *Repository) ReserveStock(ctx context.Context, catalogID string, packs map[int]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.reserve(catalogID, packs)
+}
+
+// reserve takes packs out of stock if every tracked size has enough left.
+// The caller must hold the write lock.
+func (r *Repository) reserve(catalogID string, packs map[int]int) error {
+	c, ok := r.catalogs[catalogID]
+	if !ok {
+		return nil
+	}
+	for size, count := range packs {
+		if n, ok := c.stock[size]; ok && n < count {
+			return pack.ErrInsufficientStock
+		}
+	}
+	for size, count := range packs {
+		if _, ok := c.stock[size]; ok {
+			c.stock[size] -= count
+		}
+	}
+	return nil
+}
+
+// release puts packs back into the stock of the sizes that track it.
+func (r *Repository) release(catalogID string, packs map[int]int) {
+	c, ok := r.catalogs[catalogID]
+	if !ok {
+		return
+	}
+	for size, count := range packs {
+		if _, ok := c.stock[size]; ok {
+			c.stock[size] += count
+		}
+	}
+}
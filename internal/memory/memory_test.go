@@ -0,0 +1,79 @@
+package memory_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"pfg/internal/account"
+	"pfg/internal/account/accounttest"
+	"pfg/internal/memory"
+	"pfg/internal/pack"
+	"pfg/internal/pack/packtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConcurrentReservations(t *testing.T) {
+	repo := memory.NewRepository(pack.PackSize{Size: 250})
+	ctx := context.Background()
+	assert.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 250, 10))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.ReserveStock(ctx, pack.DefaultCatalog, map[int]int{250: 1}); err == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			} else {
+				assert.ErrorIs(t, err, pack.ErrInsufficientStock)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stock, err := repo.GetStock(ctx, pack.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, reserved)
+	assert.Equal(t, 0, stock[250])
+}
+
+func TestReturnedValuesAreCopies(t *testing.T) {
+	repo := memory.NewRepository(pack.PackSize{Size: 250, UnitCost: 100})
+	ctx := context.Background()
+
+	v, err := repo.GetCatalogVersion(ctx, pack.DefaultCatalog, 0)
+	assert.NoError(t, err)
+	v.Sizes[0].UnitCost = 1
+
+	o, err := repo.InsertOrder(ctx, pack.Order{Catalog: pack.DefaultCatalog, Packs: map[int]int{250: 1}}, false)
+	assert.NoError(t, err)
+	o.Packs[250] = 99
+
+	v, err = repo.GetCatalogVersion(ctx, pack.DefaultCatalog, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), v.Sizes[0].UnitCost)
+
+	o, err = repo.GetOrder(ctx, o.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, o.Packs[250])
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	packtest.RunRepositoryTests(t, func(t *testing.T) pack.Repository {
+		return memory.NewRepository()
+	})
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	accounttest.RunRepositoryTests(t, func(t *testing.T) account.Repository {
+		return memory.NewRepository()
+	})
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+)
+
+func copyOrder(o pack.Order) pack.Order {
+	o.Sizes = slices.Clone(o.Sizes)
+	o.Packs = maps.Clone(o.Packs)
+	return o
+}
+
+func (r *Repository) InsertOrder(ctx context.Context, o pack.Order, reserve bool) (pack.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if reserve {
+		if err := r.reserve(o.Catalog, o.Packs); err != nil {
+			return pack.Order{}, err
+		}
+	}
+
+	o = copyOrder(o)
+	o.ID = int64(len(r.orders) + 1)
+	o.CreatedAt = r.now()
+	r.orders = append(r.orders, o)
+	return copyOrder(o), nil
+}
+
+func (r *Repository) GetOrder(ctx context.Context, id int64) (pack.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if id < 1 || id > int64(len(r.orders)) {
+		return pack.Order{}, pack.ErrUnknownOrder
+	}
+	return copyOrder(r.orders[id-1]), nil
+}
+
+func (r *Repository) GetOrders(ctx context.Context, f pack.OrderFilter) ([]pack.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orders := []pack.Order{}
+	for i := len(r.orders) - 1; i >= 0 && len(orders) < f.Limit; i-- {
+		o := r.orders[i]
+		switch {
+		case f.Catalog != "" && o.Catalog != f.Catalog,
+			f.RequestedBy != "" && o.RequestedBy != f.RequestedBy,
+			!f.From.IsZero() && o.CreatedAt.Before(f.From),
+			!f.To.IsZero() && !o.CreatedAt.Before(f.To):
+			continue
+		}
+		orders = append(orders, copyOrder(o))
+	}
+	return orders, nil
+}
+
+func (r *Repository) GetTransitions(ctx context.Context, orderID int64) ([]lifecycle.Transition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]lifecycle.Transition{}, r.transitions[orderID]...), nil
+}
+
+func (r *Repository) GetStates(ctx context.Context, orderIDs []int64) (map[int64]lifecycle.State, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := map[int64]lifecycle.State{}
+	for _, id := range orderIDs {
+		if history := r.transitions[id]; len(history) > 0 {
+			states[id] = history[len(history)-1].To
+		}
+	}
+	return states, nil
+}
+
+func (r *Repository) InsertTransition(ctx context.Context, o pack.Order, t lifecycle.Transition, change lifecycle.StockChange) (lifecycle.Transition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.transitions[t.OrderID]) >= t.Seq {
+		return lifecycle.Transition{}, lifecycle.ErrStaleState
+	}
+	if err := r.changeStock(o, change); err != nil {
+		return lifecycle.Transition{}, err
+	}
+
+	t.At = r.now()
+	r.transitions[t.OrderID] = append(r.transitions[t.OrderID], t)
+	return t, nil
+}
+
+func (r *Repository) CreateOrder(ctx context.Context, o pack.Order, t lifecycle.Transition, change lifecycle.StockChange) (pack.Order, lifecycle.Transition, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.changeStock(o, change); err != nil {
+		return pack.Order{}, lifecycle.Transition{}, err
+	}
+
+	o = copyOrder(o)
+	o.ID = int64(len(r.orders) + 1)
+	o.CreatedAt = r.now()
+	r.orders = append(r.orders, o)
+
+	t.OrderID, t.At = o.ID, o.CreatedAt
+	r.transitions[o.ID] = []lifecycle.Transition{t}
+	return copyOrder(o), t, nil
+}
+
+// changeStock reserves or releases the packs of o as change says. The
+// caller must hold the write lock.
+func (r *Repository) changeStock(o pack.Order, change lifecycle.StockChange) error {
+	switch change {
+	case lifecycle.ReserveStock:
+		return r.reserve(o.Catalog, o.Packs)
+	case lifecycle.ReleaseStock:
+		r.release(o.Catalog, o.Packs)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"pfg/internal/account"
+)
+
+func (r *Repository) GetUsers(ctx context.Context) ([]account.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]account.User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+func (r *Repository) GetUser(ctx context.Context, id int64) (account.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return account.User{}, account.ErrUnknownUser
+	}
+	return u, nil
+}
+
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (account.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.Email == email {
+			return u, nil
+		}
+	}
+	return account.User{}, account.ErrUnknownUser
+}
+
+func (r *Repository) InsertUser(ctx context.Context, u account.User) (account.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.users {
+		if other.Email == u.Email {
+			return account.User{}, account.ErrDuplicateUser
+		}
+	}
+	r.lastUserID++
+	u.ID, u.CreatedAt = r.lastUserID, r.now()
+	r.users[u.ID] = u
+	return u, nil
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return account.ErrUnknownUser
+	}
+	u.PasswordHash = hash
+	r.users[id] = u
+	return nil
+}
+
+func (r *Repository) UpdateRole(ctx context.Context, id int64, role account.Role) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return account.ErrUnknownUser
+	}
+	if role != account.RoleAdmin && r.lastAdmin(id) {
+		return account.ErrLastAdmin
+	}
+	u.Role = role
+	r.users[id] = u
+	return nil
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return account.ErrUnknownUser
+	}
+	if r.lastAdmin(id) {
+		return account.ErrLastAdmin
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// lastAdmin reports whether the user with the given ID is the only admin.
+// The caller holds the write lock.
+func (r *Repository) lastAdmin(id int64) bool {
+	for _, u := range r.users {
+		if u.Role == account.RoleAdmin && u.ID != id {
+			return false
+		}
+	}
+	return r.users[id].Role == account.RoleAdmin
+}
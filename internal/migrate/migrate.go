@@ -0,0 +1,103 @@
+// Package migrate applies the Atlas migrations embedded in the binary,
+// recording them in the same revisions table the Atlas CLI uses so either
+// tool can take over from the other.
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrChecksum = errors.New("migration files do not match atlas.sum")
+	ErrNoDown   = errors.New("migration has no down script")
+)
+
+const (
+	upDir   = "migrations"
+	downDir = "down"
+	sumFile = "atlas.sum"
+)
+
+// Migration is one file of the migration directory.
+type Migration struct {
+	Version     string // the timestamp before the first underscore
+	Description string // the rest of the name, without .sql
+	Hash        string // as listed in atlas.sum
+	Up          string
+	Down        string // empty when the migration cannot be reverted
+}
+
+// Load reads the migrations in fsys, oldest first, and checks them against
+// atlas.sum the way the Atlas CLI does. fsys holds a migrations directory
+// and a down directory with scripts named like the migrations they revert.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, path.Join(upDir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	sum, err := fs.ReadFile(fsys, path.Join(upDir, sumFile))
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", sumFile, err)
+	}
+
+	var (
+		migrations = make([]Migration, 0, len(names))
+		running    = sha256.New()
+		total      = sha256.New()
+		want       bytes.Buffer
+	)
+	for _, name := range names {
+		up, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		base := path.Base(name)
+
+		// Each file's hash covers it and every file before it.
+		running.Write([]byte(base))
+		running.Write(up)
+		hash := "h1:" + base64.StdEncoding.EncodeToString(running.Sum(nil))
+		total.Write([]byte(base))
+		total.Write([]byte(strings.TrimPrefix(hash, "h1:")))
+		fmt.Fprintf(&want, "%s %s\n", base, hash)
+
+		version, description, _ := strings.Cut(strings.TrimSuffix(base, ".sql"), "_")
+		m := Migration{Version: version, Description: description, Hash: hash, Up: string(up)}
+		if down, err := fs.ReadFile(fsys, path.Join(downDir, base)); err == nil {
+			m.Down = string(down)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+
+	header := "h1:" + base64.StdEncoding.EncodeToString(total.Sum(nil)) + "\n"
+	if string(sum) != header+want.String() {
+		return nil, ErrChecksum
+	}
+	return migrations, nil
+}
+
+// statements counts the statements of a migration, which Atlas records as
+// the total a revision has to apply.
+func statements(sql string) int {
+	n := 0
+	scanner := bufio.NewScanner(strings.NewReader(sql))
+	for scanner.Scan() {
+		if strings.HasSuffix(strings.TrimSpace(scanner.Text()), ";") {
+			n++
+		}
+	}
+	return n
+}
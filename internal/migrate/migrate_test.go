@@ -0,0 +1,167 @@
+package migrate_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"pfg/infra/atlas"
+	"pfg/internal/db"
+	"pfg/internal/migrate"
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := migrate.Load(atlas.Migrations)
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	assert.Equal(t, "20250716153756", migrations[0].Version)
+	assert.Equal(t, "initial", migrations[0].Description)
+	for i, m := range migrations {
+		assert.NotEmpty(t, m.Down, "%s_%s has no down script", m.Version, m.Description)
+		assert.True(t, strings.HasPrefix(m.Hash, "h1:"))
+		if i > 0 {
+			assert.Less(t, migrations[i-1].Version, m.Version)
+		}
+	}
+}
+
+func TestLoadChecksum(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/1_a.sql": {Data: []byte("CREATE TABLE a (id int);\n")},
+		"migrations/2_b.sql": {Data: []byte("CREATE TABLE b (id int);\n")},
+		"migrations/atlas.sum": {Data: []byte("h1:suWOgEl4b8+ZGnGkyRcy2AueRtZKAkT6PdNTDiVWU88=\n" +
+			"1_a.sql h1:e72Wd39UNYY4sGrVkNYkCs4GRrWBQ0ASXh73rS+WpU4=\n" +
+			"2_b.sql h1:LYBzMWp8I1J0wb5WVXG/rhd1DXb0uUx/zp1eAIICVlY=\n")},
+		"down/2_b.sql": {Data: []byte("DROP TABLE b;\n")},
+	}
+	migrations, err := migrate.Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	assert.Empty(t, migrations[0].Down)
+	assert.Equal(t, "DROP TABLE b;\n", migrations[1].Down)
+
+	fsys["migrations/2_b.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE b (id bigint);\n")}
+	_, err = migrate.Load(fsys)
+	assert.ErrorIs(t, err, migrate.ErrChecksum)
+}
+
+// databaseURL returns the server at TEST_DATABASE_URL, such as the throwaway
+// one started by make test-postgres. Without it the test is skipped, except
+// when CI is set: there it fails, so the Postgres tests cannot quietly stop
+// running.
+func databaseURL(t *testing.T) string {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		if os.Getenv("CI") != "" {
+			t.Fatal("TEST_DATABASE_URL must be set when CI is")
+		}
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	return url
+}
+
+// connectToSchema connects to a fresh schema on the test server, dropped
+// when the test ends, and returns the connection with the schema's name.
+func connectToSchema(t *testing.T) (db.Conn, string) {
+	t.Helper()
+	url := databaseURL(t)
+	ctx := context.Background()
+
+	schema := fmt.Sprintf("pfg_migrate_%d", time.Now().UnixNano())
+	admin, err := db.Connect(url)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Close() })
+	_, err = admin.Pool().Exec(ctx, `CREATE SCHEMA `+schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { admin.Pool().Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`) })
+
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	conn, err := db.Connect(url + sep + "search_path=" + schema)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, schema
+}
+
+func TestMigrator(t *testing.T) {
+	conn, schema := connectToSchema(t)
+	ctx := context.Background()
+
+	m, err := migrate.New(conn.Pool(), atlas.Migrations, migrate.WithRevisionsSchema(schema))
+	require.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	all, err := migrate.Load(atlas.Migrations)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(all))
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := m.Down(ctx, len(all))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(all))
+	assert.Equal(t, all[len(all)-1].Version, reverted[0].Version)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.False(t, s.Applied)
+	}
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, len(all))
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied)
+		assert.False(t, s.Changed)
+	}
+}
+
+// The seed applies to a freshly migrated database and records its sizes as
+// a catalog version, which is what calculations read.
+func TestSeed(t *testing.T) {
+	conn, schema := connectToSchema(t)
+	ctx := context.Background()
+
+	m, err := migrate.New(conn.Pool(), atlas.Migrations, migrate.WithRevisionsSchema(schema))
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	seed, err := os.ReadFile("../../infra/atlas/seed.sql")
+	require.NoError(t, err)
+	_, err = conn.Pool().Exec(ctx, string(seed))
+	require.NoError(t, err)
+
+	service := pack.NewService(db.NewRepository(conn))
+	result, err := service.Calculate(ctx, 251)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 1}, result.Packs)
+
+	// Seeding again changes nothing.
+	_, err = conn.Pool().Exec(ctx, string(seed))
+	require.NoError(t, err)
+	versions, err := service.CatalogHistory(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "seed", versions[0].ChangedBy)
+	assert.Len(t, versions[0].Sizes, 4)
+}
@@ -0,0 +1,240 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultRevisionsSchema is where the Atlas CLI keeps its revisions table
+// when the database URL is not bound to a schema.
+const DefaultRevisionsSchema = "atlas_schema_revisions"
+
+// lockID keeps two servers starting at once from migrating together.
+const lockID = 0x7066676d // "pfgm"
+
+// revisionApplied is Atlas's revision type for a file it executed.
+const revisionApplied = 2
+
+// Migrator applies and reverts migrations on a Postgres database.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	revisions  string
+	schema     string
+}
+
+type Option func(*Migrator)
+
+// WithRevisionsSchema keeps the revisions table in schema, matching the
+// Atlas CLI's --revisions-schema flag.
+func WithRevisionsSchema(schema string) Option {
+	return func(m *Migrator) {
+		m.schema = schema
+	}
+}
+
+// New loads the migrations in fsys, see Load, for applying to pool.
+func New(pool *pgxpool.Pool, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	m := &Migrator{pool: pool, migrations: migrations, schema: DefaultRevisionsSchema}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.revisions = pgx.Identifier{m.schema, "atlas_schema_revisions"}.Sanitize()
+	return m, nil
+}
+
+// Status is a migration and whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Changed   bool // the file differs from the one that was applied
+}
+
+// Status lists every migration, oldest first.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		statuses = make([]Status, len(m.migrations))
+		for i, mig := range m.migrations {
+			statuses[i] = Status{Migration: mig}
+			if rev, ok := applied[mig.Version]; ok {
+				statuses[i].Applied = true
+				statuses[i].AppliedAt = rev.at
+				statuses[i].Changed = rev.hash != mig.Hash
+			}
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Up applies every pending migration, oldest first, each in its own
+// transaction, and returns the ones it applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the n most recently applied migrations, newest first, and
+// returns the ones it reverted. Nothing is reverted if any of them has no
+// down script.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		var revert []Migration
+		for i := len(m.migrations) - 1; i >= 0 && len(revert) < n; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("%s_%s: %w", mig.Version, mig.Description, ErrNoDown)
+			}
+			revert = append(revert, mig)
+		}
+
+		for _, mig := range revert {
+			if err := m.revert(ctx, conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn on a connection holding the migration lock, creating the
+// revisions table first if needed.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	_, err = conn.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS `+pgx.Identifier{m.schema}.Sanitize())
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS `+m.revisions+` (
+			version character varying NOT NULL,
+			description character varying NOT NULL,
+			type bigint NOT NULL DEFAULT 2,
+			applied bigint NOT NULL DEFAULT 0,
+			total bigint NOT NULL DEFAULT 0,
+			executed_at timestamptz NOT NULL,
+			execution_time bigint NOT NULL,
+			error text NULL,
+			error_stmt text NULL,
+			hash character varying NOT NULL,
+			partial_hashes jsonb NULL,
+			operator_version character varying NOT NULL,
+			PRIMARY KEY (version)
+		)`)
+	if err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+type revision struct {
+	hash string
+	at   time.Time
+}
+
+// applied returns the fully applied revisions by version.
+func (m *Migrator) applied(ctx context.Context, conn *pgxpool.Conn) (map[string]revision, error) {
+	rows, err := conn.Query(ctx, `SELECT version, hash, executed_at FROM `+m.revisions+` WHERE applied = total`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[string]revision{}
+	for rows.Next() {
+		var (
+			version string
+			rev     revision
+		)
+		if err := rows.Scan(&version, &rev.hash, &rev.at); err != nil {
+			return nil, err
+		}
+		// Atlas keeps bookkeeping rows with versions starting with a dot.
+		if !strings.HasPrefix(version, ".") {
+			applied[version] = rev
+		}
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, mig Migration) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		start := time.Now()
+		if _, err := tx.Exec(ctx, mig.Up); err != nil {
+			return fmt.Errorf("apply %s_%s: %w", mig.Version, mig.Description, err)
+		}
+		n := statements(mig.Up)
+		_, err := tx.Exec(ctx, `
+			INSERT INTO `+m.revisions+` (version, description, type, applied, total, executed_at, execution_time, hash, operator_version)
+			VALUES ($1, $2, $3, $4, $4, $5, $6, $7, 'pfg')
+			ON CONFLICT (version) DO UPDATE SET
+				description = excluded.description, type = excluded.type, applied = excluded.applied, total = excluded.total,
+				executed_at = excluded.executed_at, execution_time = excluded.execution_time, error = NULL, error_stmt = NULL,
+				hash = excluded.hash, partial_hashes = NULL, operator_version = excluded.operator_version`,
+			mig.Version, mig.Description, revisionApplied, n, start, time.Since(start).Nanoseconds(), mig.Hash)
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, conn *pgxpool.Conn, mig Migration) error {
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, mig.Down); err != nil {
+			return fmt.Errorf("revert %s_%s: %w", mig.Version, mig.Description, err)
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM `+m.revisions+` WHERE version = $1`, mig.Version)
+		return err
+	})
+}
@@ -0,0 +1,178 @@
+package pack
+
+import (
+	"container/heap"
+	"context"
+	"maps"
+)
+
+// MaxAlternatives is the largest number of alternatives a calculation may
+// ask for.
+const MaxAlternatives = 20
+
+// alternativesBudget caps how many partial combinations the search for
+// alternatives may queue, so that catalogs with many equivalent
+// combinations cannot stall a calculation.
+const alternativesBudget = 1 << 20
+
+var ErrInvalidAlternatives = kindErrorf(ErrInvalid, "alternatives must be between 0 and %d", MaxAlternatives)
+
+// partial is a combination under construction in the alternatives search.
+// Layers above layer have been decided; bound is the best candidate any
+// completion of it can reach.
+type partial struct {
+	layer  int   // next layer of the bounded table to decide, -1 when complete
+	rest   int   // normalised total still to be covered by layers <= layer
+	packs  int   // packs taken so far, excluding folded ones
+	cost   int64 // cost of the packs taken so far
+	counts []int // packs taken per layer
+	bound  candidate
+	seq    int // insertion order, keeps ties deterministic
+}
+
+type partialQueue struct {
+	items  []*partial
+	better func(a, b candidate) bool
+}
+
+func (q *partialQueue) Len() int { return len(q.items) }
+
+func (q *partialQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if q.better(a.bound, b.bound) {
+		return true
+	}
+	if q.better(b.bound, a.bound) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (q *partialQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+func (q *partialQueue) Push(x any) { q.items = append(q.items, x.(*partial)) }
+
+func (q *partialQueue) Pop() any {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
+
+// alternatives returns up to p.Alternatives distinct combinations ranked by
+// the objective, starting with best.
+//
+// The search walks the layers of a bounded table from the largest size
+// down, deciding how many packs of each size to take. The layer below
+// always knows the best completion of what is left, so every partial
+// combination carries an exact bound and complete combinations leave the
+// queue in ranking order. Like the main result, only totals inside the table
+// window are considered, so combinations that could drop a pack and still
+// cover the order are never offered. Folded packs are kept as they are, so
+// for very large orders the alternatives only differ in the remainder.
+func (o objective) alternatives(ctx context.Context, bt *boundedTable, p Problem, best PackResult) ([]PackResult, error) {
+	out := []PackResult{best}
+	if p.Alternatives <= 1 {
+		return out, nil
+	}
+
+	foldItems, foldCost := 0, int64(0)
+	if bt.fold >= 0 {
+		foldItems = bt.shift * bt.sizes[bt.fold]
+		foldCost = int64(bt.shift) * bt.unitCost(bt.fold)
+	}
+
+	q := &partialQueue{better: o.better}
+	seq := 0
+	push := func(n *partial) {
+		n.seq = seq
+		seq++
+		heap.Push(q, n)
+	}
+
+	top := len(bt.sizes) - 1
+	for _, c := range bt.candidates() {
+		if o.only != nil && !o.only(c) {
+			continue
+		}
+		push(&partial{layer: top, rest: c.t, counts: make([]int, len(bt.sizes)), bound: c})
+	}
+
+	for q.Len() > 0 && len(out) < p.Alternatives {
+		if seq > alternativesBudget {
+			break
+		}
+
+		n := heap.Pop(q).(*partial)
+		if n.layer < 0 {
+			comb := bt.decode(n.counts)
+			if !maps.Equal(comb, best.Packs) {
+				out = append(out, PackResult{
+					Requested:  p.Quantity,
+					TotalItems: n.bound.items,
+					TotalPacks: n.bound.packs,
+					Packs:      comb,
+				})
+			}
+			continue
+		}
+
+		i, s := n.layer, bt.sizes[n.layer]
+		kmax := n.rest / s
+		if bt.avail[i] >= 0 {
+			kmax = min(kmax, bt.avail[i])
+		}
+		for k := 0; k <= kmax; k++ {
+			if seq%cancelCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+
+			rest := n.rest - k*s
+			c, w := bt.below(i, rest)
+			if c == unreachable {
+				continue
+			}
+
+			child := &partial{
+				layer:  i - 1,
+				rest:   rest,
+				packs:  n.packs + k,
+				cost:   n.cost + int64(k)*bt.unitCost(i),
+				counts: append([]int(nil), n.counts...),
+			}
+			child.counts[i] = k
+			child.bound = candidate{
+				t:     n.bound.t,
+				items: (n.bound.t + foldItems) * bt.unit,
+				packs: child.packs + int(c) - 1 + bt.shift,
+				cost:  child.cost + w + foldCost,
+			}
+			push(child)
+		}
+	}
+
+	return out, nil
+}
+
+// decode turns packs per layer into a breakdown in original pack sizes,
+// including the folded packs.
+func (bt *boundedTable) decode(counts []int) map[int]int {
+	comb := map[int]int{}
+	for i, k := range counts {
+		if k > 0 {
+			comb[bt.sizes[i]*bt.unit] += k
+		}
+	}
+	if bt.shift > 0 {
+		comb[bt.sizes[bt.fold]*bt.unit] += bt.shift
+	}
+	return comb
+}
+
+func checkAlternatives(n int) error {
+	if n < 0 || n > MaxAlternatives {
+		return ErrInvalidAlternatives
+	}
+	return nil
+}
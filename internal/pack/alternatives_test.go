@@ -0,0 +1,159 @@
+package pack_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// metrics is what an objective ranks a combination by.
+type metrics struct {
+	items int
+	packs int
+	cost  int64
+}
+
+// naiveRanking enumerates every combination covering quantity with fewer
+// than quantity+largest items and sorts them with less.
+func naiveRanking(sizes []int, costs map[int]int64, quantity int, less func(a, b metrics) bool) []metrics {
+	limit := quantity + sizes[len(sizes)-1]
+
+	var all []metrics
+	var walk func(i int, m metrics)
+	walk = func(i int, m metrics) {
+		if m.items >= limit {
+			return
+		}
+		if i == len(sizes) {
+			if m.items >= quantity {
+				all = append(all, m)
+			}
+			return
+		}
+		for k := 0; m.items+k*sizes[i] < limit; k++ {
+			walk(i+1, metrics{
+				items: m.items + k*sizes[i],
+				packs: m.packs + k,
+				cost:  m.cost + int64(k)*costs[sizes[i]],
+			})
+		}
+	}
+	walk(0, metrics{})
+
+	sort.SliceStable(all, func(i, j int) bool { return less(all[i], all[j]) })
+	return all
+}
+
+func TestCalculateAlternatives(t *testing.T) {
+	sizes := []int{23, 31, 53}
+	costs := map[int]int64{23: 10, 31: 12, 53: 25}
+
+	tests := []struct {
+		strategy string
+		less     func(a, b metrics) bool
+	}{
+		{pack.StrategyMinItems, func(a, b metrics) bool {
+			if a.items != b.items {
+				return a.items < b.items
+			}
+			return a.packs < b.packs
+		}},
+		{pack.StrategyFewestPacks, func(a, b metrics) bool {
+			if a.packs != b.packs {
+				return a.packs < b.packs
+			}
+			return a.items < b.items
+		}},
+		{pack.StrategyMinCost, func(a, b metrics) bool {
+			if a.cost != b.cost {
+				return a.cost < b.cost
+			}
+			if a.items != b.items {
+				return a.items < b.items
+			}
+			return a.packs < b.packs
+		}},
+	}
+
+	service := pack.NewService(newRepo(t, sizes, costs, nil))
+	for _, tt := range tests {
+		for q := 1; q < 300; q += 17 {
+			name := fmt.Sprintf("%s quantity %d", tt.strategy, q)
+			want := naiveRanking(sizes, costs, q, tt.less)
+			if len(want) > 5 {
+				want = want[:5]
+			}
+
+			result, err := service.Calculate(context.Background(), q, pack.WithStrategy(tt.strategy), pack.WithAlternatives(5))
+			assert.NoError(t, err, name)
+			if !assert.Len(t, result.Alternatives, len(want), name) {
+				continue
+			}
+
+			assert.Equal(t, result.Packs, result.Alternatives[0].Packs, name)
+			seen := map[string]bool{}
+			for i, alt := range result.Alternatives {
+				got := metrics{items: alt.TotalItems, packs: alt.TotalPacks, cost: alt.TotalCost}
+				assert.False(t, tt.less(want[i], got) || tt.less(got, want[i]), "%s alternative %d: got %+v, want %+v", name, i, got, want[i])
+				assert.Equal(t, alt.TotalItems-q, alt.Overage(), name)
+				assertBreakdown(t, alt)
+
+				key := fmt.Sprint(alt.Packs)
+				assert.False(t, seen[key], "%s: duplicate alternative %v", name, alt.Packs)
+				seen[key] = true
+			}
+		}
+	}
+}
+
+func TestCalculateAlternativesExact(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{250, 500, 1000}, nil, nil))
+
+	result, err := service.Calculate(context.Background(), 1000, pack.WithStrategy(pack.StrategyExact), pack.WithAlternatives(10))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[int]int{
+		{1000: 1},
+		{500: 2},
+		{500: 1, 250: 2},
+		{250: 4},
+	}, packsOf(result.Alternatives))
+	for _, alt := range result.Alternatives {
+		assert.Equal(t, pack.DefaultCatalog, alt.Catalog)
+		assert.Equal(t, result.CatalogVersion, alt.CatalogVersion)
+		assert.NotZero(t, alt.CatalogVersion)
+	}
+}
+
+func TestCalculateAlternativesWithinStock(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{250, 500, 1000}, nil, map[int]int{1000: 0, 500: 1}))
+
+	result, err := service.Calculate(context.Background(), 1000, pack.WithAlternatives(3))
+	assert.NoError(t, err)
+	assert.Equal(t, []map[int]int{
+		{500: 1, 250: 2},
+		{250: 4},
+		{500: 1, 250: 3},
+	}, packsOf(result.Alternatives))
+}
+
+func TestCalculateAlternativesInvalid(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{250}, nil, nil))
+
+	_, err := service.Calculate(context.Background(), 1, pack.WithAlternatives(-1))
+	assert.ErrorIs(t, err, pack.ErrInvalidAlternatives)
+	_, err = service.Calculate(context.Background(), 1, pack.WithAlternatives(pack.MaxAlternatives+1))
+	assert.ErrorIs(t, err, pack.ErrInvalidAlternatives)
+}
+
+func packsOf(results []pack.PackResult) []map[int]int {
+	out := make([]map[int]int, len(results))
+	for i, r := range results {
+		out[i] = r.Packs
+	}
+	return out
+}
@@ -0,0 +1,284 @@
+package pack
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrInsufficientStock = errors.New("not enough packs in stock")
+
+// boundedTable is the stock-aware counterpart of table. It keeps one layer
+// per pack size, so the number of packs taken of each size can be capped
+// while filling and recovered while rebuilding a combination.
+//
+// Large orders are folded on the best pack without a stock limit, so the
+// window does not grow with the quantity when a better pack runs out:
+// swapping a run of other packs for fold packs only frees up stock, and the
+// packs ranking above the fold pack add no more than their stock.
+type boundedTable struct {
+	unit  int
+	sizes []int      // normalised sizes with stock, ascending
+	avail []int      // packs available per size, -1 when unlimited
+	costs []int64    // unit cost per size, nil when costs are ignored
+	fold  int        // index of the folded size, -1 when nothing is folded
+	shift int        // number of fold packs removed from the request
+	count [][]uint32 // count[i][t]: packs+1 for t using sizes[:i+1]
+	cost  [][]int64  // lowest cost matching count, nil when costs are ignored
+}
+
+func newBoundedTable(ctx context.Context, p Problem, weighted bool) (*boundedTable, error) {
+	var inStock []int
+	for _, s := range p.Sizes {
+		if n, tracked := p.Stock[s]; !tracked || n > 0 {
+			inStock = append(inStock, s)
+		}
+	}
+	if len(inStock) == 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	norm, unit := normalise(inStock)
+	largest := norm[len(norm)-1]
+
+	avail := make([]int, len(norm))
+	capacity, unlimited := 0, false
+	for i, s := range norm {
+		n, tracked := p.Stock[s*unit]
+		if !tracked {
+			avail[i] = -1
+			unlimited = true
+			continue
+		}
+		avail[i] = n
+		capacity += n * s
+	}
+
+	target := ceilDiv(p.Quantity, unit)
+	if !unlimited && target > capacity {
+		return nil, ErrInsufficientStock
+	}
+
+	var costs []int64
+	if weighted {
+		costs = make([]int64, len(norm))
+		for i, s := range norm {
+			costs[i] = p.Costs[s*unit]
+		}
+	}
+
+	// Fold on the largest unlimited size, or the cheapest per item when
+	// costs are used, as table does among all sizes.
+	fold := -1
+	for i := len(norm) - 1; i >= 0; i-- {
+		if avail[i] >= 0 {
+			continue
+		}
+		if fold < 0 || (weighted && costs[i]*int64(norm[fold]) < costs[fold]*int64(norm[i])) {
+			fold = i
+		}
+	}
+
+	shift := 0
+	if fold >= 0 {
+		if limit := boundedThreshold(norm, avail, costs, fold); target > limit {
+			shift = (target - limit) / norm[fold]
+			target -= shift * norm[fold]
+		}
+	}
+
+	window := target + largest
+	work := 0
+	for i, s := range norm {
+		if avail[i] < 0 {
+			work += window
+		} else {
+			work += window * min(avail[i]+1, window/s+1)
+		}
+	}
+	if err := p.Limits.checkWork(work); err != nil {
+		return nil, err
+	}
+
+	bt := &boundedTable{unit: unit, sizes: norm, avail: avail, costs: costs, fold: fold, shift: shift}
+	bt.count = make([][]uint32, len(norm))
+	if weighted {
+		bt.cost = make([][]int64, len(norm))
+	}
+
+	for i, s := range norm {
+		bt.count[i] = make([]uint32, window)
+		if weighted {
+			bt.cost[i] = make([]int64, window)
+		}
+		for t := 0; t < window; t++ {
+			if t > 0 && t%cancelCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+
+			best, bestCost := bt.below(i, t)
+			if avail[i] < 0 {
+				// Unlimited sizes extend their own layer, as in table.
+				if t >= s && bt.count[i][t-s] != unreachable {
+					best, bestCost = better(best, bestCost, bt.count[i][t-s]+1, bt.costAt(i, t-s)+bt.unitCost(i))
+				}
+			}
+			kmax := 0
+			if avail[i] >= 0 {
+				kmax = min(t/s, avail[i])
+			}
+			for k := 1; k <= kmax; k++ {
+				c, w := bt.below(i, t-k*s)
+				if c == unreachable {
+					continue
+				}
+				best, bestCost = better(best, bestCost, c+uint32(k), w+int64(k)*bt.unitCost(i))
+			}
+
+			bt.count[i][t] = best
+			if weighted {
+				bt.cost[i][t] = bestCost
+			}
+		}
+	}
+
+	return bt, nil
+}
+
+// boundedThreshold is threshold for a fold pack with unlimited stock among
+// sizes that may be limited. Only packs ranking below the fold pack, which
+// are smaller or cost more per item, can be swapped for fold packs; the
+// ones ranking above it are all limited, and their stock caps their sum.
+func boundedThreshold(sizes, avail []int, costs []int64, fold int) int {
+	f := sizes[fold]
+	limit, other := f, 0
+	for i, s := range sizes {
+		if i == fold {
+			continue
+		}
+		below := s < f
+		if costs != nil {
+			a, b := costs[i]*int64(f), costs[fold]*int64(s)
+			below = a > b || (a == b && s < f)
+		}
+		if below {
+			other = max(other, s)
+		} else {
+			limit += avail[i] * s
+		}
+	}
+	return limit + (f-1)*other
+}
+
+// better returns whichever of the two (packs+1, cost) pairs ranks first:
+// reachable before unreachable, then lower cost, then fewer packs.
+func better(c1 uint32, w1 int64, c2 uint32, w2 int64) (uint32, int64) {
+	if c2 == unreachable {
+		return c1, w1
+	}
+	if c1 == unreachable || w2 < w1 || (w2 == w1 && c2 < c1) {
+		return c2, w2
+	}
+	return c1, w1
+}
+
+// below returns the layer under size i at total t, treating the empty
+// combination as the layer under the first size.
+func (bt *boundedTable) below(i, t int) (uint32, int64) {
+	if i == 0 {
+		if t == 0 {
+			return 1, 0
+		}
+		return unreachable, 0
+	}
+	return bt.count[i-1][t], bt.costAt(i-1, t)
+}
+
+func (bt *boundedTable) costAt(i, t int) int64 {
+	if bt.cost == nil {
+		return 0
+	}
+	return bt.cost[i][t]
+}
+
+func (bt *boundedTable) unitCost(i int) int64 {
+	if bt.costs == nil {
+		return 0
+	}
+	return bt.costs[i]
+}
+
+func (bt *boundedTable) candidates() []candidate {
+	top := len(bt.sizes) - 1
+	foldItems, foldCost := 0, int64(0)
+	if bt.fold >= 0 {
+		foldItems = bt.shift * bt.sizes[bt.fold]
+		foldCost = int64(bt.shift) * bt.unitCost(bt.fold)
+	}
+
+	var out []candidate
+	for t := len(bt.count[top]) - bt.sizes[top]; t < len(bt.count[top]); t++ {
+		if bt.count[top][t] == unreachable {
+			continue
+		}
+		out = append(out, candidate{
+			t:     t,
+			items: (t + foldItems) * bt.unit,
+			packs: int(bt.count[top][t]) - 1 + bt.shift,
+			cost:  bt.costAt(top, t) + foldCost,
+		})
+	}
+	return out
+}
+
+// combination walks the layers from the largest size down, taking as few
+// packs of each size as the optimum allows, or as many when largestFirst is
+// set.
+func (bt *boundedTable) combination(t int, largestFirst bool) map[int]int {
+	comb := map[int]int{}
+	for i := len(bt.sizes) - 1; i >= 0; i-- {
+		k := bt.take(i, t, largestFirst)
+		if k > 0 {
+			comb[bt.sizes[i]*bt.unit] += k
+			t -= k * bt.sizes[i]
+		}
+	}
+	if bt.shift > 0 {
+		comb[bt.sizes[bt.fold]*bt.unit] += bt.shift
+	}
+	return comb
+}
+
+// take returns how many packs of size i an optimal combination for t at
+// layer i uses.
+func (bt *boundedTable) take(i, t int, largestFirst bool) int {
+	s := bt.sizes[i]
+	kmax := t / s
+	if bt.avail[i] >= 0 {
+		kmax = min(kmax, bt.avail[i])
+	}
+	want, wantCost := bt.count[i][t], bt.costAt(i, t)
+
+	for n := 0; n <= kmax; n++ {
+		k := n
+		if largestFirst {
+			k = kmax - n
+		}
+		c, w := bt.below(i, t-k*s)
+		if c != unreachable && c+uint32(k) == want && w+int64(k)*bt.unitCost(i) == wantCost {
+			return k
+		}
+	}
+	return 0
+}
+
+// fitsStock reports whether packs can be taken from stock.
+func fitsStock(packs map[int]int, stock map[int]int) bool {
+	for size, count := range packs {
+		if n, tracked := stock[size]; tracked && count > n {
+			return false
+		}
+	}
+	return true
+}
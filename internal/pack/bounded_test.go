@@ -0,0 +1,131 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// naiveBounded enumerates every combination within stock and returns the
+// one with the fewest items, then fewest packs.
+func naiveBounded(sizes []int, stock map[int]int, quantity int) (items, packs int) {
+	limit := quantity + sizes[len(sizes)-1]
+	items, packs = -1, -1
+
+	var walk func(i, total, count int)
+	walk = func(i, total, count int) {
+		if total > limit {
+			return
+		}
+		if i == len(sizes) {
+			if total >= quantity && (items < 0 || total < items || (total == items && count < packs)) {
+				items, packs = total, count
+			}
+			return
+		}
+		maxK := limit / sizes[i]
+		if n, ok := stock[sizes[i]]; ok && n < maxK {
+			maxK = n
+		}
+		for k := 0; k <= maxK; k++ {
+			walk(i+1, total+k*sizes[i], count+k)
+		}
+	}
+	walk(0, 0, 0)
+	return items, packs
+}
+
+func TestCalculateWithinStock(t *testing.T) {
+	sizes := []int{23, 31, 53}
+	stocks := []map[int]int{
+		{53: 0},
+		{53: 2, 31: 1},
+		{23: 3, 31: 3, 53: 3},
+		{31: 0, 23: 10},
+	}
+
+	for _, stock := range stocks {
+		service := pack.NewService(newRepo(t, sizes, nil, stock))
+		for q := 1; q < 400; q += 11 {
+			items, packs := naiveBounded(sizes, stock, q)
+
+			result, err := service.Calculate(context.Background(), q)
+			if items < 0 {
+				assert.ErrorIs(t, err, pack.ErrInsufficientStock, "stock %v quantity %d", stock, q)
+				continue
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, items, result.TotalItems, "stock %v quantity %d", stock, q)
+			assert.Equal(t, packs, result.TotalPacks, "stock %v quantity %d", stock, q)
+			assertBreakdown(t, result)
+			for size, count := range result.Packs {
+				if n, ok := stock[size]; ok {
+					assert.LessOrEqual(t, count, n, "stock %v quantity %d", stock, q)
+				}
+			}
+		}
+	}
+}
+
+func TestCalculateWithinStockLargeQuantity(t *testing.T) {
+	repo := newRepo(t, []int{250, 500, 1000, 2000, 5000}, nil, map[int]int{250: 1, 500: 0})
+	service := pack.NewService(repo)
+
+	result, err := service.Calculate(context.Background(), 1_000_000_001)
+	assert.NoError(t, err)
+	assert.Equal(t, 1_000_000_250, result.TotalItems)
+	assert.Equal(t, 200_001, result.TotalPacks)
+	assert.Equal(t, map[int]int{5000: 200_000, 250: 1}, result.Packs)
+}
+
+// Running out of the best pack still folds large orders on the best one
+// left, so the table stays small however large the quantity.
+func TestCalculateLargeQuantityWithLimitedLargestPack(t *testing.T) {
+	repo := newRepo(t, []int{250, 500, 1000, 2000, 5000},
+		map[int]int64{250: 100, 500: 180, 1000: 300, 2000: 550, 5000: 1000},
+		map[int]int{5000: 3})
+	service := pack.NewService(repo, pack.WithLimits(pack.Limits{MaxWork: 100_000}))
+
+	result, err := service.Calculate(context.Background(), 1_000_000_001, pack.WithAlternatives(3), pack.WithExplain(true))
+	assert.NoError(t, err)
+	assert.Equal(t, 1_000_000_250, result.TotalItems)
+	assert.Equal(t, 499_997, result.TotalPacks)
+	assert.Equal(t, map[int]int{5000: 3, 2000: 499_992, 1000: 1, 250: 1}, result.Packs)
+
+	result, err = service.Calculate(context.Background(), 1_000_000_001, pack.WithStrategy(pack.StrategyMinCost))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(274_999_000), result.TotalCost)
+	assert.Equal(t, 3, result.Packs[5000])
+}
+
+func TestCalculateOutOfStock(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{250, 500}, nil, map[int]int{250: 1, 500: 1}))
+
+	_, err := service.Calculate(context.Background(), 751)
+	assert.ErrorIs(t, err, pack.ErrInsufficientStock)
+}
+
+func TestReserveStock(t *testing.T) {
+	repo := newRepo(t, []int{250, 500}, nil, nil)
+	service := pack.NewService(repo)
+
+	assert.NoError(t, service.SetStock(context.Background(), pack.DefaultCatalog, 500, 2))
+	assert.Error(t, service.SetStock(context.Background(), pack.DefaultCatalog, 750, 2))
+
+	result, err := service.Calculate(context.Background(), 1250)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 2, 250: 1}, result.Packs)
+
+	assert.NoError(t, service.ReserveStock(context.Background(), pack.DefaultCatalog, result.Packs))
+	stock, err := repo.GetStock(context.Background(), pack.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 0}, stock)
+
+	result, err = service.Calculate(context.Background(), 1250)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 5}, result.Packs)
+	assert.ErrorIs(t, service.ReserveStock(context.Background(), pack.DefaultCatalog, map[int]int{500: 1}), pack.ErrInsufficientStock)
+}
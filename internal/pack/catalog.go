@@ -0,0 +1,65 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// DefaultCatalog is the catalog used when none is named. It holds the pack
+// sizes that existed before catalogs were introduced and cannot be removed.
+const DefaultCatalog = "default"
+
+var (
+	ErrUnknownCatalog = kindErrorf(ErrNotFound, "unknown catalog")
+	ErrDefaultCatalog = kindErrorf(ErrInvalid, "the default catalog cannot be removed")
+)
+
+var catalogIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Catalog is a named set of pack sizes, typically one per product family.
+type Catalog struct {
+	ID   string // short slug such as "bolts", used in URLs and requests
+	Name string
+}
+
+func (s *Service) ListCatalogs(ctx context.Context) ([]Catalog, error) {
+	return s.repo.GetCatalogs(ctx)
+}
+
+func (s *Service) AddCatalog(ctx context.Context, c Catalog) error {
+	if !catalogIDPattern.MatchString(c.ID) {
+		return kindErrorf(ErrInvalid, "invalid catalog id: use lowercase letters, digits and dashes")
+	}
+	if c.Name == "" {
+		c.Name = c.ID
+	}
+
+	err := s.repo.InsertCatalog(ctx, c, change(ctx, "created catalog"))
+	if errors.Is(err, ErrDuplicate) {
+		return kindErrorf(ErrDuplicate, "catalog %q already exists", c.ID)
+	}
+	return err
+}
+
+// RemoveCatalog deletes a catalog together with its pack sizes and stock.
+func (s *Service) RemoveCatalog(ctx context.Context, id string) error {
+	if id == DefaultCatalog {
+		return ErrDefaultCatalog
+	}
+	return s.repo.DeleteCatalog(ctx, id)
+}
+
+// catalogID returns id, or the default catalog when id is empty.
+func catalogID(id string) string {
+	if id == "" {
+		return DefaultCatalog
+	}
+	return id
+}
+
+// checkCatalog returns ErrUnknownCatalog unless the catalog exists.
+func (s *Service) checkCatalog(ctx context.Context, id string) error {
+	_, err := s.repo.GetCatalog(ctx, id)
+	return err
+}
@@ -0,0 +1,59 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CatalogCache keeps the catalog version and stock each catalog resolved to
+// for the first calculation against it, so that a batch of calculations
+// reads them once per catalog rather than once per calculation. Changes
+// made to a catalog or its stock after that are not seen by calculations
+// using the cache. A CatalogCache is not safe for concurrent use; give
+// each batch of calculations its own.
+type CatalogCache struct {
+	catalogs map[string]resolvedCatalog
+}
+
+func NewCatalogCache() *CatalogCache {
+	return &CatalogCache{catalogs: map[string]resolvedCatalog{}}
+}
+
+// resolvedCatalog is what a calculation reads from the repository before
+// solving. Unknown catalogs and versions are kept as their error.
+type resolvedCatalog struct {
+	version CatalogVersion
+	stock   map[int]int // nil for historical calculations
+	err     error
+}
+
+// resolve returns the catalog version a calculation runs against and the
+// stock that applies to it, reading them from the repository unless c
+// already holds them. A nil cache reads them every time.
+func (c *CatalogCache) resolve(ctx context.Context, s *Service, catalog string, o calcOptions) (CatalogVersion, map[int]int, error) {
+	key := fmt.Sprint(catalog, o.version, o.asOf)
+	if rc, ok := c.lookup(key); ok {
+		return rc.version, rc.stock, rc.err
+	}
+
+	var rc resolvedCatalog
+	rc.version, rc.err = s.snapshot(ctx, catalog, o)
+	// Historical calculations replay a past catalog, so today's stock does
+	// not apply to them.
+	if rc.err == nil && !o.historical() {
+		rc.stock, rc.err = s.repo.GetStock(ctx, catalog)
+	}
+	if c != nil && (rc.err == nil || errors.Is(rc.err, ErrNotFound)) {
+		c.catalogs[key] = rc
+	}
+	return rc.version, rc.stock, rc.err
+}
+
+func (c *CatalogCache) lookup(key string) (resolvedCatalog, bool) {
+	if c == nil {
+		return resolvedCatalog{}, false
+	}
+	rc, ok := c.catalogs[key]
+	return rc, ok
+}
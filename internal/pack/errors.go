@@ -0,0 +1,36 @@
+package pack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of failure callers can act on without knowing the specific cause.
+// Every error of a kind matches it with errors.Is, e.g. ErrUnknownCatalog
+// is ErrNotFound.
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("already exists")
+	ErrInvalid   = errors.New("invalid input")
+)
+
+// Errors repositories report for pack sizes.
+var (
+	ErrUnknownPackSize   = kindErrorf(ErrNotFound, "pack size not found")
+	ErrDuplicatePackSize = kindErrorf(ErrDuplicate, "pack size already exists")
+	ErrDuplicateCatalog  = kindErrorf(ErrDuplicate, "catalog already exists")
+)
+
+// kindError is an error of one of the kinds above, with its own message.
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+
+func (e *kindError) Unwrap() error { return e.kind }
+
+func kindErrorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
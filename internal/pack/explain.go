@@ -0,0 +1,241 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// MaxExplainedTotals caps how many totals an Explanation lists.
+const MaxExplainedTotals = 200
+
+// maxExplainedTies caps how many tied combinations an Explanation lists.
+const maxExplainedTies = 5
+
+// maxSummaryTotals caps how many totals of each kind the summary names
+// before it only counts the rest.
+const maxSummaryTotals = 10
+
+// Statuses of a total in an Explanation.
+const (
+	TotalChosen      = "chosen"
+	TotalUnreachable = "unreachable"
+	TotalOutOfStock  = "out-of-stock"
+	TotalRankedLower = "ranked-lower"
+)
+
+// Explanation traces how a calculation arrived at its result: which totals
+// were searched, why each of them was or was not picked and how ties were
+// broken.
+type Explanation struct {
+	Unit       int          // every total that can be shipped is a multiple of Unit
+	Folded     int          // packs of FoldSize set aside before searching
+	FoldSize   int          // pack size set aside for large orders
+	SearchFrom int          // smallest total searched, in items
+	SearchTo   int          // largest total searched, in items
+	Totals     []TotalTrace // searched totals in ascending order
+	Truncated  bool         // Totals was cut at MaxExplainedTotals
+	Ties       []PackResult // other combinations ranked equal to the result
+	TieBreak   string       // how the result was preferred over Ties
+	Summary    []string     // human-readable trace
+}
+
+// TotalTrace is a single searched total in an Explanation.
+type TotalTrace struct {
+	Items     int
+	Reachable bool
+	Packs     int   // packs in the best combination for Items, 0 when not reachable
+	Cost      int64 // cost of that combination when the strategy ranks by cost
+	Status    string
+}
+
+// cell is a total in a table window, reachable or not.
+type cell struct {
+	candidate
+	reachable bool
+}
+
+// cells returns every total in the window of the table.
+func (tb *table) cells() []cell {
+	var out []cell
+	for t := len(tb.count) - tb.sizes[len(tb.sizes)-1]; t < len(tb.count); t++ {
+		c := cell{candidate: candidate{t: t, items: tb.items(t)}, reachable: tb.count[t] != unreachable}
+		if c.reachable {
+			c.packs, c.cost = tb.packs(t), tb.totalCost(t)
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// cells returns every total in the window of the bounded table.
+func (bt *boundedTable) cells() []cell {
+	reachable := map[int]candidate{}
+	for _, c := range bt.candidates() {
+		reachable[c.t] = c
+	}
+
+	foldItems := 0
+	if bt.fold >= 0 {
+		foldItems = bt.shift * bt.sizes[bt.fold]
+	}
+
+	top := len(bt.sizes) - 1
+	var out []cell
+	for t := len(bt.count[top]) - bt.sizes[top]; t < len(bt.count[top]); t++ {
+		if c, ok := reachable[t]; ok {
+			out = append(out, cell{candidate: c, reachable: true})
+			continue
+		}
+		out = append(out, cell{candidate: candidate{t: t, items: (t + foldItems) * bt.unit}})
+	}
+	return out
+}
+
+// explain builds the Explanation for result. bt is the stock-aware table
+// and bounded reports whether result was picked from it rather than from
+// the unbounded tb.
+func (o objective) explain(ctx context.Context, tb *table, bt *boundedTable, bounded bool, p Problem, result PackResult) (*Explanation, error) {
+	e := &Explanation{Unit: tb.unit, Folded: tb.shift, FoldSize: tb.sizes[tb.fold] * tb.unit}
+	cells := tb.cells()
+	if bounded {
+		e.Unit, e.Folded, e.FoldSize = bt.unit, bt.shift, 0
+		if bt.fold >= 0 {
+			e.FoldSize = bt.sizes[bt.fold] * bt.unit
+		}
+		cells = bt.cells()
+	}
+	e.SearchFrom, e.SearchTo = cells[0].items, cells[len(cells)-1].items
+
+	totals := map[int]TotalTrace{}
+	for _, c := range cells {
+		tr := TotalTrace{Items: c.items, Reachable: c.reachable, Status: TotalUnreachable}
+		if c.reachable {
+			tr.Packs, tr.Cost, tr.Status = c.packs, c.cost, TotalRankedLower
+		}
+		if c.items == result.TotalItems {
+			tr.Status = TotalChosen
+		}
+		totals[c.items] = tr
+	}
+	if bounded {
+		// Totals only the unbounded table can reach need packs that are not
+		// in stock.
+		for _, c := range tb.cells() {
+			if !c.reachable || c.items < e.SearchFrom || c.items > e.SearchTo {
+				continue
+			}
+			if tr, ok := totals[c.items]; !ok || !tr.Reachable {
+				totals[c.items] = TotalTrace{Items: c.items, Status: TotalOutOfStock}
+			}
+		}
+	}
+
+	for _, tr := range totals {
+		e.Totals = append(e.Totals, tr)
+	}
+	sort.Slice(e.Totals, func(i, j int) bool { return e.Totals[i].Items < e.Totals[j].Items })
+
+	ties, err := o.alternatives(ctx, bt, Problem{Quantity: p.Quantity, Alternatives: maxExplainedTies + 1}, result)
+	if err != nil {
+		return nil, err
+	}
+	chosen := totals[result.TotalItems]
+	for _, alt := range ties[1:] {
+		if alt.TotalItems != result.TotalItems || alt.TotalPacks != result.TotalPacks {
+			continue
+		}
+		if o.weighted && bt.costOf(alt.Packs) != chosen.Cost {
+			continue
+		}
+		e.Ties = append(e.Ties, alt)
+	}
+	e.TieBreak = "smaller packs are preferred on ties"
+	if o.largestFirst {
+		e.TieBreak = "larger packs are preferred on ties"
+	}
+
+	e.Summary = e.summarise(p.Quantity, result, o.weighted)
+
+	if len(e.Totals) > MaxExplainedTotals {
+		e.Totals, e.Truncated = e.Totals[:MaxExplainedTotals], true
+		if e.Totals[len(e.Totals)-1].Items < result.TotalItems {
+			e.Totals = append(e.Totals, chosen)
+		}
+	}
+	return e, nil
+}
+
+// costOf returns what packs cost in the table's unit costs.
+func (bt *boundedTable) costOf(packs map[int]int) int64 {
+	var cost int64
+	for i, s := range bt.sizes {
+		cost += int64(packs[s*bt.unit]) * bt.unitCost(i)
+	}
+	return cost
+}
+
+func (e *Explanation) summarise(quantity int, result PackResult, weighted bool) []string {
+	var lines []string
+	if e.Unit > 1 {
+		lines = append(lines, fmt.Sprintf("All pack sizes are multiples of %d, so only multiples of %d items can be shipped.", e.Unit, e.Unit))
+	}
+	if e.Folded > 0 {
+		lines = append(lines, fmt.Sprintf("%d packs of %d were set aside first: an order this large always contains them, so only the rest was searched.", e.Folded, e.FoldSize))
+	}
+	lines = append(lines, fmt.Sprintf("Searched totals from %d to %d items. Larger totals are never better, as a pack could be dropped and the order would still be covered.", e.SearchFrom, e.SearchTo))
+
+	byStatus := map[string][]TotalTrace{}
+	for _, tr := range e.Totals {
+		if tr.Items < result.TotalItems {
+			byStatus[tr.Status] = append(byStatus[tr.Status], tr)
+		}
+	}
+	describe := func(status string, format func(TotalTrace) string, rest string) {
+		list := byStatus[status]
+		for i, tr := range list {
+			if i == maxSummaryTotals {
+				lines = append(lines, fmt.Sprintf(rest, len(list)-i))
+				break
+			}
+			lines = append(lines, format(tr))
+		}
+	}
+	describe(TotalUnreachable, func(tr TotalTrace) string {
+		return fmt.Sprintf("%d items is impossible: no combination of the pack sizes adds up to it.", tr.Items)
+	}, "%d more lower totals are impossible.")
+	describe(TotalOutOfStock, func(tr TotalTrace) string {
+		return fmt.Sprintf("%d items would need packs that are out of stock.", tr.Items)
+	}, "%d more lower totals would need packs that are out of stock.")
+	describe(TotalRankedLower, func(tr TotalTrace) string {
+		if weighted {
+			return fmt.Sprintf("%d items is possible with %d packs costing %d cents, but ranks below the chosen total.", tr.Items, tr.Packs, tr.Cost)
+		}
+		return fmt.Sprintf("%d items is possible with %d packs, but ranks below the chosen total.", tr.Items, tr.Packs)
+	}, "%d more lower totals are possible but rank below the chosen total.")
+
+	if result.TotalItems == quantity {
+		lines = append(lines, fmt.Sprintf("Chose %d items in %d packs, exactly the quantity requested.", result.TotalItems, result.TotalPacks))
+	} else {
+		lines = append(lines, fmt.Sprintf("Chose %d items in %d packs, %d over the quantity requested.", result.TotalItems, result.TotalPacks, result.Overage()))
+	}
+
+	if len(e.Ties) == 0 {
+		lines = append(lines, fmt.Sprintf("No other combination of %d items uses %d packs.", result.TotalItems, result.TotalPacks))
+		return lines
+	}
+
+	ties := "1 other combination of %d items also uses %d packs"
+	if len(e.Ties) > 1 {
+		ties = fmt.Sprintf("%d other combinations of %%d items also use %%d packs", len(e.Ties))
+	}
+	if len(e.Ties) == maxExplainedTies {
+		ties = "At least " + ties
+	}
+	sameCost := ""
+	if weighted {
+		sameCost = " at the same cost"
+	}
+	lines = append(lines, fmt.Sprintf(ties, result.TotalItems, result.TotalPacks)+sameCost+"; "+e.TieBreak+".")
+	return lines
+}
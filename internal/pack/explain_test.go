@@ -0,0 +1,72 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateExplain(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{250, 500, 1000}, nil, nil))
+
+	result, err := service.Calculate(context.Background(), 1001, pack.WithExplain(true))
+	assert.NoError(t, err)
+	e := result.Explanation
+	if !assert.NotNil(t, e) {
+		return
+	}
+
+	assert.Equal(t, 250, e.Unit)
+	assert.Equal(t, 1250, e.SearchFrom)
+	assert.Equal(t, []pack.TotalTrace{
+		{Items: 1250, Reachable: true, Packs: 2, Status: pack.TotalChosen},
+		{Items: 1500, Reachable: true, Packs: 2, Status: pack.TotalRankedLower},
+		{Items: 1750, Reachable: true, Packs: 3, Status: pack.TotalRankedLower},
+		{Items: 2000, Reachable: true, Packs: 2, Status: pack.TotalRankedLower},
+	}, e.Totals)
+	assert.Empty(t, e.Ties)
+	assert.Contains(t, e.Summary, "All pack sizes are multiples of 250, so only multiples of 250 items can be shipped.")
+	assert.Contains(t, e.Summary, "Chose 1250 items in 2 packs, 249 over the quantity requested.")
+}
+
+func TestCalculateExplainLowerTotals(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{6, 9, 20}, nil, map[int]int{6: 0}))
+
+	result, err := service.Calculate(context.Background(), 10, pack.WithExplain(true))
+	assert.NoError(t, err)
+	e := result.Explanation
+	if !assert.NotNil(t, e) {
+		return
+	}
+
+	status := map[int]string{}
+	for _, tr := range e.Totals {
+		status[tr.Items] = tr.Status
+	}
+	assert.Equal(t, pack.TotalOutOfStock, status[12])
+	assert.Equal(t, pack.TotalUnreachable, status[10])
+	assert.Equal(t, pack.TotalChosen, status[18])
+	assert.Equal(t, 18, result.TotalItems)
+	assert.Contains(t, e.Summary, "12 items would need packs that are out of stock.")
+}
+
+func TestCalculateExplainTies(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{1, 2, 5, 7}, nil, nil))
+
+	result, err := service.Calculate(context.Background(), 11, pack.WithStrategy(pack.StrategyLargePacks), pack.WithExplain(true))
+	assert.NoError(t, err)
+	e := result.Explanation
+	if !assert.NotNil(t, e) {
+		return
+	}
+
+	assert.Equal(t, map[int]int{7: 1, 2: 2}, result.Packs)
+	assert.Equal(t, []map[int]int{{5: 2, 1: 1}}, packsOf(e.Ties))
+	assert.Equal(t, pack.DefaultCatalog, e.Ties[0].Catalog)
+	assert.Equal(t, result.CatalogVersion, e.Ties[0].CatalogVersion)
+	assert.Equal(t, "larger packs are preferred on ties", e.TieBreak)
+	assert.Contains(t, e.Summary, "1 other combination of 11 items also uses 3 packs; larger packs are preferred on ties.")
+}
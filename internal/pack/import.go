@@ -0,0 +1,133 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// CatalogDiff is how importing a set of pack sizes changes a catalog. Each
+// list is ordered by size.
+type CatalogDiff struct {
+	Added     []PackSize
+	Removed   []PackSize
+	Changed   []PackChange
+	Unchanged []PackSize
+}
+
+// PackChange is a pack size an import keeps but with a different cost,
+// metadata, schedule or state.
+type PackChange struct {
+	Before PackSize
+	After  PackSize
+}
+
+// HasChanges reports whether applying the diff changes the catalog at all.
+func (d CatalogDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0
+}
+
+// ExportPacks returns every pack size of a catalog, active or not, failing
+// with ErrUnknownCatalog if there is no such catalog.
+func (s *Service) ExportPacks(ctx context.Context, catalog string) ([]PackSize, error) {
+	catalog = catalogID(catalog)
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPackSizes(ctx, catalog)
+}
+
+// PreviewImport reports how ImportPacks would change a catalog, without
+// changing it.
+func (s *Service) PreviewImport(ctx context.Context, catalog string, packs []PackSize) (CatalogDiff, error) {
+	catalog = catalogID(catalog)
+	if err := s.checkImport(packs); err != nil {
+		return CatalogDiff{}, err
+	}
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return CatalogDiff{}, err
+	}
+	existing, err := s.repo.GetPackSizes(ctx, catalog)
+	if err != nil {
+		return CatalogDiff{}, err
+	}
+
+	current := make(map[int]PackSize, len(existing))
+	for _, p := range existing {
+		current[p.Size] = p
+	}
+	imported := make(map[int]bool, len(packs))
+
+	diff := CatalogDiff{Added: []PackSize{}, Removed: []PackSize{}, Changed: []PackChange{}, Unchanged: []PackSize{}}
+	for _, p := range sortedPacks(packs) {
+		imported[p.Size] = true
+		before, ok := current[p.Size]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, p)
+		case samePack(before, p):
+			diff.Unchanged = append(diff.Unchanged, p)
+		default:
+			diff.Changed = append(diff.Changed, PackChange{Before: before, After: p})
+		}
+	}
+	for _, p := range existing {
+		if !imported[p.Size] {
+			diff.Removed = append(diff.Removed, p)
+		}
+	}
+	return diff, nil
+}
+
+// ImportPacks makes packs the whole set of pack sizes of a catalog, with
+// every field as given, in a single change. Stock of the sizes that stay
+// is kept. It returns the diff it applied.
+func (s *Service) ImportPacks(ctx context.Context, catalog string, packs []PackSize) (CatalogDiff, error) {
+	diff, err := s.PreviewImport(ctx, catalog, packs)
+	if err != nil || !diff.HasChanges() {
+		return diff, err
+	}
+
+	description := fmt.Sprintf("imported %d pack sizes: %d added, %d removed, %d changed", len(packs), len(diff.Added), len(diff.Removed), len(diff.Changed))
+	return diff, s.repo.ReplacePackSizes(ctx, catalogID(catalog), sortedPacks(packs), change(ctx, description))
+}
+
+// checkImport validates imported pack sizes. Unlike SchedulePack it takes
+// schedule times in the past, as an exported catalog carries them.
+func (s *Service) checkImport(packs []PackSize) error {
+	sizes := make([]int, len(packs))
+	for i, p := range packs {
+		sizes[i] = p.Size
+	}
+	if err := s.checkSizeSet(sizes); err != nil {
+		return err
+	}
+
+	for _, p := range packs {
+		if p.UnitCost < 0 {
+			return kindErrorf(ErrInvalid, "invalid unit cost for pack size %d", p.Size)
+		}
+		if err := checkDetails(p); err != nil {
+			return err
+		}
+		if err := checkScheduleOrder(p.Schedule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortedPacks(packs []PackSize) []PackSize {
+	sorted := append([]PackSize(nil), packs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Size < sorted[j].Size })
+	return sorted
+}
+
+// samePack reports whether a and b hold the same values. Schedule times
+// are compared as instants, as repositories may return them in another
+// location.
+func samePack(a, b PackSize) bool {
+	as, bs := a.Schedule, b.Schedule
+	a.Schedule, b.Schedule = Schedule{}, Schedule{}
+	return a == b && as.AvailableFrom.Equal(bs.AvailableFrom) && as.RetiresAt.Equal(bs.RetiresAt)
+}
@@ -0,0 +1,81 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPacks(t *testing.T) {
+	repo := newRepo(t, []int{250, 500, 1000}, map[int]int64{250: 100, 500: 150}, map[int]int{500: 7})
+	service := pack.NewService(repo)
+	ctx := context.Background()
+
+	past := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	file := []pack.PackSize{
+		{Size: 2000, UnitCost: 400},
+		{Size: 500, UnitCost: 150},
+		{Size: 250, UnitCost: 110, Label: "Small box", Schedule: pack.Schedule{AvailableFrom: past}},
+	}
+
+	diff, err := service.PreviewImport(ctx, "", file)
+	require.NoError(t, err)
+	assert.Equal(t, pack.CatalogDiff{
+		Added:     []pack.PackSize{{Size: 2000, UnitCost: 400}},
+		Removed:   []pack.PackSize{{Size: 1000}},
+		Changed:   []pack.PackChange{{Before: pack.PackSize{Size: 250, UnitCost: 100}, After: file[2]}},
+		Unchanged: []pack.PackSize{{Size: 500, UnitCost: 150}},
+	}, diff)
+	assert.True(t, diff.HasChanges())
+
+	// The preview changes nothing.
+	history, err := service.CatalogHistory(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	applied, err := service.ImportPacks(ctx, "", file)
+	require.NoError(t, err)
+	assert.Equal(t, diff, applied)
+
+	packs, err := service.ExportPacks(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{file[2], file[1], file[0]}, packs)
+	stock, err := service.ListStock(ctx, "")
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 7}, stock)
+
+	history, err = service.CatalogHistory(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "imported 3 pack sizes: 1 added, 1 removed, 1 changed", history[0].Change)
+
+	// Importing the export again is a no-op.
+	diff, err = service.ImportPacks(ctx, "", packs)
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+	assert.Len(t, diff.Unchanged, 3)
+	history, err = service.CatalogHistory(ctx, "")
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	for _, bad := range [][]pack.PackSize{
+		nil,
+		{{Size: 250}, {Size: 250}},
+		{{Size: 0}},
+		{{Size: 250, UnitCost: -1}},
+		{{Size: 250, TareWeight: -1}},
+		{{Size: 250, Schedule: pack.Schedule{AvailableFrom: past, RetiresAt: past}}},
+	} {
+		_, err := service.PreviewImport(ctx, "", bad)
+		assert.ErrorIs(t, err, pack.ErrInvalid, "%v", bad)
+	}
+	_, err = service.PreviewImport(ctx, "missing", file)
+	assert.ErrorIs(t, err, pack.ErrNotFound)
+	_, err = service.ExportPacks(ctx, "missing")
+	assert.ErrorIs(t, err, pack.ErrNotFound)
+}
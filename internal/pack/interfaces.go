@@ -1,9 +1,71 @@
 package pack
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
+// Repository stores pack catalogs together with their pack sizes, version
+// history and stock, as well as the orders placed against them. Everything
+// but the catalogs and orders themselves is keyed by catalog ID.
+//
+// Every change to a catalog's pack sizes records a new CatalogVersion in the
+// same transaction, described by the Change passed in.
 type Repository interface {
-	GetPackSizes(ctx context.Context) ([]int, error)
-	InsertPackSize(ctx context.Context, size int) error
-	DeletePackSize(ctx context.Context, size int) error
+	GetCatalogs(ctx context.Context) ([]Catalog, error)
+	// GetCatalog returns ErrUnknownCatalog if there is no catalog with id.
+	GetCatalog(ctx context.Context, id string) (Catalog, error)
+	// InsertCatalog creates the catalog along with its first, empty version.
+	InsertCatalog(ctx context.Context, c Catalog, change Change) error
+	// DeleteCatalog removes the catalog along with its pack sizes and stock.
+	DeleteCatalog(ctx context.Context, id string) error
+
+	GetPackSizes(ctx context.Context, catalogID string) ([]PackSize, error)
+	InsertPackSize(ctx context.Context, catalogID string, p PackSize, change Change) error
+	UpdatePackCost(ctx context.Context, catalogID string, size int, unitCost int64, change Change) error
+	// UpdatePackDetails sets the label, SKU, dimensions and tare weight of
+	// the pack size p.Size, failing with ErrUnknownPackSize if there is none.
+	UpdatePackDetails(ctx context.Context, catalogID string, p PackSize, change Change) error
+	// SetPackActive marks the pack size active or inactive, failing with
+	// ErrUnknownPackSize if there is none.
+	SetPackActive(ctx context.Context, catalogID string, size int, active bool, change Change) error
+	// UpdatePackSchedule replaces the schedule of the pack size, failing with
+	// ErrUnknownPackSize if there is none.
+	UpdatePackSchedule(ctx context.Context, catalogID string, size int, s Schedule, change Change) error
+	DeletePackSize(ctx context.Context, catalogID string, size int, change Change) error
+	// ReplacePackSizes makes packs the catalog's whole set of pack sizes in
+	// a single transaction: sizes not among them are deleted and the rest
+	// are stored with every field as given. The sizes must be unique.
+	ReplacePackSizes(ctx context.Context, catalogID string, packs []PackSize, change Change) error
+
+	// GetCatalogVersions returns every version of a catalog, newest first.
+	GetCatalogVersions(ctx context.Context, catalogID string) ([]CatalogVersion, error)
+	// GetCatalogVersion returns the given version of a catalog, or the
+	// latest one when version is 0. It fails with ErrUnknownVersion if
+	// there is no such version.
+	GetCatalogVersion(ctx context.Context, catalogID string, version int) (CatalogVersion, error)
+	// GetCatalogVersionAt returns the version of a catalog that was current
+	// at the given time, failing with ErrUnknownVersion if the catalog did
+	// not exist yet.
+	GetCatalogVersionAt(ctx context.Context, catalogID string, at time.Time) (CatalogVersion, error)
+
+	// GetStock returns the packs on hand for every tracked size. Sizes
+	// without an entry are not tracked and treated as unlimited.
+	GetStock(ctx context.Context, catalogID string) (map[int]int, error)
+	SetStock(ctx context.Context, catalogID string, size int, onHand int) error
+	DeleteStock(ctx context.Context, catalogID string, size int) error
+	// ReserveStock takes packs out of stock atomically, failing with
+	// ErrInsufficientStock if any tracked size would go negative.
+	ReserveStock(ctx context.Context, catalogID string, packs map[int]int) error
+
+	// InsertOrder stores the order and returns it with its ID and CreatedAt
+	// set. With reserve set, the order's packs are taken out of stock like
+	// ReserveStock does, in the same transaction. Orders stored this way
+	// have no recorded states; the lifecycle stores new orders together
+	// with their first one.
+	InsertOrder(ctx context.Context, o Order, reserve bool) (Order, error)
+	// GetOrder returns ErrUnknownOrder if there is no order with id.
+	GetOrder(ctx context.Context, id int64) (Order, error)
+	// GetOrders returns up to f.Limit orders matching f, newest first.
+	GetOrders(ctx context.Context, f OrderFilter) ([]Order, error)
 }
@@ -0,0 +1,68 @@
+package pack
+
+import "fmt"
+
+// Limits caps how much work a single calculation may do and how many pack
+// sizes a catalog may hold. Zero values mean no limit.
+type Limits struct {
+	// MaxQuantity is the largest quantity accepted by Calculate.
+	MaxQuantity int
+	// MaxWork is the largest number of solver steps (table cells times
+	// pack sizes) a calculation may take.
+	MaxWork int
+	// MaxOrderLines is the largest number of lines CalculateOrder accepts,
+	// each of which is a calculation of its own.
+	MaxOrderLines int
+	// MaxPackSizes is the largest number of pack sizes in one catalog.
+	MaxPackSizes int
+}
+
+// TooExpensiveError is returned when a calculation would exceed the
+// configured Limits.
+type TooExpensiveError struct {
+	Field string // "quantity", "work" or "lines"
+	Value int
+	Limit int
+}
+
+func (e *TooExpensiveError) Error() string {
+	return fmt.Sprintf("calculation too expensive: %s %d exceeds limit %d", e.Field, e.Value, e.Limit)
+}
+
+func (l Limits) checkQuantity(quantity int) error {
+	if l.MaxQuantity > 0 && quantity > l.MaxQuantity {
+		return &TooExpensiveError{Field: "quantity", Value: quantity, Limit: l.MaxQuantity}
+	}
+	return nil
+}
+
+func (l Limits) checkWork(work int) error {
+	if l.MaxWork > 0 && work > l.MaxWork {
+		return &TooExpensiveError{Field: "work", Value: work, Limit: l.MaxWork}
+	}
+	return nil
+}
+
+func (l Limits) checkOrderLines(lines int) error {
+	if l.MaxOrderLines > 0 && lines > l.MaxOrderLines {
+		return &TooExpensiveError{Field: "lines", Value: lines, Limit: l.MaxOrderLines}
+	}
+	return nil
+}
+
+func (l Limits) checkPackSizes(n int) error {
+	if l.MaxPackSizes > 0 && n > l.MaxPackSizes {
+		return kindErrorf(ErrInvalid, "a catalog cannot have more than %d pack sizes", l.MaxPackSizes)
+	}
+	return nil
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithLimits sets the compute budget applied to every calculation.
+func WithLimits(l Limits) Option {
+	return func(s *Service) {
+		s.limits = l
+	}
+}
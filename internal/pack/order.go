@@ -0,0 +1,78 @@
+package pack
+
+import "context"
+
+var ErrInvalidQuantity = kindErrorf(ErrInvalid, "invalid quantity")
+
+// OrderLine is one line of a multi-line order: a product reference, the
+// catalog its packaging comes from and how many items of it are wanted.
+type OrderLine struct {
+	Reference string
+	Catalog   string // empty uses the catalog given to CalculateOrder, if any
+	Quantity  int
+}
+
+// LineResult is the outcome of a single order line. Err is set when the line
+// could not be calculated, in which case Result is empty.
+type LineResult struct {
+	Line   OrderLine
+	Result PackResult
+	Err    error
+}
+
+// OrderResult holds every line of an order in request order, together with
+// totals over the lines that were calculated successfully.
+type OrderResult struct {
+	Lines      []LineResult
+	Requested  int
+	TotalItems int
+	TotalPacks int
+	TotalCost  int64 // in cents
+	Failed     int   // number of lines with an error
+}
+
+// Overage is how many items the successful lines ship beyond what they
+// asked for.
+func (o OrderResult) Overage() int {
+	return o.TotalItems - o.Requested
+}
+
+// CalculateOrder runs Calculate for each line of an order. A failing line
+// does not stop the others; its error is reported on the line instead. Lines
+// are calculated independently, so each one sees the full stock. Orders with
+// more lines than the limits allow fail as a whole with a
+// *TooExpensiveError.
+func (s *Service) CalculateOrder(ctx context.Context, lines []OrderLine, opts ...CalcOption) (OrderResult, error) {
+	if err := s.limits.checkOrderLines(len(lines)); err != nil {
+		return OrderResult{}, err
+	}
+	order := OrderResult{Lines: make([]LineResult, len(lines))}
+
+	for i, line := range lines {
+		order.Lines[i].Line = line
+		if line.Quantity <= 0 {
+			order.Lines[i].Err = ErrInvalidQuantity
+			order.Failed++
+			continue
+		}
+
+		lineOpts := opts
+		if line.Catalog != "" {
+			lineOpts = append(opts[:len(opts):len(opts)], WithCatalog(line.Catalog))
+		}
+		result, err := s.Calculate(ctx, line.Quantity, lineOpts...)
+		if err != nil {
+			order.Lines[i].Err = err
+			order.Failed++
+			continue
+		}
+
+		order.Lines[i].Result = result
+		order.Requested += result.Requested
+		order.TotalItems += result.TotalItems
+		order.TotalPacks += result.TotalPacks
+		order.TotalCost += result.TotalCost
+	}
+
+	return order, nil
+}
@@ -0,0 +1,60 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculateOrder(t *testing.T) {
+	repo := newRepo(t, []int{250, 500, 1000}, map[int]int64{250: 100, 500: 150, 1000: 250}, nil)
+	service := pack.NewService(repo, pack.WithLimits(pack.Limits{MaxQuantity: 10_000}))
+
+	order, err := service.CalculateOrder(context.Background(), []pack.OrderLine{
+		{Reference: "SKU-1", Quantity: 251},
+		{Reference: "SKU-2", Quantity: 0},
+		{Reference: "SKU-3", Quantity: 1000},
+		{Reference: "SKU-4", Quantity: 20_000},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, order.Lines, 4)
+	assert.Equal(t, 2, order.Failed)
+
+	assert.NoError(t, order.Lines[0].Err)
+	assert.Equal(t, "SKU-1", order.Lines[0].Line.Reference)
+	assert.Equal(t, map[int]int{500: 1}, order.Lines[0].Result.Packs)
+
+	assert.ErrorIs(t, order.Lines[1].Err, pack.ErrInvalidQuantity)
+
+	assert.NoError(t, order.Lines[2].Err)
+	assert.Equal(t, map[int]int{1000: 1}, order.Lines[2].Result.Packs)
+
+	var tooExpensive *pack.TooExpensiveError
+	assert.ErrorAs(t, order.Lines[3].Err, &tooExpensive)
+
+	assert.Equal(t, 1251, order.Requested)
+	assert.Equal(t, 1500, order.TotalItems)
+	assert.Equal(t, 2, order.TotalPacks)
+	assert.Equal(t, int64(400), order.TotalCost)
+	assert.Equal(t, 249, order.Overage())
+}
+
+func TestCalculateOrderTooManyLines(t *testing.T) {
+	repo := newRepo(t, []int{250, 500}, nil, nil)
+	service := pack.NewService(repo, pack.WithLimits(pack.Limits{MaxOrderLines: 2}))
+
+	_, err := service.CalculateOrder(context.Background(), make([]pack.OrderLine, 3))
+	var tooExpensive *pack.TooExpensiveError
+	require.ErrorAs(t, err, &tooExpensive)
+	assert.Equal(t, "lines", tooExpensive.Field)
+	assert.Equal(t, 3, tooExpensive.Value)
+
+	order, err := service.CalculateOrder(context.Background(), []pack.OrderLine{{Quantity: 1}, {Quantity: 251}})
+	require.NoError(t, err)
+	assert.Zero(t, order.Failed)
+}
@@ -5,109 +5,385 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// PackSize is a pack size in the catalog together with what one pack of
+// it costs and how the warehouse knows it.
+type PackSize struct {
+	Size     int
+	UnitCost int64 // in cents
+
+	Label      string // display name such as "Small box"
+	SKU        string // internal stock keeping unit
+	Dimensions Dimensions
+	TareWeight int // weight of the empty pack in grams
+
+	// Inactive sizes stay in the catalog but are not used by calculations
+	// until they are activated again.
+	Inactive bool
+	Schedule Schedule
+}
+
+// Dimensions are the outer dimensions of a pack in millimetres. Zero means
+// not recorded.
+type Dimensions struct {
+	Length int
+	Width  int
+	Height int
+}
+
+// checkDetails validates the metadata of a pack size.
+func checkDetails(p PackSize) error {
+	if p.Dimensions.Length < 0 || p.Dimensions.Width < 0 || p.Dimensions.Height < 0 {
+		return kindErrorf(ErrInvalid, "invalid pack dimensions")
+	}
+	if p.TareWeight < 0 {
+		return kindErrorf(ErrInvalid, "invalid tare weight")
+	}
+	return nil
+}
+
 type PackResult struct {
 	Requested  int
 	TotalItems int
 	TotalPacks int
 	Packs      map[int]int
+	Strategy   string
+	Catalog    string
+	// CatalogVersion is the version of the catalog the result was
+	// calculated against.
+	CatalogVersion int
+	// Details holds the catalog entry of every size the result could have
+	// used, for its label, SKU, dimensions and weight.
+	Details   map[int]PackSize
+	TotalCost int64         // in cents
+	LineCosts map[int]int64 // cost of all packs of each size, in cents
+	// Alternatives holds the best distinct combinations in ranking order
+	// when asked for with WithAlternatives, starting with this one.
+	Alternatives []PackResult
+	// Explanation traces how this result was chosen when asked for with
+	// WithExplain.
+	Explanation *Explanation
+}
+
+// Overage is how many items the result ships beyond the request.
+func (r PackResult) Overage() int {
+	return r.TotalItems - r.Requested
 }
 
 type Service struct {
-	repo Repository
+	repo   Repository
+	limits Limits
+	now    func() time.Time
 }
 
-func NewService(repo Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo Repository, opts ...Option) *Service {
+	s := &Service{repo: repo, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *Service) ListPacks(ctx context.Context) ([]int, error) {
-	return s.repo.GetPackSizes(ctx)
+func (s *Service) ListPacks(ctx context.Context, catalog string) ([]PackSize, error) {
+	catalog = catalogID(catalog)
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPackSizes(ctx, catalog)
 }
 
-func (s *Service) AddPack(ctx context.Context, size int) error {
-	if size <= 0 {
-		return errors.New("invalid pack size")
+func (s *Service) AddPack(ctx context.Context, catalog string, p PackSize) error {
+	catalog = catalogID(catalog)
+	if p.Size <= 0 {
+		return kindErrorf(ErrInvalid, "invalid pack size")
+	}
+	if p.UnitCost < 0 {
+		return kindErrorf(ErrInvalid, "invalid unit cost")
+	}
+	if err := checkDetails(p); err != nil {
+		return err
+	}
+	if err := s.checkSchedule(p.Schedule); err != nil {
+		return err
 	}
 
-	existing, err := s.repo.GetPackSizes(ctx)
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetPackSizes(ctx, catalog)
 	if err != nil {
 		return err
 	}
+	if err := s.limits.checkPackSizes(len(existing) + 1); err != nil {
+		return err
+	}
 
-	for _, s := range existing {
-		if s == size {
-			return fmt.Errorf("pack size %d already exists", size)
-		}
+	// The repository rejects duplicates itself, so concurrent adds of the
+	// same size cannot both succeed.
+	err = s.repo.InsertPackSize(ctx, catalog, p, change(ctx, fmt.Sprintf("added pack size %d at %d cents", p.Size, p.UnitCost)))
+	if errors.Is(err, ErrDuplicate) {
+		return kindErrorf(ErrDuplicate, "pack size %d already exists", p.Size)
 	}
+	return err
+}
 
-	return s.repo.InsertPackSize(ctx, size)
+func (s *Service) SetPackCost(ctx context.Context, catalog string, size int, unitCost int64) error {
+	if unitCost < 0 {
+		return kindErrorf(ErrInvalid, "invalid unit cost")
+	}
+	return s.repo.UpdatePackCost(ctx, catalogID(catalog), size, unitCost, change(ctx, fmt.Sprintf("set cost of pack size %d to %d cents", size, unitCost)))
 }
 
-func (s *Service) RemovePack(ctx context.Context, size int) error {
-	return s.repo.DeletePackSize(ctx, size)
+// SetPackDetails replaces the label, SKU, dimensions and tare weight of the
+// pack size p.Size, leaving its cost alone.
+func (s *Service) SetPackDetails(ctx context.Context, catalog string, p PackSize) error {
+	if err := checkDetails(p); err != nil {
+		return err
+	}
+	return s.repo.UpdatePackDetails(ctx, catalogID(catalog), p, change(ctx, fmt.Sprintf("updated details of pack size %d", p.Size)))
+}
+
+// ActivatePack makes a deactivated pack size available to calculations
+// again. Activating an active size does nothing.
+func (s *Service) ActivatePack(ctx context.Context, catalog string, size int) error {
+	return s.setPackActive(ctx, catalog, size, true)
 }
 
-func (s *Service) Calculate(ctx context.Context, quantity int) (PackResult, error) {
-	sizes, err := s.repo.GetPackSizes(ctx)
+// DeactivatePack keeps a pack size in the catalog, with its stock and
+// history, but leaves it out of calculations. Deactivating an inactive
+// size does nothing.
+func (s *Service) DeactivatePack(ctx context.Context, catalog string, size int) error {
+	return s.setPackActive(ctx, catalog, size, false)
+}
+
+func (s *Service) setPackActive(ctx context.Context, catalog string, size int, active bool) error {
+	catalog = catalogID(catalog)
+	existing, err := s.repo.GetPackSizes(ctx, catalog)
 	if err != nil {
-		return PackResult{}, err
+		return err
 	}
 
-	if len(sizes) == 0 {
-		return PackResult{}, errors.New("no pack sizes available")
+	for _, p := range existing {
+		if p.Size != size {
+			continue
+		}
+		if p.Inactive != active {
+			return nil
+		}
+		description := fmt.Sprintf("deactivated pack size %d", size)
+		if active {
+			description = fmt.Sprintf("activated pack size %d", size)
+		}
+		return s.repo.SetPackActive(ctx, catalog, size, active, change(ctx, description))
 	}
+	return kindErrorf(ErrNotFound, "pack size %d not found", size)
+}
 
-	sort.Ints(sizes)
+func (s *Service) RemovePack(ctx context.Context, catalog string, size int) error {
+	return s.repo.DeletePackSize(ctx, catalogID(catalog), size, change(ctx, fmt.Sprintf("removed pack size %d", size)))
+}
 
-	maxSize := sizes[len(sizes)-1]
-	limit := quantity + maxSize*2
+// ReplacePacks makes sizes the whole set of pack sizes of a catalog in a
+// single change, so calculations never see it half edited. Sizes the
+// catalog already has keep their cost, details, schedule and state; new
+// ones start out active and at no cost.
+func (s *Service) ReplacePacks(ctx context.Context, catalog string, sizes []int) error {
+	catalog = catalogID(catalog)
+	if err := s.checkSizeSet(sizes); err != nil {
+		return err
+	}
 
-	type state struct {
-		packCount   int
-		combination map[int]int
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetPackSizes(ctx, catalog)
+	if err != nil {
+		return err
+	}
+	current := make(map[int]PackSize, len(existing))
+	for _, p := range existing {
+		current[p.Size] = p
 	}
 
-	dp := make([]*state, limit+1)
-	dp[0] = &state{packCount: 0, combination: map[int]int{}}
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+	if len(existing) == len(sorted) {
+		unchanged := true
+		for i, p := range existing {
+			unchanged = unchanged && p.Size == sorted[i]
+		}
+		if unchanged {
+			return nil
+		}
+	}
 
-	for i := 0; i <= limit; i++ {
-		if dp[i] == nil {
-			continue
+	packs := make([]PackSize, len(sorted))
+	labels := make([]string, len(sorted))
+	for i, size := range sorted {
+		p, ok := current[size]
+		if !ok {
+			p = PackSize{Size: size}
 		}
-		for _, size := range sizes {
-			next := i + size
-			if next > limit {
-				continue
-			}
-			newCount := dp[i].packCount + 1
-			if dp[next] == nil || newCount < dp[next].packCount {
-				newComb := copyMap(dp[i].combination)
-				newComb[size]++
-				dp[next] = &state{packCount: newCount, combination: newComb}
-			}
+		packs[i], labels[i] = p, strconv.Itoa(size)
+	}
+	return s.repo.ReplacePackSizes(ctx, catalog, packs, change(ctx, "replaced pack sizes with "+strings.Join(labels, ", ")))
+}
+
+// checkSizeSet validates a whole new set of pack sizes for a catalog.
+func (s *Service) checkSizeSet(sizes []int) error {
+	if len(sizes) == 0 {
+		return kindErrorf(ErrInvalid, "a catalog needs at least one pack size")
+	}
+	seen := make(map[int]bool, len(sizes))
+	for _, size := range sizes {
+		if size <= 0 {
+			return kindErrorf(ErrInvalid, "invalid pack size %d", size)
+		}
+		if seen[size] {
+			return kindErrorf(ErrInvalid, "pack size %d is listed more than once", size)
+		}
+		seen[size] = true
+	}
+	return s.limits.checkPackSizes(len(sizes))
+}
+
+func (s *Service) ListStock(ctx context.Context, catalog string) (map[int]int, error) {
+	catalog = catalogID(catalog)
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return nil, err
+	}
+	return s.repo.GetStock(ctx, catalog)
+}
+
+// SetStock records how many packs of size are on hand. The size must be in
+// the catalog.
+func (s *Service) SetStock(ctx context.Context, catalog string, size int, onHand int) error {
+	if onHand < 0 {
+		return kindErrorf(ErrInvalid, "invalid stock level")
+	}
+
+	catalog = catalogID(catalog)
+	existing, err := s.repo.GetPackSizes(ctx, catalog)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range existing {
+		if e.Size == size {
+			return s.repo.SetStock(ctx, catalog, size, onHand)
 		}
 	}
+	return kindErrorf(ErrNotFound, "pack size %d not found", size)
+}
+
+// UntrackStock stops tracking stock for size, making it unlimited again.
+func (s *Service) UntrackStock(ctx context.Context, catalog string, size int) error {
+	catalog = catalogID(catalog)
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return err
+	}
+	return s.repo.DeleteStock(ctx, catalog, size)
+}
+
+// ReserveStock takes the packs of a confirmed calculation out of stock.
+func (s *Service) ReserveStock(ctx context.Context, catalog string, packs map[int]int) error {
+	return s.repo.ReserveStock(ctx, catalogID(catalog), packs)
+}
+
+// ErrNoPackSizes is returned by calculations against a catalog without pack
+// sizes usable at the time they are for.
+var ErrNoPackSizes = errors.New("no pack sizes available")
+
+func (s *Service) Calculate(ctx context.Context, quantity int, opts ...CalcOption) (PackResult, error) {
+	var o calcOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	strategy, err := LookupStrategy(o.strategy)
+	if err != nil {
+		return PackResult{}, err
+	}
+
+	if err := checkAlternatives(o.alternatives); err != nil {
+		return PackResult{}, err
+	}
+
+	if err := s.limits.checkQuantity(quantity); err != nil {
+		return PackResult{}, err
+	}
+
+	catalog := catalogID(o.catalog)
+	version, stock, err := o.catalogs.resolve(ctx, s, catalog, o)
+	if err != nil {
+		return PackResult{}, err
+	}
 
-	for i := quantity; i <= limit; i++ {
-		if dp[i] != nil {
-			return PackResult{
-				Requested:  quantity,
-				TotalItems: i,
-				TotalPacks: dp[i].packCount,
-				Packs:      dp[i].combination,
-			}, nil
+	// Scheduled sizes are resolved at the time the calculation is for,
+	// which lets a future asOf preview announced changes.
+	at := o.asOf
+	if at.IsZero() {
+		at = s.now()
+	}
+	var packs []PackSize
+	for _, p := range version.Sizes {
+		if p.Usable(at) {
+			packs = append(packs, p)
 		}
 	}
+	if len(packs) == 0 {
+		return PackResult{}, ErrNoPackSizes
+	}
+
+	sizes := make([]int, len(packs))
+	costs := make(map[int]int64, len(packs))
+	details := make(map[int]PackSize, len(packs))
+	for i, p := range packs {
+		sizes[i] = p.Size
+		costs[p.Size] = p.UnitCost
+		details[p.Size] = p
+	}
 
-	return PackResult{}, errors.New("no valid pack combination found")
+	result, err := strategy.Solver.Solve(ctx, Problem{Sizes: sizes, Costs: costs, Stock: stock, Quantity: quantity, Limits: s.limits, Alternatives: o.alternatives, Explain: o.explain, Tables: o.tables})
+	if err != nil {
+		return PackResult{}, err
+	}
+	result.Strategy = strategy.Name
+	result.Catalog = catalog
+	result.CatalogVersion = version.Version
+	result.Details = details
+	result.price(costs)
+	for i := range result.Alternatives {
+		result.Alternatives[i].Strategy = strategy.Name
+		result.Alternatives[i].Catalog = catalog
+		result.Alternatives[i].CatalogVersion = version.Version
+		result.Alternatives[i].Details = details
+		result.Alternatives[i].price(costs)
+	}
+	if e := result.Explanation; e != nil {
+		for i := range e.Ties {
+			e.Ties[i].Strategy = strategy.Name
+			e.Ties[i].Catalog = catalog
+			e.Ties[i].CatalogVersion = version.Version
+			e.Ties[i].Details = details
+			e.Ties[i].price(costs)
+		}
+		e.Summary = append([]string{fmt.Sprintf("Strategy %s ranks combinations by: %s.", strategy.Name, strategy.Description)}, e.Summary...)
+	}
+	return result, nil
 }
 
-func copyMap(m map[int]int) map[int]int {
-	cp := make(map[int]int, len(m))
-	for k, v := range m {
-		cp[k] = v
+// price fills in the line and total costs of the result.
+func (r *PackResult) price(costs map[int]int64) {
+	r.TotalCost = 0
+	r.LineCosts = make(map[int]int64, len(r.Packs))
+	for size, count := range r.Packs {
+		line := int64(count) * costs[size]
+		r.LineCosts[size] = line
+		r.TotalCost += line
 	}
-	return cp
 }
@@ -4,37 +4,39 @@ import (
 	"context"
 	"testing"
 
+	"pfg/internal/memory"
 	"pfg/internal/pack"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-type mockRepo struct {
-	sizes []int
-}
-
-func (m *mockRepo) GetPackSizes(ctx context.Context) ([]int, error) {
-	return m.sizes, nil
-}
-
-func (m *mockRepo) InsertPackSize(ctx context.Context, size int) error {
-	m.sizes = append(m.sizes, size)
-	return nil
+// newRepo returns an in-memory repository whose default catalog has the
+// given sizes at their cost in costs, with stock tracked for the sizes in
+// stock.
+func newRepo(t testing.TB, sizes []int, costs map[int]int64, stock map[int]int) *memory.Repository {
+	t.Helper()
+	packs := make([]pack.PackSize, len(sizes))
+	for i, size := range sizes {
+		packs[i] = pack.PackSize{Size: size, UnitCost: costs[size]}
+	}
+	repo := memory.NewRepository(packs...)
+	for size, onHand := range stock {
+		require.NoError(t, repo.SetStock(context.Background(), pack.DefaultCatalog, size, onHand))
+	}
+	return repo
 }
 
-func (m *mockRepo) DeletePackSize(ctx context.Context, size int) error {
-	result := make([]int, 0)
-	for _, s := range m.sizes {
-		if s != size {
-			result = append(result, s)
-		}
-	}
-	m.sizes = result
-	return nil
+// onHand returns the stock of size in the default catalog.
+func onHand(t testing.TB, repo pack.Repository, size int) int {
+	t.Helper()
+	stock, err := repo.GetStock(context.Background(), pack.DefaultCatalog)
+	require.NoError(t, err)
+	return stock[size]
 }
 
 func TestCalculate(t *testing.T) {
-	repo := &mockRepo{sizes: []int{250, 500, 1000, 2000, 5000}}
+	repo := newRepo(t, []int{250, 500, 1000, 2000, 5000}, nil, nil)
 	service := pack.NewService(repo)
 
 	tests := []struct {
@@ -79,3 +81,128 @@ func TestCalculate(t *testing.T) {
 		})
 	}
 }
+
+func TestCalculateCatalogs(t *testing.T) {
+	repo := newRepo(t, []int{250, 500, 1000}, nil, nil)
+	service := pack.NewService(repo)
+	ctx := context.Background()
+
+	assert.NoError(t, service.AddCatalog(ctx, pack.Catalog{ID: "widgets", Name: "Widgets"}))
+	assert.ErrorIs(t, service.AddCatalog(ctx, pack.Catalog{ID: "widgets"}), pack.ErrDuplicate)
+	assert.ErrorIs(t, service.AddCatalog(ctx, pack.Catalog{ID: "Not A Slug"}), pack.ErrInvalid)
+	for _, size := range []int{6, 12, 24} {
+		assert.NoError(t, service.AddPack(ctx, "widgets", pack.PackSize{Size: size}))
+	}
+	assert.ErrorIs(t, service.AddPack(ctx, "gadgets", pack.PackSize{Size: 5}), pack.ErrUnknownCatalog)
+	assert.ErrorIs(t, service.AddPack(ctx, "gadgets", pack.PackSize{Size: 5}), pack.ErrNotFound)
+	assert.EqualError(t, service.AddPack(ctx, "widgets", pack.PackSize{Size: 6}), "pack size 6 already exists")
+	assert.ErrorIs(t, service.AddPack(ctx, "widgets", pack.PackSize{Size: 6}), pack.ErrDuplicate)
+	assert.ErrorIs(t, service.AddPack(ctx, "widgets", pack.PackSize{Size: -6}), pack.ErrInvalid)
+
+	result, err := service.Calculate(ctx, 30, pack.WithCatalog("widgets"))
+	assert.NoError(t, err)
+	assert.Equal(t, "widgets", result.Catalog)
+	assert.Equal(t, map[int]int{6: 1, 24: 1}, result.Packs)
+
+	result, err = service.Calculate(ctx, 30)
+	assert.NoError(t, err)
+	assert.Equal(t, pack.DefaultCatalog, result.Catalog)
+	assert.Equal(t, map[int]int{250: 1}, result.Packs)
+
+	_, err = service.Calculate(ctx, 30, pack.WithCatalog("gadgets"))
+	assert.ErrorIs(t, err, pack.ErrUnknownCatalog)
+	_, err = service.ListStock(ctx, "gadgets")
+	assert.ErrorIs(t, err, pack.ErrUnknownCatalog)
+	assert.ErrorIs(t, service.UntrackStock(ctx, "gadgets", 6), pack.ErrUnknownCatalog)
+
+	assert.ErrorIs(t, service.RemoveCatalog(ctx, pack.DefaultCatalog), pack.ErrInvalid)
+	assert.NoError(t, service.RemoveCatalog(ctx, "widgets"))
+}
+
+func TestPackDetails(t *testing.T) {
+	repo := newRepo(t, []int{250, 500}, nil, nil)
+	service := pack.NewService(repo)
+	ctx := context.Background()
+
+	box := pack.PackSize{Size: 250, Label: "Small box", SKU: "BOX-250", Dimensions: pack.Dimensions{Length: 300, Width: 200, Height: 150}, TareWeight: 180}
+	assert.NoError(t, service.SetPackDetails(ctx, "", box))
+	assert.ErrorIs(t, service.SetPackDetails(ctx, "", pack.PackSize{Size: 42, Label: "Nope"}), pack.ErrNotFound)
+	assert.ErrorIs(t, service.SetPackDetails(ctx, "", pack.PackSize{Size: 250, TareWeight: -1}), pack.ErrInvalid)
+	assert.ErrorIs(t, service.AddPack(ctx, "", pack.PackSize{Size: 1000, Dimensions: pack.Dimensions{Height: -1}}), pack.ErrInvalid)
+
+	result, err := service.Calculate(ctx, 260, pack.WithAlternatives(2))
+	assert.NoError(t, err)
+	assert.Equal(t, box, result.Details[250])
+	assert.Equal(t, "Small box", result.Alternatives[1].Details[250].Label)
+	assert.Equal(t, pack.PackSize{Size: 500}, result.Details[500])
+}
+
+func TestReplacePacks(t *testing.T) {
+	repo := newRepo(t, []int{250, 500, 1000, 5000}, map[int]int64{500: 150}, nil)
+	service := pack.NewService(repo, pack.WithLimits(pack.Limits{MaxPackSizes: 4}))
+	ctx := context.Background()
+
+	assert.ErrorIs(t, service.ReplacePacks(ctx, "", nil), pack.ErrInvalid)
+	assert.ErrorIs(t, service.ReplacePacks(ctx, "", []int{300, 0}), pack.ErrInvalid)
+	assert.ErrorIs(t, service.ReplacePacks(ctx, "", []int{300, 600, 300}), pack.ErrInvalid)
+	assert.ErrorIs(t, service.ReplacePacks(ctx, "", []int{1, 2, 3, 4, 5}), pack.ErrInvalid)
+	assert.ErrorIs(t, service.ReplacePacks(ctx, "missing", []int{300}), pack.ErrNotFound)
+	assert.ErrorIs(t, service.AddPack(ctx, "", pack.PackSize{Size: 42}), pack.ErrInvalid)
+
+	// Unchanged sets, in any order, record nothing.
+	assert.NoError(t, service.ReplacePacks(ctx, "", []int{5000, 1000, 500, 250}))
+	history, err := service.CatalogHistory(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	assert.NoError(t, service.ReplacePacks(ctx, "", []int{900, 500, 300}))
+	packs, err := service.ListPacks(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 300}, {Size: 500, UnitCost: 150}, {Size: 900}}, packs)
+
+	history, err = service.CatalogHistory(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	assert.Equal(t, "replaced pack sizes with 300, 500, 900", history[0].Change)
+
+	result, err := service.Calculate(ctx, 1200)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{300: 1, 900: 1}, result.Packs)
+}
+
+func TestDeactivatePack(t *testing.T) {
+	repo := newRepo(t, []int{250, 500, 1000}, nil, nil)
+	service := pack.NewService(repo)
+	ctx := context.Background()
+
+	assert.NoError(t, service.DeactivatePack(ctx, "", 250))
+	assert.NoError(t, service.DeactivatePack(ctx, "", 250))
+	assert.ErrorIs(t, service.DeactivatePack(ctx, "", 42), pack.ErrNotFound)
+
+	result, err := service.Calculate(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 1}, result.Packs)
+	assert.NotContains(t, result.Details, 250)
+
+	// The size stays listed and in the history, and earlier versions still
+	// use it.
+	packs, err := service.ListPacks(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 250, Inactive: true}, {Size: 500}, {Size: 1000}}, packs)
+	history, err := service.CatalogHistory(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+	result, err = service.Calculate(ctx, 1, pack.WithVersion(1))
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 1}, result.Packs)
+
+	assert.NoError(t, service.DeactivatePack(ctx, "", 500))
+	assert.NoError(t, service.DeactivatePack(ctx, "", 1000))
+	_, err = service.Calculate(ctx, 1)
+	assert.EqualError(t, err, "no pack sizes available")
+
+	assert.NoError(t, service.ActivatePack(ctx, "", 250))
+	result, err = service.Calculate(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 1}, result.Packs)
+}
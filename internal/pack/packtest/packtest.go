@@ -0,0 +1,445 @@
+// Package packtest checks that a pack.Repository behaves the way
+// pack.Service relies on, so every storage backend can be held to the same
+// contract.
+package packtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// NewRepository returns an empty repository holding only the default
+// catalog, without pack sizes, stock or orders. It is called once per test.
+type NewRepository func(t *testing.T) pack.Repository
+
+// RunRepositoryTests runs the conformance suite against repositories made
+// by newRepo.
+func RunRepositoryTests(t *testing.T, newRepo NewRepository) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo pack.Repository)
+	}{
+		{"CatalogOrdering", testCatalogOrdering},
+		{"PackSizeOrdering", testPackSizeOrdering},
+		{"VersionOrdering", testVersionOrdering},
+		{"OrderOrdering", testOrderOrdering},
+		{"DuplicatePackSize", testDuplicatePackSize},
+		{"PackDetails", testPackDetails},
+		{"PackActive", testPackActive},
+		{"PackSchedule", testPackSchedule},
+		{"ReplacePackSizes", testReplacePackSizes},
+		{"DeletePackSize", testDeletePackSize},
+		{"NotFound", testNotFound},
+		{"DeleteCatalog", testDeleteCatalog},
+		{"ConcurrentInserts", testConcurrentInserts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newRepo(t))
+		})
+	}
+}
+
+func by(description string) pack.Change {
+	return pack.Change{By: "packtest", Description: description}
+}
+
+func sizesOf(t *testing.T, repo pack.Repository, catalog string) []int {
+	t.Helper()
+	packs, err := repo.GetPackSizes(context.Background(), catalog)
+	require.NoError(t, err)
+	sizes := make([]int, len(packs))
+	for i, p := range packs {
+		sizes[i] = p.Size
+	}
+	return sizes
+}
+
+func versionsOf(t *testing.T, repo pack.Repository, catalog string) []pack.CatalogVersion {
+	t.Helper()
+	versions, err := repo.GetCatalogVersions(context.Background(), catalog)
+	require.NoError(t, err)
+	return versions
+}
+
+// Catalogs are listed by ID, and adding one twice fails with ErrDuplicate.
+func testCatalogOrdering(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertCatalog(ctx, pack.Catalog{ID: "widgets", Name: "Widgets"}, by("add widgets")))
+	require.NoError(t, repo.InsertCatalog(ctx, pack.Catalog{ID: "bolts", Name: "Bolts"}, by("add bolts")))
+	assert.ErrorIs(t, repo.InsertCatalog(ctx, pack.Catalog{ID: "bolts", Name: "Other"}, by("add bolts again")), pack.ErrDuplicate)
+
+	catalogs, err := repo.GetCatalogs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.Catalog{
+		{ID: "bolts", Name: "Bolts"},
+		{ID: pack.DefaultCatalog, Name: "Default"},
+		{ID: "widgets", Name: "Widgets"},
+	}, catalogs)
+
+	c, err := repo.GetCatalog(ctx, "bolts")
+	require.NoError(t, err)
+	assert.Equal(t, pack.Catalog{ID: "bolts", Name: "Bolts"}, c)
+}
+
+// Pack sizes are listed smallest first, whatever order they were added in,
+// and each catalog only sees its own.
+func testPackSizeOrdering(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertCatalog(ctx, pack.Catalog{ID: "widgets", Name: "Widgets"}, by("add widgets")))
+	for _, size := range []int{1000, 250, 5000, 500} {
+		require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: size, UnitCost: int64(size / 10)}, by("add")))
+	}
+	require.NoError(t, repo.InsertPackSize(ctx, "widgets", pack.PackSize{Size: 6}, by("add")))
+
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{
+		{Size: 250, UnitCost: 25},
+		{Size: 500, UnitCost: 50},
+		{Size: 1000, UnitCost: 100},
+		{Size: 5000, UnitCost: 500},
+	}, packs)
+	assert.Equal(t, []int{6}, sizesOf(t, repo, "widgets"))
+}
+
+// Versions are listed newest first, numbered from 1 without gaps, and each
+// holds the sizes as they were after its change.
+func testVersionOrdering(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500}, by("add 500")))
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250}, by("add 250")))
+	require.NoError(t, repo.UpdatePackCost(ctx, pack.DefaultCatalog, 250, 30, by("cost 250")))
+	require.NoError(t, repo.DeletePackSize(ctx, pack.DefaultCatalog, 500, by("remove 500")))
+
+	versions := versionsOf(t, repo, pack.DefaultCatalog)
+	require.Len(t, versions, 5)
+	for i, v := range versions {
+		assert.Equal(t, pack.DefaultCatalog, v.Catalog)
+		assert.Equal(t, len(versions)-i, v.Version)
+		if i > 0 {
+			assert.False(t, v.ChangedAt.After(versions[i-1].ChangedAt), "version %d is newer than version %d", v.Version, versions[i-1].Version)
+		}
+	}
+	assert.Equal(t, "remove 500", versions[0].Change)
+	assert.Equal(t, "packtest", versions[0].ChangedBy)
+	assert.Equal(t, []pack.PackSize{{Size: 250, UnitCost: 30}}, versions[0].Sizes)
+	assert.Equal(t, []pack.PackSize{{Size: 250}, {Size: 500}}, versions[2].Sizes)
+	assert.Empty(t, versions[4].Sizes)
+
+	latest, err := repo.GetCatalogVersion(ctx, pack.DefaultCatalog, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 5, latest.Version)
+
+	second, err := repo.GetCatalogVersion(ctx, pack.DefaultCatalog, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 500}}, second.Sizes)
+
+	at, err := repo.GetCatalogVersionAt(ctx, pack.DefaultCatalog, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 5, at.Version)
+}
+
+// Orders get increasing IDs and are listed newest first, up to the limit.
+func testOrderOrdering(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	var ids []int64
+	for i := 1; i <= 3; i++ {
+		o, err := repo.InsertOrder(ctx, pack.Order{
+			RequestedBy: "packtest",
+			Catalog:     pack.DefaultCatalog,
+			Requested:   i,
+			TotalItems:  250,
+			TotalPacks:  1,
+			Packs:       map[int]int{250: 1},
+		}, false)
+		require.NoError(t, err)
+		if len(ids) > 0 {
+			assert.Greater(t, o.ID, ids[len(ids)-1])
+		}
+		ids = append(ids, o.ID)
+	}
+
+	orders, err := repo.GetOrders(ctx, pack.OrderFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, ids[2], orders[0].ID)
+	assert.Equal(t, ids[1], orders[1].ID)
+	assert.Equal(t, 3, orders[0].Requested)
+
+	o, err := repo.GetOrder(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 1}, o.Packs)
+}
+
+// Adding a size that already exists fails with ErrDuplicate and leaves the
+// catalog as it was, without a second entry or a new version.
+func testDuplicatePackSize(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250, UnitCost: 10}, by("add 250")))
+	versions := len(versionsOf(t, repo, pack.DefaultCatalog))
+
+	err := repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250, UnitCost: 99}, by("add 250 again"))
+	assert.ErrorIs(t, err, pack.ErrDuplicate)
+
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 250, UnitCost: 10}}, packs)
+	assert.Len(t, versionsOf(t, repo, pack.DefaultCatalog), versions)
+}
+
+// Changing or reading something that does not exist fails with ErrNotFound
+// without recording a version.
+func testNotFound(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250}, by("add 250")))
+
+	assert.ErrorIs(t, repo.DeletePackSize(ctx, pack.DefaultCatalog, 500, by("remove 500")), pack.ErrNotFound)
+	assert.ErrorIs(t, repo.UpdatePackCost(ctx, pack.DefaultCatalog, 500, 10, by("cost 500")), pack.ErrNotFound)
+	assert.ErrorIs(t, repo.UpdatePackDetails(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500, Label: "Box"}, by("label 500")), pack.ErrNotFound)
+	assert.ErrorIs(t, repo.SetPackActive(ctx, pack.DefaultCatalog, 500, false, by("deactivate 500")), pack.ErrNotFound)
+	assert.ErrorIs(t, repo.UpdatePackSchedule(ctx, pack.DefaultCatalog, 500, pack.Schedule{}, by("schedule 500")), pack.ErrNotFound)
+	assert.Equal(t, []int{250}, sizesOf(t, repo, pack.DefaultCatalog))
+	assert.Len(t, versionsOf(t, repo, pack.DefaultCatalog), 2)
+
+	_, err := repo.GetCatalog(ctx, "missing")
+	assert.ErrorIs(t, err, pack.ErrUnknownCatalog)
+	assert.ErrorIs(t, repo.DeleteCatalog(ctx, "missing"), pack.ErrUnknownCatalog)
+
+	_, err = repo.GetCatalogVersion(ctx, pack.DefaultCatalog, 3)
+	assert.ErrorIs(t, err, pack.ErrUnknownVersion)
+	_, err = repo.GetCatalogVersion(ctx, "missing", 0)
+	assert.ErrorIs(t, err, pack.ErrUnknownVersion)
+	_, err = repo.GetCatalogVersionAt(ctx, pack.DefaultCatalog, time.Now().Add(-24*time.Hour))
+	assert.ErrorIs(t, err, pack.ErrUnknownVersion)
+
+	_, err = repo.GetOrder(ctx, 1)
+	assert.ErrorIs(t, err, pack.ErrUnknownOrder)
+}
+
+// Pack details are stored with the size, kept apart from its cost, and
+// carried into version snapshots and orders.
+func testPackDetails(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	box := pack.PackSize{
+		Size:       250,
+		UnitCost:   120,
+		Label:      "Small box",
+		SKU:        "BOX-250",
+		Dimensions: pack.Dimensions{Length: 300, Width: 200, Height: 150},
+		TareWeight: 180,
+	}
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, box, by("add 250")))
+
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{box}, packs)
+
+	relabelled := pack.PackSize{Size: 250, UnitCost: 999, Label: "Box S", SKU: "BOX-250B", Dimensions: pack.Dimensions{Length: 310}}
+	require.NoError(t, repo.UpdatePackDetails(ctx, pack.DefaultCatalog, relabelled, by("relabel 250")))
+	relabelled.UnitCost = 120
+
+	packs, err = repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{relabelled}, packs)
+
+	versions := versionsOf(t, repo, pack.DefaultCatalog)
+	require.Len(t, versions, 3)
+	assert.Equal(t, []pack.PackSize{relabelled}, versions[0].Sizes)
+	assert.Equal(t, []pack.PackSize{box}, versions[1].Sizes)
+
+	order, err := repo.InsertOrder(ctx, pack.Order{Catalog: pack.DefaultCatalog, CatalogVersion: 3, Sizes: versions[0].Sizes, Packs: map[int]int{250: 1}}, false)
+	require.NoError(t, err)
+	order, err = repo.GetOrder(ctx, order.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{relabelled}, order.Sizes)
+}
+
+// Inactive pack sizes are still listed, keep their details and cost and are
+// flagged in version snapshots; changing their details or cost leaves them
+// inactive.
+func testPackActive(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250, UnitCost: 120}, by("add 250")))
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500, Inactive: true}, by("add 500 inactive")))
+	require.NoError(t, repo.SetPackActive(ctx, pack.DefaultCatalog, 250, false, by("deactivate 250")))
+	require.NoError(t, repo.UpdatePackDetails(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250, Label: "Small box"}, by("label 250")))
+	require.NoError(t, repo.UpdatePackCost(ctx, pack.DefaultCatalog, 250, 130, by("cost 250")))
+
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{
+		{Size: 250, UnitCost: 130, Label: "Small box", Inactive: true},
+		{Size: 500, Inactive: true},
+	}, packs)
+
+	require.NoError(t, repo.SetPackActive(ctx, pack.DefaultCatalog, 500, true, by("activate 500")))
+	versions := versionsOf(t, repo, pack.DefaultCatalog)
+	require.Len(t, versions, 7)
+	assert.Equal(t, []pack.PackSize{{Size: 250, UnitCost: 130, Label: "Small box", Inactive: true}, {Size: 500}}, versions[0].Sizes)
+	assert.Equal(t, []pack.PackSize{{Size: 250, UnitCost: 120}, {Size: 500, Inactive: true}}, versions[4].Sizes)
+}
+
+// inUTC returns packs with their schedule times in UTC, as repositories may
+// hand them back in another location.
+func inUTC(packs []pack.PackSize) []pack.PackSize {
+	out := make([]pack.PackSize, len(packs))
+	for i, p := range packs {
+		if !p.Schedule.AvailableFrom.IsZero() {
+			p.Schedule.AvailableFrom = p.Schedule.AvailableFrom.UTC()
+		}
+		if !p.Schedule.RetiresAt.IsZero() {
+			p.Schedule.RetiresAt = p.Schedule.RetiresAt.UTC()
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// Schedules are stored with the size, survive changes to its details and
+// are carried into version snapshots; a zero schedule clears them.
+func testPackSchedule(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	from := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	until := time.Date(2030, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250, Schedule: pack.Schedule{AvailableFrom: from}}, by("add 250")))
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500}, by("add 500")))
+	require.NoError(t, repo.UpdatePackSchedule(ctx, pack.DefaultCatalog, 500, pack.Schedule{RetiresAt: until}, by("retire 500")))
+	require.NoError(t, repo.UpdatePackDetails(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500, Label: "Box"}, by("label 500")))
+
+	want := []pack.PackSize{
+		{Size: 250, Schedule: pack.Schedule{AvailableFrom: from}},
+		{Size: 500, Label: "Box", Schedule: pack.Schedule{RetiresAt: until}},
+	}
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, want, inUTC(packs))
+
+	versions := versionsOf(t, repo, pack.DefaultCatalog)
+	require.Len(t, versions, 5)
+	assert.Equal(t, want, inUTC(versions[0].Sizes))
+
+	require.NoError(t, repo.UpdatePackSchedule(ctx, pack.DefaultCatalog, 250, pack.Schedule{}, by("clear 250")))
+	packs, err = repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, pack.PackSize{Size: 250}, packs[0])
+}
+
+// Replacing the pack sizes drops the ones left out, along with their stock,
+// overwrites the rest and records the whole change as one version.
+func testReplacePackSizes(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250, UnitCost: 120, Label: "Small box"}, by("add 250")))
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500}, by("add 500")))
+	require.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 250, 4))
+	require.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 500, 7))
+	before := len(versionsOf(t, repo, pack.DefaultCatalog))
+
+	replacement := []pack.PackSize{
+		{Size: 250, UnitCost: 130, Label: "Small box", Inactive: true},
+		{Size: 750, SKU: "BOX-750"},
+		{Size: 1000, UnitCost: 300},
+	}
+	require.NoError(t, repo.ReplacePackSizes(ctx, pack.DefaultCatalog, replacement, by("replace")))
+
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, replacement, packs)
+
+	versions := versionsOf(t, repo, pack.DefaultCatalog)
+	require.Len(t, versions, before+1)
+	assert.Equal(t, "replace", versions[0].Change)
+	assert.Equal(t, replacement, versions[0].Sizes)
+
+	stock, err := repo.GetStock(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 4}, stock)
+}
+
+// Deleting a pack size takes its stock with it, so adding the size back
+// starts it untracked.
+func testDeletePackSize(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250}, by("add 250")))
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500}, by("add 500")))
+	require.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 250, 4))
+	require.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 500, 7))
+
+	require.NoError(t, repo.DeletePackSize(ctx, pack.DefaultCatalog, 500, by("drop 500")))
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500}, by("add 500 again")))
+
+	stock, err := repo.GetStock(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 4}, stock)
+}
+
+// Deleting a catalog takes its pack sizes and stock with it.
+func testDeleteCatalog(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	require.NoError(t, repo.InsertCatalog(ctx, pack.Catalog{ID: "widgets", Name: "Widgets"}, by("add widgets")))
+	require.NoError(t, repo.InsertPackSize(ctx, "widgets", pack.PackSize{Size: 6}, by("add 6")))
+	require.NoError(t, repo.SetStock(ctx, "widgets", 6, 10))
+
+	require.NoError(t, repo.DeleteCatalog(ctx, "widgets"))
+
+	_, err := repo.GetCatalog(ctx, "widgets")
+	assert.ErrorIs(t, err, pack.ErrUnknownCatalog)
+	assert.Empty(t, sizesOf(t, repo, "widgets"))
+	stock, err := repo.GetStock(ctx, "widgets")
+	require.NoError(t, err)
+	assert.Empty(t, stock)
+	assert.ErrorIs(t, repo.DeleteCatalog(ctx, "widgets"), pack.ErrUnknownCatalog)
+}
+
+// Concurrent inserts neither lose sizes nor hand out a version number
+// twice, and of racing inserts of the same size exactly one succeeds.
+func testConcurrentInserts(t *testing.T, repo pack.Repository) {
+	ctx := context.Background()
+	const workers = 20
+
+	var (
+		wg   sync.WaitGroup
+		wins atomic.Int32
+	)
+	for i := 1; i <= workers; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: i}, by(fmt.Sprintf("add %d", i))))
+		}()
+		go func() {
+			defer wg.Done()
+			err := repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 1000}, by("add 1000"))
+			if err == nil {
+				wins.Add(1)
+			} else {
+				assert.ErrorIs(t, err, pack.ErrDuplicate)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), wins.Load())
+
+	want := make([]int, 0, workers+1)
+	for i := 1; i <= workers; i++ {
+		want = append(want, i)
+	}
+	assert.Equal(t, append(want, 1000), sizesOf(t, repo, pack.DefaultCatalog))
+
+	versions := versionsOf(t, repo, pack.DefaultCatalog)
+	require.Len(t, versions, workers+2)
+	for i, v := range versions {
+		assert.Equal(t, len(versions)-i, v.Version)
+	}
+	assert.Len(t, versions[0].Sizes, workers+1)
+}
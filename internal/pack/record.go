@@ -0,0 +1,82 @@
+package pack
+
+import (
+	"context"
+	"time"
+)
+
+var ErrUnknownOrder = kindErrorf(ErrNotFound, "order not found")
+
+// Limits on how many orders a listing returns.
+const (
+	DefaultOrderLimit = 50
+	MaxOrderLimit     = 500
+)
+
+// Order is a confirmed calculation kept for later reference: what was asked
+// for, the packs reserved for it and the catalog version they came from.
+type Order struct {
+	ID             int64
+	CreatedAt      time.Time
+	RequestedBy    string
+	Catalog        string
+	CatalogVersion int
+	Sizes          []PackSize // pack sizes of the catalog at CatalogVersion
+	Strategy       string
+	Requested      int
+	TotalItems     int
+	TotalPacks     int
+	TotalCost      int64 // in cents
+	Packs          map[int]int
+}
+
+// Overage is how many items the order ships beyond what was asked for.
+func (o Order) Overage() int {
+	return o.TotalItems - o.Requested
+}
+
+// OrderFilter narrows a listing of orders. Zero fields match every order.
+type OrderFilter struct {
+	Catalog     string
+	RequestedBy string
+	From        time.Time // orders created at or after From
+	To          time.Time // orders created before To
+	Limit       int       // DefaultOrderLimit when 0, capped at MaxOrderLimit
+}
+
+// NewOrder turns a calculation into an order on behalf of the user in ctx,
+// with a copy of the pack sizes of the catalog version it used. The order
+// is not stored.
+func (s *Service) NewOrder(ctx context.Context, result PackResult) (Order, error) {
+	catalog := catalogID(result.Catalog)
+	version, err := s.repo.GetCatalogVersion(ctx, catalog, result.CatalogVersion)
+	if err != nil {
+		return Order{}, err
+	}
+
+	return Order{
+		RequestedBy:    ActorFromContext(ctx),
+		Catalog:        catalog,
+		CatalogVersion: version.Version,
+		Sizes:          version.Sizes,
+		Strategy:       result.Strategy,
+		Requested:      result.Requested,
+		TotalItems:     result.TotalItems,
+		TotalPacks:     result.TotalPacks,
+		TotalCost:      result.TotalCost,
+		Packs:          result.Packs,
+	}, nil
+}
+
+func (s *Service) GetOrder(ctx context.Context, id int64) (Order, error) {
+	return s.repo.GetOrder(ctx, id)
+}
+
+// ListOrders returns the orders matching f, newest first.
+func (s *Service) ListOrders(ctx context.Context, f OrderFilter) ([]Order, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultOrderLimit
+	}
+	f.Limit = min(f.Limit, MaxOrderLimit)
+	return s.repo.GetOrders(ctx, f)
+}
@@ -0,0 +1,78 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOrder(t *testing.T) {
+	repo := newRepo(t, []int{250, 500}, map[int]int64{250: 100, 500: 150}, map[int]int{500: 1})
+	service := pack.NewService(repo)
+	ctx := pack.ContextWithActor(context.Background(), "alice@example.com")
+
+	result, err := service.Calculate(ctx, 501)
+	assert.NoError(t, err)
+
+	order, err := service.NewOrder(ctx, result)
+	assert.NoError(t, err)
+	assert.Zero(t, order.ID)
+	assert.Equal(t, "alice@example.com", order.RequestedBy)
+	assert.Equal(t, pack.DefaultCatalog, order.Catalog)
+	assert.Equal(t, 1, order.CatalogVersion)
+	assert.Equal(t, []pack.PackSize{{Size: 250, UnitCost: 100}, {Size: 500, UnitCost: 150}}, order.Sizes)
+	assert.Equal(t, 501, order.Requested)
+	assert.Equal(t, 750, order.TotalItems)
+	assert.Equal(t, 249, order.Overage())
+	assert.Equal(t, int64(250), order.TotalCost)
+	assert.Equal(t, map[int]int{250: 1, 500: 1}, order.Packs)
+	assert.Equal(t, 1, onHand(t, repo, 500), "building an order leaves stock alone")
+
+	_, err = service.GetOrder(ctx, 1)
+	assert.ErrorIs(t, err, pack.ErrUnknownOrder)
+}
+
+func TestListOrders(t *testing.T) {
+	start := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	repo := newRepo(t, []int{250}, nil, nil)
+	service := pack.NewService(repo)
+	require.NoError(t, service.AddCatalog(context.Background(), pack.Catalog{ID: "bolts", Name: "Bolts"}))
+	require.NoError(t, service.AddPack(context.Background(), "bolts", pack.PackSize{Size: 100}))
+	now := start
+	repo.SetClock(func() time.Time { return now })
+
+	confirm := func(actor, catalog string, day int) {
+		now = start.AddDate(0, 0, day)
+		ctx := pack.ContextWithActor(context.Background(), actor)
+		result, err := service.Calculate(ctx, 1, pack.WithCatalog(catalog))
+		assert.NoError(t, err)
+		order, err := service.NewOrder(ctx, result)
+		assert.NoError(t, err)
+		_, err = repo.InsertOrder(ctx, order, false)
+		assert.NoError(t, err)
+	}
+	confirm("alice", pack.DefaultCatalog, 0)
+	confirm("bob", "bolts", 1)
+	confirm("alice", "bolts", 2)
+
+	ids := func(f pack.OrderFilter) []int64 {
+		orders, err := service.ListOrders(context.Background(), f)
+		assert.NoError(t, err)
+		out := []int64{}
+		for _, o := range orders {
+			out = append(out, o.ID)
+		}
+		return out
+	}
+
+	assert.Equal(t, []int64{3, 2, 1}, ids(pack.OrderFilter{}))
+	assert.Equal(t, []int64{3, 2}, ids(pack.OrderFilter{Catalog: "bolts"}))
+	assert.Equal(t, []int64{3, 1}, ids(pack.OrderFilter{RequestedBy: "alice"}))
+	assert.Equal(t, []int64{2}, ids(pack.OrderFilter{From: start.AddDate(0, 0, 1), To: start.AddDate(0, 0, 2)}))
+	assert.Equal(t, []int64{3}, ids(pack.OrderFilter{Limit: 1}))
+}
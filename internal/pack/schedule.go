@@ -0,0 +1,131 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Schedule limits when a pack size may be used, for changes suppliers
+// announce ahead of time. Zero times are open ended.
+type Schedule struct {
+	AvailableFrom time.Time // not used before this time
+	RetiresAt     time.Time // not used from this time on
+}
+
+// In reports whether the schedule allows use at t.
+func (s Schedule) In(t time.Time) bool {
+	if !s.AvailableFrom.IsZero() && t.Before(s.AvailableFrom) {
+		return false
+	}
+	return s.RetiresAt.IsZero() || t.Before(s.RetiresAt)
+}
+
+// Usable reports whether calculations at t may use the pack size: it is
+// active and within its schedule.
+func (p PackSize) Usable(at time.Time) bool {
+	return !p.Inactive && p.Schedule.In(at)
+}
+
+// WithClock makes the Service take the current time from now, which decides
+// the pack sizes a calculation may use. It defaults to time.Now.
+func WithClock(now func() time.Time) Option {
+	return func(s *Service) {
+		s.now = now
+	}
+}
+
+// checkSchedule validates a new schedule: its times must lie ahead, and a
+// size cannot retire before it becomes available.
+func (s *Service) checkSchedule(sched Schedule) error {
+	now := s.now()
+	for _, t := range []time.Time{sched.AvailableFrom, sched.RetiresAt} {
+		if !t.IsZero() && !t.After(now) {
+			return kindErrorf(ErrInvalid, "scheduled time %s is not in the future", t.Format(time.RFC3339))
+		}
+	}
+	return checkScheduleOrder(sched)
+}
+
+// checkScheduleOrder makes sure a size does not retire before it becomes
+// available.
+func checkScheduleOrder(sched Schedule) error {
+	if !sched.AvailableFrom.IsZero() && !sched.RetiresAt.IsZero() && !sched.RetiresAt.After(sched.AvailableFrom) {
+		return kindErrorf(ErrInvalid, "a pack size cannot retire before it becomes available")
+	}
+	return nil
+}
+
+// SchedulePack replaces the schedule of a pack size. A zero schedule makes
+// it usable at all times again.
+func (s *Service) SchedulePack(ctx context.Context, catalog string, size int, sched Schedule) error {
+	if err := s.checkSchedule(sched); err != nil {
+		return err
+	}
+	return s.repo.UpdatePackSchedule(ctx, catalogID(catalog), size, sched, change(ctx, describeSchedule(size, sched)))
+}
+
+func describeSchedule(size int, sched Schedule) string {
+	switch {
+	case !sched.AvailableFrom.IsZero() && !sched.RetiresAt.IsZero():
+		return fmt.Sprintf("scheduled pack size %d from %s until %s", size, sched.AvailableFrom.UTC().Format(time.RFC3339), sched.RetiresAt.UTC().Format(time.RFC3339))
+	case !sched.AvailableFrom.IsZero():
+		return fmt.Sprintf("scheduled pack size %d to become available at %s", size, sched.AvailableFrom.UTC().Format(time.RFC3339))
+	case !sched.RetiresAt.IsZero():
+		return fmt.Sprintf("scheduled pack size %d to retire at %s", size, sched.RetiresAt.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("cleared schedule of pack size %d", size)
+}
+
+// UpcomingChange is a scheduled change to a catalog that has not happened
+// yet.
+type UpcomingChange struct {
+	At        time.Time
+	Size      int
+	Available bool // the size becomes available at At, or else retires
+}
+
+// UpcomingChanges lists the scheduled changes to a catalog that lie ahead,
+// soonest first. Inactive sizes are left out as their schedule has no
+// effect until they are activated.
+func (s *Service) UpcomingChanges(ctx context.Context, catalog string) ([]UpcomingChange, error) {
+	packs, err := s.repo.GetPackSizes(ctx, catalogID(catalog))
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	changes := []UpcomingChange{}
+	for _, p := range packs {
+		if p.Inactive {
+			continue
+		}
+		if p.Schedule.AvailableFrom.After(now) {
+			changes = append(changes, UpcomingChange{At: p.Schedule.AvailableFrom, Size: p.Size, Available: true})
+		}
+		if p.Schedule.RetiresAt.After(now) {
+			changes = append(changes, UpcomingChange{At: p.Schedule.RetiresAt, Size: p.Size})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].At.Before(changes[j].At) })
+	return changes, nil
+}
+
+// AvailablePacks lists the pack sizes of a catalog that calculations may use
+// right now.
+func (s *Service) AvailablePacks(ctx context.Context, catalog string) ([]PackSize, error) {
+	packs, err := s.ListPacks(ctx, catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	usable := make([]PackSize, 0, len(packs))
+	for _, p := range packs {
+		if p.Usable(now) {
+			usable = append(usable, p)
+		}
+	}
+	return usable, nil
+}
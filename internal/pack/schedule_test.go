@@ -0,0 +1,79 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleIn(t *testing.T) {
+	from := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := pack.Schedule{AvailableFrom: from, RetiresAt: until}
+
+	assert.False(t, s.In(from.Add(-time.Second)))
+	assert.True(t, s.In(from))
+	assert.True(t, s.In(until.Add(-time.Second)))
+	assert.False(t, s.In(until))
+	assert.True(t, pack.Schedule{}.In(from))
+}
+
+func TestScheduledPackSizes(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	repo := newRepo(t, []int{250, 500}, nil, nil)
+	service := pack.NewService(repo, pack.WithClock(func() time.Time { return now }))
+	ctx := context.Background()
+
+	nextWeek, nextMonth := now.Add(7*24*time.Hour), now.Add(30*24*time.Hour)
+	assert.NoError(t, service.AddPack(ctx, "", pack.PackSize{Size: 1000, Schedule: pack.Schedule{AvailableFrom: nextWeek}}))
+	assert.NoError(t, service.SchedulePack(ctx, "", 250, pack.Schedule{RetiresAt: nextMonth}))
+
+	assert.ErrorIs(t, service.SchedulePack(ctx, "", 500, pack.Schedule{RetiresAt: now}), pack.ErrInvalid)
+	assert.ErrorIs(t, service.SchedulePack(ctx, "", 500, pack.Schedule{AvailableFrom: nextMonth, RetiresAt: nextWeek}), pack.ErrInvalid)
+	assert.ErrorIs(t, service.SchedulePack(ctx, "", 42, pack.Schedule{RetiresAt: nextWeek}), pack.ErrNotFound)
+
+	history, err := service.CatalogHistory(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "scheduled pack size 250 to retire at 2026-11-17T12:00:00Z", history[0].Change)
+
+	upcoming, err := service.UpcomingChanges(ctx, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []pack.UpcomingChange{
+		{At: nextWeek, Size: 1000, Available: true},
+		{At: nextMonth, Size: 250},
+	}, upcoming)
+
+	// Today the 1000 pack is not available yet.
+	result, err := service.Calculate(ctx, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 2}, result.Packs)
+
+	// A future asOf previews the catalog after both changes.
+	result, err = service.Calculate(ctx, 1, pack.WithAsOf(nextMonth))
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 1}, result.Packs)
+
+	// Once the time comes the changes apply without anyone acting on them.
+	now = nextWeek
+	result, err = service.Calculate(ctx, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{1000: 1}, result.Packs)
+
+	now = nextMonth
+	result, err = service.Calculate(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 1}, result.Packs)
+
+	upcoming, err = service.UpcomingChanges(ctx, "")
+	assert.NoError(t, err)
+	assert.Empty(t, upcoming)
+
+	assert.NoError(t, service.SchedulePack(ctx, "", 250, pack.Schedule{}))
+	result, err = service.Calculate(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 1}, result.Packs)
+}
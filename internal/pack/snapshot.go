@@ -0,0 +1,70 @@
+package pack
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// snapshotSize is how a pack size is stored in the snapshots kept with
+// catalog versions and orders. Stored snapshots are read back to replay old
+// versions, so every backend goes through MarshalSnapshot and
+// UnmarshalSnapshot and fields may only be added, never renamed. Snapshots
+// taken before pack sizes had details leave them out.
+type snapshotSize struct {
+	Size       int    `json:"size"`
+	UnitCost   int64  `json:"unitCost"`
+	Label      string `json:"label,omitempty"`
+	SKU        string `json:"sku,omitempty"`
+	Length     int    `json:"lengthMm,omitempty"`
+	Width      int    `json:"widthMm,omitempty"`
+	Height     int    `json:"heightMm,omitempty"`
+	TareWeight int    `json:"tareWeightG,omitempty"`
+	Inactive   bool   `json:"inactive,omitempty"`
+
+	AvailableFrom time.Time `json:"availableFrom,omitzero"`
+	RetiresAt     time.Time `json:"retiresAt,omitzero"`
+}
+
+// MarshalSnapshot encodes pack sizes for storage as a snapshot.
+func MarshalSnapshot(packs []PackSize) ([]byte, error) {
+	sizes := make([]snapshotSize, len(packs))
+	for i, p := range packs {
+		sizes[i] = snapshotSize{
+			Size:       p.Size,
+			UnitCost:   p.UnitCost,
+			Label:      p.Label,
+			SKU:        p.SKU,
+			Length:     p.Dimensions.Length,
+			Width:      p.Dimensions.Width,
+			Height:     p.Dimensions.Height,
+			TareWeight: p.TareWeight,
+			Inactive:   p.Inactive,
+
+			AvailableFrom: p.Schedule.AvailableFrom,
+			RetiresAt:     p.Schedule.RetiresAt,
+		}
+	}
+	return json.Marshal(sizes)
+}
+
+// UnmarshalSnapshot decodes pack sizes stored by MarshalSnapshot.
+func UnmarshalSnapshot(data []byte) ([]PackSize, error) {
+	var sizes []snapshotSize
+	if err := json.Unmarshal(data, &sizes); err != nil {
+		return nil, err
+	}
+	packs := make([]PackSize, len(sizes))
+	for i, s := range sizes {
+		packs[i] = PackSize{
+			Size:       s.Size,
+			UnitCost:   s.UnitCost,
+			Label:      s.Label,
+			SKU:        s.SKU,
+			Dimensions: Dimensions{Length: s.Length, Width: s.Width, Height: s.Height},
+			TareWeight: s.TareWeight,
+			Inactive:   s.Inactive,
+			Schedule:   Schedule{AvailableFrom: s.AvailableFrom, RetiresAt: s.RetiresAt},
+		}
+	}
+	return packs, nil
+}
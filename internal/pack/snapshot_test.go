@@ -0,0 +1,36 @@
+package pack_test
+
+import (
+	"testing"
+	"time"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	from := time.Date(2026, 9, 1, 12, 0, 0, 0, time.UTC)
+	packs := []pack.PackSize{
+		{Size: 250, UnitCost: 120},
+		{
+			Size: 500, UnitCost: 200, Label: "Medium box", SKU: "BOX-500",
+			Dimensions: pack.Dimensions{Length: 300, Width: 200, Height: 150}, TareWeight: 90,
+			Inactive: true, Schedule: pack.Schedule{AvailableFrom: from, RetiresAt: from.AddDate(1, 0, 0)},
+		},
+	}
+
+	data, err := pack.MarshalSnapshot(packs)
+	require.NoError(t, err)
+	got, err := pack.UnmarshalSnapshot(data)
+	require.NoError(t, err)
+	assert.Equal(t, packs, got)
+}
+
+// Snapshots stored before pack sizes had details still read back.
+func TestUnmarshalOldSnapshot(t *testing.T) {
+	got, err := pack.UnmarshalSnapshot([]byte(`[{"size": 250, "unitCost": 120}, {"size": 500, "unitCost": 0}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 250, UnitCost: 120}, {Size: 500}}, got)
+}
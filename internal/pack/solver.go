@@ -0,0 +1,340 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// unreachable marks totals in a table that no combination of packs can hit.
+const unreachable = 0
+
+// cancelCheckInterval is how many totals are filled between context checks.
+const cancelCheckInterval = 1 << 14
+
+// table holds the best pack count, and optionally the lowest cost, for
+// every total in a bounded window.
+//
+// All values are expressed in units of the greatest common divisor of the
+// pack sizes, which keeps the window small for catalogs such as 250/500/1000.
+// Orders larger than the window are folded into it by removing whole fold
+// packs (the largest pack, or the cheapest per item when costs are used):
+// once a total is past the periodicity threshold, the best combination for
+// it is the best combination for total-fold plus one fold pack, so only the
+// remainder needs to be solved explicitly.
+type table struct {
+	unit  int      // gcd of the original pack sizes
+	sizes []int    // normalised, de-duplicated sizes in ascending order
+	costs []int64  // unit cost per size, nil when costs are ignored
+	fold  int      // index into sizes of the pack folded out of large orders
+	shift int      // number of fold packs removed from the request
+	count []uint32 // packs+1 for each reachable total, unreachable otherwise
+	cost  []int64  // lowest cost for each total, nil when costs are ignored
+}
+
+// threshold returns the total above which reachability, pack counts and
+// costs repeat with a period of the fold pack.
+//
+// An optimal combination never uses fold-1 or more of the other packs: by
+// pigeonhole some run of them would sum to a multiple of the fold size and
+// could be swapped for fewer, no more expensive fold packs. Their sum is
+// therefore at most (fold-1)*otherLargest, and any total beyond that plus
+// one fold pack must contain at least one fold pack.
+func threshold(sizes []int, fold int) int {
+	if len(sizes) == 1 {
+		return sizes[fold]
+	}
+	other := sizes[len(sizes)-1]
+	if fold == len(sizes)-1 {
+		other = sizes[len(sizes)-2]
+	}
+	return (sizes[fold]-1)*other + sizes[fold]
+}
+
+// newTable fills the table for p, giving up if the estimated work exceeds
+// p.Limits or ctx is cancelled while filling. When weighted is set, totals
+// are ranked by the cost of their packs first and pack count second.
+func newTable(ctx context.Context, p Problem, weighted bool) (*table, error) {
+	norm, unit := normalise(p.Sizes)
+	largest := norm[len(norm)-1]
+
+	var costs []int64
+	fold := len(norm) - 1
+	if weighted {
+		costs = make([]int64, len(norm))
+		for i, s := range norm {
+			costs[i] = p.Costs[s*unit]
+		}
+		fold = cheapestPerItem(norm, costs)
+	}
+
+	target := ceilDiv(p.Quantity, unit)
+	shift := 0
+	if limit := threshold(norm, fold); target > limit {
+		shift = (target - limit) / norm[fold]
+		target -= shift * norm[fold]
+	}
+
+	// The best total at or above target is always below target+largest:
+	// a combination reaching further could drop a pack and still cover the
+	// order with fewer items, fewer packs and no more cost.
+	window := target + largest
+	if err := p.Limits.checkWork(window * len(norm)); err != nil {
+		return nil, err
+	}
+
+	count, cost, err := p.Tables.fill(ctx, norm, costs, window)
+	if err != nil {
+		return nil, err
+	}
+
+	return &table{unit: unit, sizes: norm, costs: costs, fold: fold, shift: shift, count: count, cost: cost}, nil
+}
+
+// fillTotals fills count, and cost when costs are given, for every total
+// from the index from on. The totals below from must already be filled.
+func fillTotals(ctx context.Context, count []uint32, cost []int64, from int, sizes []int, costs []int64) error {
+	if from == 0 {
+		count[0] = 1
+		from = 1
+	}
+	for t := from; t < len(count); t++ {
+		if t%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		best := uint32(unreachable)
+		var bestCost int64
+		for i, s := range sizes {
+			if s > t {
+				break
+			}
+			c := count[t-s]
+			if c == unreachable {
+				continue
+			}
+			var w int64
+			if costs != nil {
+				w = cost[t-s] + costs[i]
+			}
+			if best == unreachable || w < bestCost || (w == bestCost && c+1 < best) {
+				best, bestCost = c+1, w
+			}
+		}
+		count[t] = best
+		if costs != nil {
+			cost[t] = bestCost
+		}
+	}
+	return nil
+}
+
+// cheapestPerItem returns the index of the size with the lowest cost per
+// item, preferring larger sizes on ties.
+func cheapestPerItem(sizes []int, costs []int64) int {
+	best := len(sizes) - 1
+	for i := len(sizes) - 2; i >= 0; i-- {
+		if costs[i]*int64(sizes[best]) < costs[best]*int64(sizes[i]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// packs returns the number of packs for the normalised total t, including
+// the folded packs, or -1 if t cannot be reached.
+func (tb *table) packs(t int) int {
+	if tb.count[t] == unreachable {
+		return -1
+	}
+	return int(tb.count[t]) - 1 + tb.shift
+}
+
+// totalCost returns the lowest cost for the normalised total t, including
+// the folded packs. It is zero when costs are ignored.
+func (tb *table) totalCost(t int) int64 {
+	if tb.cost == nil {
+		return 0
+	}
+	return tb.cost[t] + int64(tb.shift)*tb.costs[tb.fold]
+}
+
+// combination rebuilds the pack breakdown for the normalised total t,
+// expressed in original pack sizes. Among equally good combinations it
+// prefers smaller packs, or larger ones when largestFirst is set.
+func (tb *table) combination(t int, largestFirst bool) map[int]int {
+	comb := map[int]int{}
+	for t > 0 {
+		for k := range tb.sizes {
+			i := k
+			if largestFirst {
+				i = len(tb.sizes) - 1 - k
+			}
+			if tb.step(t, i) {
+				comb[tb.sizes[i]*tb.unit]++
+				t -= tb.sizes[i]
+				break
+			}
+		}
+	}
+	if tb.shift > 0 {
+		comb[tb.sizes[tb.fold]*tb.unit] += tb.shift
+	}
+	return comb
+}
+
+// step reports whether the best combination for t can end with size i.
+func (tb *table) step(t, i int) bool {
+	s := tb.sizes[i]
+	if s > t || tb.count[t-s] == unreachable || tb.count[t-s] != tb.count[t]-1 {
+		return false
+	}
+	return tb.cost == nil || tb.cost[t-s]+tb.costs[i] == tb.cost[t]
+}
+
+// items converts the normalised total t back into items, including the
+// folded packs.
+func (tb *table) items(t int) int {
+	return (t + tb.shift*tb.sizes[tb.fold]) * tb.unit
+}
+
+// candidate is a reachable total inside the table window.
+type candidate struct {
+	t     int // normalised total, index into the table
+	items int
+	packs int
+	cost  int64
+}
+
+// candidates returns every reachable total that covers the order, in
+// ascending order of items. Totals beyond the window never need to be
+// considered: dropping any pack from them still covers the order with
+// fewer items, fewer packs and no more cost.
+func (tb *table) candidates() []candidate {
+	var out []candidate
+	for t := len(tb.count) - tb.sizes[len(tb.sizes)-1]; t < len(tb.count); t++ {
+		if tb.count[t] == unreachable {
+			continue
+		}
+		out = append(out, candidate{t: t, items: tb.items(t), packs: tb.packs(t), cost: tb.totalCost(t)})
+	}
+	return out
+}
+
+// grid is a filled solver table an objective can pick a total from.
+type grid interface {
+	candidates() []candidate
+	combination(t int, largestFirst bool) map[int]int
+}
+
+// objective describes how a strategy ranks the candidates of a table.
+type objective struct {
+	weighted     bool // rank totals by pack cost before pack count
+	largestFirst bool // prefer larger packs when rebuilding ties
+	better       func(a, b candidate) bool
+	only         func(c candidate) bool // restricts alternatives, nil accepts all
+}
+
+// solve returns the combination for p preferred by the objective. The
+// unbounded table is tried first; only when its answer needs more packs of
+// some size than p.Stock allows is the slower bounded table built.
+func (o objective) solve(ctx context.Context, p Problem) (PackResult, error) {
+	tb, err := newTable(ctx, p, o.weighted)
+	if err != nil {
+		return PackResult{}, err
+	}
+
+	result, err := o.pick(tb, p.Quantity)
+	if err != nil {
+		return PackResult{}, err
+	}
+
+	var bt *boundedTable
+	if !fitsStock(result.Packs, p.Stock) {
+		bt, err = newBoundedTable(ctx, p, o.weighted)
+		if err != nil {
+			return PackResult{}, err
+		}
+
+		result, err = o.pick(bt, p.Quantity)
+		if err != nil {
+			return PackResult{}, ErrInsufficientStock
+		}
+	}
+
+	if p.Alternatives == 0 && !p.Explain {
+		return result, nil
+	}
+
+	bounded := bt != nil
+	if !bounded {
+		if bt, err = newBoundedTable(ctx, p, o.weighted); err != nil {
+			return PackResult{}, err
+		}
+	}
+	if p.Alternatives > 0 {
+		if result.Alternatives, err = o.alternatives(ctx, bt, p, result); err != nil {
+			return PackResult{}, err
+		}
+	}
+	if p.Explain {
+		if result.Explanation, err = o.explain(ctx, tb, bt, bounded, p, result); err != nil {
+			return PackResult{}, err
+		}
+	}
+	return result, nil
+}
+
+// pick returns the candidate of g preferred by the objective.
+func (o objective) pick(g grid, quantity int) (PackResult, error) {
+	var best *candidate
+	for _, c := range g.candidates() {
+		if best == nil || o.better(c, *best) {
+			best = &c
+		}
+	}
+	if best == nil {
+		return PackResult{}, errors.New("no valid pack combination found")
+	}
+
+	return PackResult{
+		Requested:  quantity,
+		TotalItems: best.items,
+		TotalPacks: best.packs,
+		Packs:      g.combination(best.t, o.largestFirst),
+	}, nil
+}
+
+// normalise sorts and de-duplicates sizes and divides them by their gcd.
+func normalise(sizes []int) ([]int, int) {
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+
+	unit := 0
+	for _, s := range sorted {
+		unit = gcd(unit, s)
+	}
+
+	norm := make([]int, 0, len(sorted))
+	for _, s := range sorted {
+		if n := s / unit; len(norm) == 0 || norm[len(norm)-1] != n {
+			norm = append(norm, n)
+		}
+	}
+	return norm, unit
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func ceilDiv(a, b int) int {
+	if a <= 0 {
+		return 0
+	}
+	return (a-1)/b + 1
+}
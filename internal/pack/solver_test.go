@@ -0,0 +1,231 @@
+package pack_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// legacyCalculate is the original dense DP that kept a combination map for
+// every total up to quantity+2*maxSize. It is kept as a reference for
+// correctness checks and benchmarks only.
+func legacyCalculate(sizes []int, quantity int) (pack.PackResult, error) {
+	sizes = append([]int(nil), sizes...)
+	sort.Ints(sizes)
+
+	maxSize := sizes[len(sizes)-1]
+	limit := quantity + maxSize*2
+
+	type state struct {
+		packCount   int
+		combination map[int]int
+	}
+
+	dp := make([]*state, limit+1)
+	dp[0] = &state{packCount: 0, combination: map[int]int{}}
+
+	for i := 0; i <= limit; i++ {
+		if dp[i] == nil {
+			continue
+		}
+		for _, size := range sizes {
+			next := i + size
+			if next > limit {
+				continue
+			}
+			newCount := dp[i].packCount + 1
+			if dp[next] == nil || newCount < dp[next].packCount {
+				newComb := make(map[int]int, len(dp[i].combination)+1)
+				for k, v := range dp[i].combination {
+					newComb[k] = v
+				}
+				newComb[size]++
+				dp[next] = &state{packCount: newCount, combination: newComb}
+			}
+		}
+	}
+
+	for i := quantity; i <= limit; i++ {
+		if dp[i] != nil {
+			return pack.PackResult{
+				Requested:  quantity,
+				TotalItems: i,
+				TotalPacks: dp[i].packCount,
+				Packs:      dp[i].combination,
+			}, nil
+		}
+	}
+
+	return pack.PackResult{}, errors.New("no valid pack combination found")
+}
+
+var solverCatalogs = [][]int{
+	{250, 500, 1000, 2000, 5000},
+	{23, 31, 53},
+	{6, 9, 20},
+	{7},
+	{3, 5, 5, 12},
+	{97, 100},
+}
+
+func TestCalculateMatchesLegacy(t *testing.T) {
+	for _, sizes := range solverCatalogs {
+		service := pack.NewService(newRepo(t, sizes, nil, nil))
+		quantities := []int{1, 2, 3, 50, 99, 251, 501, 999, 12001}
+		for q := 9000; q < 11000; q += 7 {
+			quantities = append(quantities, q)
+		}
+
+		for _, q := range quantities {
+			want, err := legacyCalculate(sizes, q)
+			assert.NoError(t, err)
+
+			got, err := service.Calculate(context.Background(), q)
+			assert.NoError(t, err)
+			assert.Equal(t, want.TotalItems, got.TotalItems, "sizes %v quantity %d", sizes, q)
+			assert.Equal(t, want.TotalPacks, got.TotalPacks, "sizes %v quantity %d", sizes, q)
+			assertBreakdown(t, got)
+		}
+	}
+}
+
+func TestCalculateLargeQuantities(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{23, 31, 53}, nil, nil))
+
+	tests := []struct {
+		quantity   int
+		totalItems int
+		totalPacks int
+	}{
+		{quantity: 500_000_000, totalItems: 500_000_000, totalPacks: 9_433_966},
+		{quantity: 1_000_000_000, totalItems: 1_000_000_000, totalPacks: 18_867_928},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.quantity), func(t *testing.T) {
+			result, err := service.Calculate(context.Background(), tt.quantity)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.totalItems, result.TotalItems)
+			assert.Equal(t, tt.totalPacks, result.TotalPacks)
+			assertBreakdown(t, result)
+		})
+	}
+}
+
+func TestCalculateLimits(t *testing.T) {
+	repo := newRepo(t, []int{4999, 5000}, nil, nil)
+
+	t.Run("quantity", func(t *testing.T) {
+		service := pack.NewService(repo, pack.WithLimits(pack.Limits{MaxQuantity: 1000}))
+		_, err := service.Calculate(context.Background(), 1001)
+
+		var tooExpensive *pack.TooExpensiveError
+		assert.ErrorAs(t, err, &tooExpensive)
+		assert.Equal(t, "quantity", tooExpensive.Field)
+		assert.Equal(t, 1000, tooExpensive.Limit)
+	})
+
+	t.Run("work", func(t *testing.T) {
+		service := pack.NewService(repo, pack.WithLimits(pack.Limits{MaxWork: 1_000_000}))
+		_, err := service.Calculate(context.Background(), 1_000_000_000)
+
+		var tooExpensive *pack.TooExpensiveError
+		assert.ErrorAs(t, err, &tooExpensive)
+		assert.Equal(t, "work", tooExpensive.Field)
+	})
+
+	t.Run("within budget", func(t *testing.T) {
+		service := pack.NewService(repo, pack.WithLimits(pack.Limits{MaxQuantity: 1000, MaxWork: 1_000_000}))
+		result, err := service.Calculate(context.Background(), 1000)
+		assert.NoError(t, err)
+		assert.Equal(t, 4999, result.TotalItems)
+	})
+}
+
+func TestCalculateCancelled(t *testing.T) {
+	service := pack.NewService(newRepo(t, []int{4999, 5000}, nil, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := service.Calculate(ctx, 1_000_000_000)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestCalculateTableCache(t *testing.T) {
+	repo := newRepo(t, []int{23, 31, 53}, map[int]int64{23: 100, 31: 120, 53: 250}, nil)
+	service := pack.NewService(repo)
+	ctx := context.Background()
+
+	// Descending quantities are served from the first table, ascending ones
+	// extend it; both must match a calculation without the cache.
+	quantities := []int{5000, 263, 1, 9001, 10_000, 70, 1_000_000}
+	for _, strategy := range []string{pack.StrategyMinItems, pack.StrategyMinCost} {
+		cache := pack.NewTableCache()
+		for _, q := range quantities {
+			want, err := service.Calculate(ctx, q, pack.WithStrategy(strategy))
+			assert.NoError(t, err)
+
+			got, err := service.Calculate(ctx, q, pack.WithStrategy(strategy), pack.WithTableCache(cache))
+			assert.NoError(t, err)
+			assert.Equal(t, want.Packs, got.Packs, "strategy %s quantity %d", strategy, q)
+		}
+	}
+}
+
+func assertBreakdown(t *testing.T, r pack.PackResult) {
+	t.Helper()
+	items, packs := 0, 0
+	for size, count := range r.Packs {
+		items += size * count
+		packs += count
+	}
+	assert.Equal(t, r.TotalItems, items)
+	assert.Equal(t, r.TotalPacks, packs)
+}
+
+var benchQuantities = []int{1, 1_000, 1_000_000, 10_000_000, 100_000_000, 1_000_000_000}
+
+// legacyBenchLimit keeps the reference implementation away from quantities
+// whose state slice alone would need gigabytes of memory.
+const legacyBenchLimit = 1_000_000
+
+func BenchmarkCalculate(b *testing.B) {
+	for _, sizes := range [][]int{{250, 500, 1000, 2000, 5000}, {23, 31, 53}} {
+		service := pack.NewService(newRepo(b, sizes, nil, nil))
+		for _, q := range benchQuantities {
+			b.Run(fmt.Sprintf("sizes=%v/q=%d", sizes, q), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := service.Calculate(context.Background(), q); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkLegacyCalculate(b *testing.B) {
+	for _, sizes := range [][]int{{250, 500, 1000, 2000, 5000}, {23, 31, 53}} {
+		for _, q := range benchQuantities {
+			b.Run(fmt.Sprintf("sizes=%v/q=%d", sizes, q), func(b *testing.B) {
+				if q > legacyBenchLimit {
+					b.Skipf("legacy solver needs O(quantity) memory; skipping q=%d", q)
+				}
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := legacyCalculate(sizes, q); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Names of the built-in strategies.
+const (
+	StrategyMinItems      = "min-items"
+	StrategyFewestPacks   = "fewest-packs"
+	StrategyExact         = "exact"
+	StrategyMinOveragePct = "min-overage-pct"
+	StrategyLargePacks    = "large-packs"
+	StrategyMinCost       = "min-cost"
+)
+
+// DefaultStrategy is used when a calculation does not ask for one.
+const DefaultStrategy = StrategyMinItems
+
+var (
+	ErrUnknownStrategy = kindErrorf(ErrInvalid, "unknown strategy")
+	ErrNoExactMatch    = errors.New("no pack combination matches the quantity exactly")
+)
+
+// Problem is a single calculation handed to a Solver.
+type Problem struct {
+	Sizes    []int
+	Costs    map[int]int64 // unit cost per pack size, in cents
+	Stock    map[int]int   // packs available per size; sizes not listed are unlimited
+	Quantity int
+	Limits   Limits
+	// Alternatives is how many ranked combinations to return in
+	// PackResult.Alternatives, the best one included. Zero skips them.
+	Alternatives int
+	// Explain asks for PackResult.Explanation.
+	Explain bool
+	// Tables, if set, holds tables filled for earlier problems that this
+	// one may reuse.
+	Tables *TableCache
+}
+
+// Solver picks a pack combination for a Problem according to its own
+// objective.
+type Solver interface {
+	Solve(ctx context.Context, p Problem) (PackResult, error)
+}
+
+// Strategy is a named, user-selectable Solver.
+type Strategy struct {
+	Name        string
+	Description string
+	Solver      Solver
+}
+
+var strategies = []Strategy{
+	{Name: StrategyMinItems, Description: "Fewest items, then fewest packs", Solver: MinItems{}},
+	{Name: StrategyFewestPacks, Description: "Fewest packs, then fewest items", Solver: FewestPacks{}},
+	{Name: StrategyExact, Description: "Exact quantity only, fail otherwise", Solver: Exact{}},
+	{Name: StrategyMinOveragePct, Description: "Lowest overage percentage, then fewest packs", Solver: MinOveragePct{}},
+	{Name: StrategyLargePacks, Description: "Fewest items and packs, larger packs on ties", Solver: LargePacks{}},
+	{Name: StrategyMinCost, Description: "Lowest pack cost, then fewest items", Solver: MinCost{}},
+}
+
+// Strategies returns the built-in strategies in display order.
+func Strategies() []Strategy {
+	return append([]Strategy(nil), strategies...)
+}
+
+// LookupStrategy returns the strategy called name, or the default one when
+// name is empty.
+func LookupStrategy(name string) (Strategy, error) {
+	if name == "" {
+		name = DefaultStrategy
+	}
+	for _, st := range strategies {
+		if st.Name == name {
+			return st, nil
+		}
+	}
+	return Strategy{}, fmt.Errorf("%w: %q", ErrUnknownStrategy, name)
+}
+
+// MinItems ships the fewest items and, among those, uses the fewest packs.
+type MinItems struct{}
+
+func (MinItems) Solve(ctx context.Context, p Problem) (PackResult, error) {
+	return objective{better: fewerItems}.solve(ctx, p)
+}
+
+// FewestPacks uses the fewest packs and, among those, ships the fewest items.
+type FewestPacks struct{}
+
+func (FewestPacks) Solve(ctx context.Context, p Problem) (PackResult, error) {
+	return objective{better: func(a, b candidate) bool {
+		if a.packs != b.packs {
+			return a.packs < b.packs
+		}
+		return a.items < b.items
+	}}.solve(ctx, p)
+}
+
+// Exact only accepts combinations that ship exactly the requested quantity,
+// using the fewest packs.
+type Exact struct{}
+
+func (Exact) Solve(ctx context.Context, p Problem) (PackResult, error) {
+	result, err := objective{better: fewerItems, only: func(c candidate) bool {
+		return c.items == p.Quantity
+	}}.solve(ctx, p)
+	if err != nil {
+		return PackResult{}, err
+	}
+	if result.TotalItems != p.Quantity {
+		return PackResult{}, ErrNoExactMatch
+	}
+	return result, nil
+}
+
+// MinOveragePct minimises the overage as a whole percentage of the quantity,
+// so totals within the same percent are treated as equal and the one with
+// the fewest packs wins.
+type MinOveragePct struct{}
+
+func (MinOveragePct) Solve(ctx context.Context, p Problem) (PackResult, error) {
+	pct := func(c candidate) int {
+		if p.Quantity <= 0 {
+			return 0
+		}
+		return (c.items - p.Quantity) * 100 / p.Quantity
+	}
+	return objective{better: func(a, b candidate) bool {
+		if pa, pb := pct(a), pct(b); pa != pb {
+			return pa < pb
+		}
+		if a.packs != b.packs {
+			return a.packs < b.packs
+		}
+		return a.items < b.items
+	}}.solve(ctx, p)
+}
+
+// LargePacks behaves like MinItems but breaks ties between equally short
+// combinations in favour of larger packs.
+type LargePacks struct{}
+
+func (LargePacks) Solve(ctx context.Context, p Problem) (PackResult, error) {
+	return objective{largestFirst: true, better: fewerItems}.solve(ctx, p)
+}
+
+// MinCost pays the least for packs and, among equally cheap combinations,
+// ships the fewest items and then uses the fewest packs. Sizes without a
+// cost are treated as free.
+type MinCost struct{}
+
+func (MinCost) Solve(ctx context.Context, p Problem) (PackResult, error) {
+	return objective{weighted: true, better: func(a, b candidate) bool {
+		if a.cost != b.cost {
+			return a.cost < b.cost
+		}
+		if a.items != b.items {
+			return a.items < b.items
+		}
+		return a.packs < b.packs
+	}}.solve(ctx, p)
+}
+
+func fewerItems(a, b candidate) bool {
+	if a.items != b.items {
+		return a.items < b.items
+	}
+	return a.packs < b.packs
+}
+
+// CalcOption adjusts a single Calculate call.
+type CalcOption func(*calcOptions)
+
+type calcOptions struct {
+	strategy     string
+	catalog      string
+	version      int
+	asOf         time.Time
+	alternatives int
+	explain      bool
+	tables       *TableCache
+	catalogs     *CatalogCache
+}
+
+// historical reports whether the calculation runs against a past version
+// of the catalog rather than the latest one.
+func (o calcOptions) historical() bool {
+	return o.version > 0 || !o.asOf.IsZero()
+}
+
+// WithStrategy selects the strategy by name. An empty name keeps the
+// default.
+func WithStrategy(name string) CalcOption {
+	return func(o *calcOptions) {
+		o.strategy = name
+	}
+}
+
+// WithCatalog selects the pack catalog by ID. An empty ID keeps the
+// default catalog.
+func WithCatalog(id string) CalcOption {
+	return func(o *calcOptions) {
+		o.catalog = id
+	}
+}
+
+// WithVersion runs the calculation against the given version of the
+// catalog. Zero keeps the latest version.
+func WithVersion(version int) CalcOption {
+	return func(o *calcOptions) {
+		o.version = version
+	}
+}
+
+// WithAsOf runs the calculation against the version of the catalog that was
+// current at t, using the pack sizes scheduled for t, so a future t previews
+// announced changes. The version is taken from WithVersion when that is also
+// given; the zero time keeps the latest version and the current time.
+func WithAsOf(t time.Time) CalcOption {
+	return func(o *calcOptions) {
+		o.asOf = t
+	}
+}
+
+// WithAlternatives asks for the n best distinct combinations, ranked by the
+// strategy, in PackResult.Alternatives. The first one is the result itself.
+func WithAlternatives(n int) CalcOption {
+	return func(o *calcOptions) {
+		o.alternatives = n
+	}
+}
+
+// WithExplain asks for a trace of how the result was chosen in
+// PackResult.Explanation when explain is set.
+func WithExplain(explain bool) CalcOption {
+	return func(o *calcOptions) {
+		o.explain = explain
+	}
+}
+
+// WithTableCache lets the calculation reuse solver tables kept in c by
+// earlier calculations against the same pack sizes and costs, and keep its
+// own there for later ones.
+func WithTableCache(c *TableCache) CalcOption {
+	return func(o *calcOptions) {
+		o.tables = c
+	}
+}
+
+// WithCatalogCache lets the calculation reuse the catalog version and stock
+// read by earlier calculations sharing c, and keep its own there for later
+// ones.
+func WithCatalogCache(c *CatalogCache) CalcOption {
+	return func(o *calcOptions) {
+		o.catalogs = c
+	}
+}
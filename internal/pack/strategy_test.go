@@ -0,0 +1,157 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		sizes    []int
+		costs    map[int]int64
+		strategy string
+		quantity int
+		expected pack.PackResult
+		err      error
+	}{
+		{
+			name:     "Default Strategy",
+			sizes:    []int{250, 500, 1000, 2000, 5000},
+			quantity: 501,
+			expected: pack.PackResult{TotalItems: 750, TotalPacks: 2, Packs: map[int]int{500: 1, 250: 1}, Strategy: pack.StrategyMinItems},
+		},
+		{
+			name:     "Fewest Packs",
+			sizes:    []int{250, 500, 1000, 2000, 5000},
+			strategy: pack.StrategyFewestPacks,
+			quantity: 501,
+			expected: pack.PackResult{TotalItems: 1000, TotalPacks: 1, Packs: map[int]int{1000: 1}, Strategy: pack.StrategyFewestPacks},
+		},
+		{
+			name:     "Exact Match",
+			sizes:    []int{250, 500, 1000, 2000, 5000},
+			strategy: pack.StrategyExact,
+			quantity: 750,
+			expected: pack.PackResult{TotalItems: 750, TotalPacks: 2, Packs: map[int]int{500: 1, 250: 1}, Strategy: pack.StrategyExact},
+		},
+		{
+			name:     "Exact Match Impossible",
+			sizes:    []int{250, 500, 1000, 2000, 5000},
+			strategy: pack.StrategyExact,
+			quantity: 251,
+			err:      pack.ErrNoExactMatch,
+		},
+		{
+			name:     "Overage Within A Percent",
+			sizes:    []int{3, 100},
+			strategy: pack.StrategyMinOveragePct,
+			quantity: 199,
+			expected: pack.PackResult{TotalItems: 200, TotalPacks: 2, Packs: map[int]int{100: 2}, Strategy: pack.StrategyMinOveragePct},
+		},
+		{
+			name:     "Smaller Packs On Ties By Default",
+			sizes:    []int{1, 2, 5, 7},
+			quantity: 11,
+			expected: pack.PackResult{TotalItems: 11, TotalPacks: 3, Packs: map[int]int{1: 1, 5: 2}, Strategy: pack.StrategyMinItems},
+		},
+		{
+			name:     "Larger Packs On Ties",
+			sizes:    []int{1, 2, 5, 7},
+			strategy: pack.StrategyLargePacks,
+			quantity: 11,
+			expected: pack.PackResult{TotalItems: 11, TotalPacks: 3, Packs: map[int]int{7: 1, 2: 2}, Strategy: pack.StrategyLargePacks},
+		},
+		{
+			name:     "Cheapest Combination",
+			sizes:    []int{250, 500, 1000},
+			costs:    map[int]int64{250: 100, 500: 150, 1000: 500},
+			strategy: pack.StrategyMinCost,
+			quantity: 1000,
+			expected: pack.PackResult{TotalItems: 1000, TotalPacks: 2, Packs: map[int]int{500: 2}, Strategy: pack.StrategyMinCost, TotalCost: 300},
+		},
+		{
+			name:     "Default Strategy Reports Cost",
+			sizes:    []int{250, 500, 1000},
+			costs:    map[int]int64{250: 100, 500: 150, 1000: 500},
+			quantity: 1000,
+			expected: pack.PackResult{TotalItems: 1000, TotalPacks: 1, Packs: map[int]int{1000: 1}, Strategy: pack.StrategyMinItems, TotalCost: 500},
+		},
+		{
+			name:     "Unknown Strategy",
+			sizes:    []int{250},
+			strategy: "cheapest-ever",
+			quantity: 1,
+			err:      pack.ErrUnknownStrategy,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := pack.NewService(newRepo(t, tt.sizes, tt.costs, nil))
+			result, err := service.Calculate(context.Background(), tt.quantity, pack.WithStrategy(tt.strategy))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected.TotalItems, result.TotalItems)
+			assert.Equal(t, tt.expected.TotalPacks, result.TotalPacks)
+			assert.Equal(t, tt.expected.Packs, result.Packs)
+			assert.Equal(t, tt.expected.Strategy, result.Strategy)
+			assert.Equal(t, tt.expected.TotalCost, result.TotalCost)
+		})
+	}
+}
+
+// naiveMinCost ranks every total up to quantity+maxSize by cost, items and
+// pack count without any folding.
+func naiveMinCost(sizes []int, costs map[int]int64, quantity int) (items, packs int, cost int64) {
+	limit := quantity + sizes[len(sizes)-1]
+	type cell struct {
+		ok    bool
+		cost  int64
+		packs int
+	}
+	dp := make([]cell, limit+1)
+	dp[0] = cell{ok: true}
+	for t := 1; t <= limit; t++ {
+		for _, s := range sizes {
+			if s > t || !dp[t-s].ok {
+				continue
+			}
+			c := cell{ok: true, cost: dp[t-s].cost + costs[s], packs: dp[t-s].packs + 1}
+			if !dp[t].ok || c.cost < dp[t].cost || (c.cost == dp[t].cost && c.packs < dp[t].packs) {
+				dp[t] = c
+			}
+		}
+	}
+	items = -1
+	for t := quantity; t <= limit; t++ {
+		if dp[t].ok && (items < 0 || dp[t].cost < cost) {
+			items, packs, cost = t, dp[t].packs, dp[t].cost
+		}
+	}
+	return items, packs, cost
+}
+
+func TestMinCostMatchesNaive(t *testing.T) {
+	sizes := []int{23, 31, 53}
+	costs := map[int]int64{23: 10, 31: 12, 53: 25}
+	service := pack.NewService(newRepo(t, sizes, costs, nil))
+
+	for q := 1; q < 5000; q += 13 {
+		items, packs, cost := naiveMinCost(sizes, costs, q)
+
+		result, err := service.Calculate(context.Background(), q, pack.WithStrategy(pack.StrategyMinCost))
+		assert.NoError(t, err)
+		assert.Equal(t, items, result.TotalItems, "quantity %d", q)
+		assert.Equal(t, packs, result.TotalPacks, "quantity %d", q)
+		assert.Equal(t, cost, result.TotalCost, "quantity %d", q)
+		assertBreakdown(t, result)
+	}
+}
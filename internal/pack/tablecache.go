@@ -0,0 +1,72 @@
+package pack
+
+import (
+	"context"
+	"fmt"
+)
+
+// TableCache keeps the solver tables filled for earlier calculations, so
+// that running many quantities against the same pack sizes and costs fills
+// each table once and only extends it for larger quantities. Tables that
+// account for limited stock are not kept. A TableCache is not safe for
+// concurrent use; give each batch of calculations its own.
+type TableCache struct {
+	tables map[string]*filledTable
+}
+
+func NewTableCache() *TableCache {
+	return &TableCache{tables: map[string]*filledTable{}}
+}
+
+// filledTable is the part of a table that depends only on the pack sizes
+// and costs, not on the quantity.
+type filledTable struct {
+	count []uint32
+	cost  []int64 // nil when costs are ignored
+}
+
+// fill returns the pack counts, and the costs when costs are given, of the
+// first window totals for the normalised sizes. A nil cache fills a new
+// table every time.
+func (c *TableCache) fill(ctx context.Context, sizes []int, costs []int64, window int) ([]uint32, []int64, error) {
+	key := fmt.Sprint(sizes, costs)
+	ft := &filledTable{}
+	if c != nil && c.tables[key] != nil {
+		ft = c.tables[key]
+	}
+
+	if from := len(ft.count); from < window {
+		// Cached tables grow into spare capacity, doubling it when it runs
+		// out, so a batch of rising quantities is not copied every time.
+		grown := &filledTable{count: ft.count, cost: ft.cost}
+		if window > cap(ft.count) {
+			size := window
+			if c != nil {
+				size = max(window, 2*cap(ft.count))
+			}
+			grown.count = make([]uint32, from, size)
+			copy(grown.count, ft.count)
+			if costs != nil {
+				grown.cost = make([]int64, from, size)
+				copy(grown.cost, ft.cost)
+			}
+		}
+		grown.count = grown.count[:window]
+		if costs != nil {
+			grown.cost = grown.cost[:window]
+		}
+		if err := fillTotals(ctx, grown.count, grown.cost, from, sizes, costs); err != nil {
+			return nil, nil, err
+		}
+		ft = grown
+		if c != nil {
+			c.tables[key] = ft
+		}
+	}
+
+	cost := ft.cost
+	if cost != nil {
+		cost = cost[:window]
+	}
+	return ft.count[:window], cost, nil
+}
@@ -0,0 +1,78 @@
+package pack
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrUnknownVersion = kindErrorf(ErrNotFound, "catalog version not found")
+
+// CatalogVersion is a snapshot of a catalog's pack sizes taken after each
+// change, together with who made the change, when and what it was.
+type CatalogVersion struct {
+	Catalog   string
+	Version   int // starts at 1 when the catalog is created
+	ChangedAt time.Time
+	ChangedBy string
+	Change    string
+	Sizes     []PackSize
+}
+
+// Change describes a modification to a catalog for its version history.
+type Change struct {
+	By          string
+	Description string
+}
+
+type actorKey struct{}
+
+// ContextWithActor returns a context carrying the user responsible for any
+// catalog changes made with it.
+func ContextWithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the user set by ContextWithActor, or "system".
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return "system"
+}
+
+func change(ctx context.Context, description string) Change {
+	return Change{By: ActorFromContext(ctx), Description: description}
+}
+
+// CatalogHistory returns every version of a catalog, newest first.
+func (s *Service) CatalogHistory(ctx context.Context, catalog string) ([]CatalogVersion, error) {
+	catalog = catalogID(catalog)
+	if err := s.checkCatalog(ctx, catalog); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCatalogVersions(ctx, catalog)
+}
+
+// snapshot loads the catalog version a calculation runs against: the one
+// asked for by number or date, or the latest.
+func (s *Service) snapshot(ctx context.Context, catalog string, o calcOptions) (CatalogVersion, error) {
+	var (
+		v   CatalogVersion
+		err error
+	)
+	switch {
+	case o.version > 0:
+		v, err = s.repo.GetCatalogVersion(ctx, catalog, o.version)
+	case !o.asOf.IsZero():
+		v, err = s.repo.GetCatalogVersionAt(ctx, catalog, o.asOf)
+	default:
+		v, err = s.repo.GetCatalogVersion(ctx, catalog, 0)
+	}
+	if errors.Is(err, ErrUnknownVersion) {
+		if cerr := s.checkCatalog(ctx, catalog); cerr != nil {
+			return CatalogVersion{}, cerr
+		}
+	}
+	return v, err
+}
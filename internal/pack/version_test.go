@@ -0,0 +1,66 @@
+package pack_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pfg/internal/pack"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogVersions(t *testing.T) {
+	repo := newRepo(t, []int{250, 500}, nil, nil)
+	service := pack.NewService(repo)
+	ctx := pack.ContextWithActor(context.Background(), "ops@example.com")
+
+	start := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	repo.SetClock(func() time.Time { return now })
+	assert.NoError(t, service.AddPack(ctx, pack.DefaultCatalog, pack.PackSize{Size: 1000}))
+	now = start.Add(24 * time.Hour)
+	assert.NoError(t, service.RemovePack(ctx, pack.DefaultCatalog, 500))
+
+	history, err := service.CatalogHistory(ctx, pack.DefaultCatalog)
+	assert.NoError(t, err)
+	if assert.Len(t, history, 3) {
+		assert.Equal(t, 3, history[0].Version)
+		assert.Equal(t, "ops@example.com", history[0].ChangedBy)
+		assert.Equal(t, "removed pack size 500", history[0].Change)
+		assert.Equal(t, []pack.PackSize{{Size: 250}, {Size: 1000}}, history[0].Sizes)
+		assert.Equal(t, "added pack size 1000 at 0 cents", history[1].Change)
+	}
+
+	result, err := service.Calculate(ctx, 501)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.CatalogVersion)
+	assert.Equal(t, map[int]int{250: 3}, result.Packs)
+
+	result, err = service.Calculate(ctx, 501, pack.WithVersion(1))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.CatalogVersion)
+	assert.Equal(t, map[int]int{500: 1, 250: 1}, result.Packs)
+
+	result, err = service.Calculate(ctx, 501, pack.WithAsOf(start.Add(time.Hour)))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.CatalogVersion)
+
+	_, err = service.Calculate(ctx, 501, pack.WithVersion(7))
+	assert.ErrorIs(t, err, pack.ErrUnknownVersion)
+	_, err = service.Calculate(ctx, 501, pack.WithCatalog("gadgets"), pack.WithVersion(1))
+	assert.ErrorIs(t, err, pack.ErrUnknownCatalog)
+}
+
+func TestHistoricalCalculationIgnoresStock(t *testing.T) {
+	repo := newRepo(t, []int{250, 500}, nil, map[int]int{500: 0})
+	service := pack.NewService(repo)
+
+	result, err := service.Calculate(context.Background(), 500)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{250: 2}, result.Packs)
+
+	result, err = service.Calculate(context.Background(), 500, pack.WithVersion(1))
+	assert.NoError(t, err)
+	assert.Equal(t, map[int]int{500: 1}, result.Packs)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"pfg/internal/account"
 	"pfg/internal/auth"
 	"pfg/internal/handler"
 	"pfg/internal/html"
@@ -29,41 +30,111 @@ func NewRouter(jsonHandler *handler.Handler, htmlHandler *html.HTMLHandler, logg
 
 	r.Handle("/static/*", http.StripPrefix("/static/", html.StaticFileServer()))
 
-	// API routes
+	// API routes. Each declares the permission it needs; tokens without it
+	// are refused with 403 Forbidden.
 	r.Route("/api", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(jwt.Auth))
 		r.Use(jwtauth.Authenticator(jwt.Auth))
-		r.Use(auth.RequireToken)
+		r.Use(auth.WithActor)
 
-		r.Group(func(r chi.Router) {
-			r.Get("/packs", jsonHandler.ListPackSizes)
-			r.Post("/packs", jsonHandler.AddPackSize)
-			r.Delete("/packs", jsonHandler.DeletePackSize)
-		})
+		calculate := auth.Require(account.PermCalculate, nil)
+		viewCatalog := auth.Require(account.PermViewCatalog, nil)
+		editCatalog := auth.Require(account.PermEditCatalog, nil)
+		manageOrders := auth.Require(account.PermManageOrders, nil)
+		manageUsers := auth.Require(account.PermManageUsers, nil)
+
+		r.With(viewCatalog).Get("/packs", jsonHandler.ListPackSizes)
+		r.With(editCatalog).Post("/packs", jsonHandler.AddPackSize)
+		r.With(editCatalog).Put("/packs", jsonHandler.ReplacePackSizes)
+		r.With(editCatalog).Put("/packs/cost", jsonHandler.UpdatePackCost)
+		r.With(editCatalog).Post("/packs/activate", jsonHandler.ActivatePackSize)
+		r.With(editCatalog).Post("/packs/deactivate", jsonHandler.DeactivatePackSize)
+		r.With(editCatalog).Delete("/packs", jsonHandler.DeletePackSize)
+
+		r.With(viewCatalog).Get("/catalogs", jsonHandler.ListCatalogs)
+		r.With(editCatalog).Post("/catalogs", jsonHandler.AddCatalog)
+		r.With(editCatalog).Delete("/catalogs", jsonHandler.DeleteCatalog)
+		r.With(viewCatalog).Get("/catalogs/versions", jsonHandler.ListCatalogVersions)
+		r.With(viewCatalog).Get("/catalogs/export", jsonHandler.ExportCatalog)
+		r.With(editCatalog).Post("/catalogs/import", jsonHandler.ImportCatalog)
+
+		r.With(manageOrders).Get("/orders", jsonHandler.ListOrders)
+		r.With(manageOrders).Post("/orders", jsonHandler.QuoteOrder)
+		r.With(manageOrders).Get("/orders/{id}", jsonHandler.GetOrder)
+		r.With(manageOrders).Post("/orders/{id}/transitions", jsonHandler.AdvanceOrder)
+
+		r.With(manageUsers).Get("/users", jsonHandler.ListUsers)
+		r.With(manageUsers).Post("/users", jsonHandler.AddUser)
+		r.With(manageUsers).Put("/users/{id}", jsonHandler.UpdateUser)
+		r.With(manageUsers).Delete("/users/{id}", jsonHandler.DeleteUser)
+
+		r.With(viewCatalog).Get("/inventory", jsonHandler.ListStock)
+		r.With(editCatalog).Put("/inventory", jsonHandler.SetStock)
+		r.With(editCatalog).Delete("/inventory", jsonHandler.UntrackStock)
+
+		// Confirming a calculation also needs the manage_orders
+		// permission, which CalculatePacks checks itself.
+		r.With(calculate).Post("/calculate", jsonHandler.CalculatePacks)
+		r.With(calculate).Post("/orders/calculate", jsonHandler.CalculateOrder)
+		r.With(calculate).Post("/orders/bulk", jsonHandler.CalculateBulk)
 
-		r.Post("/calculate", jsonHandler.CalculatePacks)
+		// Version 2 serves pack sizes as objects with their cost and
+		// metadata; the routes above keep serving bare sizes.
+		r.Route("/v2", func(r chi.Router) {
+			r.With(viewCatalog).Get("/packs", jsonHandler.ListPackSizesV2)
+			r.With(editCatalog).Post("/packs", jsonHandler.AddPackSizeV2)
+			r.With(editCatalog).Put("/packs/details", jsonHandler.UpdatePackDetails)
+			r.With(editCatalog).Put("/packs/schedule", jsonHandler.SchedulePackSize)
+			r.With(viewCatalog).Get("/packs/upcoming", jsonHandler.ListUpcomingChanges)
+		})
 	})
 
+	// Admin pages. Users without the permission a page needs are shown
+	// the access denied page instead.
 	r.Group(func(r chi.Router) {
-		r.Use(func(next http.Handler) http.Handler {
-			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				if !auth.RequireAdminOnly(r) {
-					htmlHandler.RenderUnauthorized(w, r)
-					return
-				}
-				next.ServeHTTP(w, r)
-			})
-		})
+		r.Use(auth.WithActor)
+
+		calculate := auth.Require(account.PermCalculate, htmlHandler.RenderUnauthorized)
+		viewCatalog := auth.Require(account.PermViewCatalog, htmlHandler.RenderUnauthorized)
+		editCatalog := auth.Require(account.PermEditCatalog, htmlHandler.RenderUnauthorized)
+		manageOrders := auth.Require(account.PermManageOrders, htmlHandler.RenderUnauthorized)
+		manageUsers := auth.Require(account.PermManageUsers, htmlHandler.RenderUnauthorized)
+
+		r.With(viewCatalog).Get("/packs", htmlHandler.RenderPackList)
+		r.With(editCatalog).Post("/packs/add", htmlHandler.HandleAddPack)
+		r.With(editCatalog).Post("/packs/replace", htmlHandler.HandleReplacePacks)
+		r.With(editCatalog).Post("/packs/cost", htmlHandler.HandleUpdatePackCost)
+		r.With(editCatalog).Post("/packs/details", htmlHandler.HandleUpdatePackDetails)
+		r.With(editCatalog).Post("/packs/activate", htmlHandler.HandleActivatePack)
+		r.With(editCatalog).Post("/packs/deactivate", htmlHandler.HandleDeactivatePack)
+		r.With(editCatalog).Post("/packs/schedule", htmlHandler.HandleSchedulePack)
+		r.With(viewCatalog).Get("/packs/export", htmlHandler.HandleExportCatalog)
+		r.With(editCatalog).Post("/packs/import", htmlHandler.HandlePreviewImport)
+		r.With(editCatalog).Post("/packs/import/apply", htmlHandler.HandleApplyImport)
+		r.With(editCatalog).Post("/packs/delete", htmlHandler.HandleDeletePack)
+		r.With(editCatalog).Post("/packs/stock", htmlHandler.HandleSetStock)
+
+		r.With(viewCatalog).Get("/catalogs", htmlHandler.RenderCatalogList)
+		r.With(editCatalog).Post("/catalogs/add", htmlHandler.HandleAddCatalog)
+		r.With(editCatalog).Post("/catalogs/delete", htmlHandler.HandleDeleteCatalog)
+
+		r.With(manageOrders).Get("/orders", htmlHandler.RenderOrderList)
+		r.With(manageOrders).Post("/orders/advance", htmlHandler.HandleAdvanceOrder)
+		r.With(calculate).Get("/orders/bulk", htmlHandler.RenderBulkForm)
+		r.With(calculate).Post("/orders/bulk", htmlHandler.HandleBulkCalculate)
 
-		r.Get("/packs", htmlHandler.RenderPackList)
-		r.Post("/packs/add", htmlHandler.HandleAddPack)
-		r.Post("/packs/delete", htmlHandler.HandleDeletePack)
+		r.With(manageUsers).Get("/users", htmlHandler.RenderUserList)
+		r.With(manageUsers).Post("/users/add", htmlHandler.HandleAddUser)
+		r.With(manageUsers).Post("/users/password", htmlHandler.HandleSetPassword)
+		r.With(manageUsers).Post("/users/role", htmlHandler.HandleSetRole)
+		r.With(manageUsers).Post("/users/delete", htmlHandler.HandleDeleteUser)
 	})
 
-	// Public routes
+	// Public routes. The calculate page may confirm orders for users who
+	// manage them, so it records who is logged in as their requester.
 	r.Get("/", htmlHandler.RenderWelcomePage)
-	r.Get("/calculate", htmlHandler.RenderCalculateForm)
-	r.Post("/calculate", htmlHandler.RenderCalculateForm)
+	r.With(auth.WithActor).Get("/calculate", htmlHandler.RenderCalculateForm)
+	r.With(auth.WithActor).Post("/calculate", htmlHandler.RenderCalculateForm)
 	r.Get("/login", htmlHandler.RenderLoginForm)
 	r.Post("/login", htmlHandler.HandleLoginPost)
 
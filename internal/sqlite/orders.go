@@ -0,0 +1,267 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+)
+
+// orderPack is how a line of an order's breakdown is stored.
+type orderPack struct {
+	Size  int `json:"size"`
+	Count int `json:"count"`
+}
+
+func (r *Repository) InsertOrder(ctx context.Context, o pack.Order, reserve bool) (pack.Order, error) {
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if reserve {
+			if err := reserveStock(ctx, tx, o.Catalog, o.Packs); err != nil {
+				return err
+			}
+		}
+		var err error
+		o, err = insertOrder(ctx, tx, o)
+		return err
+	})
+	if err != nil {
+		return pack.Order{}, err
+	}
+	return o, nil
+}
+
+// insertOrder stores o and returns it with its ID and CreatedAt set.
+func insertOrder(ctx context.Context, tx *sql.Tx, o pack.Order) (pack.Order, error) {
+	snapshot, err := pack.MarshalSnapshot(o.Sizes)
+	if err != nil {
+		return pack.Order{}, err
+	}
+	packs := make([]orderPack, 0, len(o.Packs))
+	for size, count := range o.Packs {
+		packs = append(packs, orderPack{Size: size, Count: count})
+	}
+	breakdown, err := json.Marshal(packs)
+	if err != nil {
+		return pack.Order{}, err
+	}
+
+	o.CreatedAt = time.Now()
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO pack_orders (created_at, requested_by, catalog_id, catalog_version, sizes, strategy, requested, total_items, total_packs, total_cost_cents, packs)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`,
+		o.CreatedAt.UnixNano(), o.RequestedBy, o.Catalog, o.CatalogVersion, snapshot, o.Strategy, o.Requested, o.TotalItems, o.TotalPacks, o.TotalCost, string(breakdown),
+	).Scan(&o.ID)
+	if err != nil {
+		return pack.Order{}, err
+	}
+	return o, nil
+}
+
+const orderColumns = `id, created_at, requested_by, catalog_id, catalog_version, sizes, strategy, requested, total_items, total_packs, total_cost_cents, packs`
+
+func scanOrder(row interface{ Scan(...any) error }) (pack.Order, error) {
+	var (
+		o                   pack.Order
+		createdAt           int64
+		snapshot, breakdown string
+	)
+	err := row.Scan(&o.ID, &createdAt, &o.RequestedBy, &o.Catalog, &o.CatalogVersion, &snapshot,
+		&o.Strategy, &o.Requested, &o.TotalItems, &o.TotalPacks, &o.TotalCost, &breakdown)
+	if err != nil {
+		return pack.Order{}, err
+	}
+	o.CreatedAt = time.Unix(0, createdAt)
+
+	if o.Sizes, err = pack.UnmarshalSnapshot([]byte(snapshot)); err != nil {
+		return pack.Order{}, err
+	}
+
+	var packs []orderPack
+	if err := json.Unmarshal([]byte(breakdown), &packs); err != nil {
+		return pack.Order{}, err
+	}
+	o.Packs = make(map[int]int, len(packs))
+	for _, p := range packs {
+		o.Packs[p.Size] = p.Count
+	}
+	return o, nil
+}
+
+func (r *Repository) GetOrder(ctx context.Context, id int64) (pack.Order, error) {
+	o, err := scanOrder(r.db.QueryRowContext(ctx, `SELECT `+orderColumns+` FROM pack_orders WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return pack.Order{}, pack.ErrUnknownOrder
+	}
+	return o, err
+}
+
+func (r *Repository) GetOrders(ctx context.Context, f pack.OrderFilter) ([]pack.Order, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.Catalog != "" {
+		where, args = append(where, "catalog_id = ?"), append(args, f.Catalog)
+	}
+	if f.RequestedBy != "" {
+		where, args = append(where, "requested_by = ?"), append(args, f.RequestedBy)
+	}
+	if !f.From.IsZero() {
+		where, args = append(where, "created_at >= ?"), append(args, f.From.UnixNano())
+	}
+	if !f.To.IsZero() {
+		where, args = append(where, "created_at < ?"), append(args, f.To.UnixNano())
+	}
+
+	query := `SELECT ` + orderColumns + ` FROM pack_orders`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []pack.Order{}
+	for rows.Next() {
+		o, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+	return orders, rows.Err()
+}
+
+func (r *Repository) GetTransitions(ctx context.Context, orderID int64) ([]lifecycle.Transition, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT order_id, seq, from_state, to_state, changed_at, changed_by
+		FROM order_transitions WHERE order_id = ? ORDER BY seq ASC`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []lifecycle.Transition{}
+	for rows.Next() {
+		var (
+			t  lifecycle.Transition
+			at int64
+		)
+		if err := rows.Scan(&t.OrderID, &t.Seq, &t.From, &t.To, &at, &t.By); err != nil {
+			return nil, err
+		}
+		t.At = time.Unix(0, at)
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+func (r *Repository) GetStates(ctx context.Context, orderIDs []int64) (map[int64]lifecycle.State, error) {
+	states := map[int64]lifecycle.State{}
+	if len(orderIDs) == 0 {
+		return states, nil
+	}
+
+	args := make([]any, len(orderIDs))
+	for i, id := range orderIDs {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT t.order_id, t.to_state FROM order_transitions t
+		WHERE t.order_id IN (?`+strings.Repeat(", ?", len(orderIDs)-1)+`)
+		AND t.seq = (SELECT MAX(seq) FROM order_transitions WHERE order_id = t.order_id)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id    int64
+			state lifecycle.State
+		)
+		if err := rows.Scan(&id, &state); err != nil {
+			return nil, err
+		}
+		states[id] = state
+	}
+	return states, rows.Err()
+}
+
+func (r *Repository) InsertTransition(ctx context.Context, o pack.Order, t lifecycle.Transition, change lifecycle.StockChange) (lifecycle.Transition, error) {
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if t, err = insertTransition(ctx, tx, t); err != nil {
+			return err
+		}
+		return changeStock(ctx, tx, o, change)
+	})
+	if err != nil {
+		return lifecycle.Transition{}, err
+	}
+	return t, nil
+}
+
+func (r *Repository) CreateOrder(ctx context.Context, o pack.Order, t lifecycle.Transition, change lifecycle.StockChange) (pack.Order, lifecycle.Transition, error) {
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := changeStock(ctx, tx, o, change); err != nil {
+			return err
+		}
+		var err error
+		if o, err = insertOrder(ctx, tx, o); err != nil {
+			return err
+		}
+		t.OrderID = o.ID
+		t, err = insertTransition(ctx, tx, t)
+		return err
+	})
+	if err != nil {
+		return pack.Order{}, lifecycle.Transition{}, err
+	}
+	return o, t, nil
+}
+
+// insertTransition records t and returns it with At set. It fails with
+// ErrStaleState if the order already has a transition numbered t.Seq.
+func insertTransition(ctx context.Context, tx *sql.Tx, t lifecycle.Transition) (lifecycle.Transition, error) {
+	var taken bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM order_transitions WHERE order_id = ? AND seq = ?)`, t.OrderID, t.Seq).Scan(&taken)
+	if err != nil {
+		return lifecycle.Transition{}, err
+	}
+	if taken {
+		return lifecycle.Transition{}, lifecycle.ErrStaleState
+	}
+
+	t.At = time.Now()
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO order_transitions (order_id, seq, from_state, to_state, changed_at, changed_by)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		t.OrderID, t.Seq, t.From, t.To, t.At.UnixNano(), t.By)
+	if err != nil {
+		return lifecycle.Transition{}, err
+	}
+	return t, nil
+}
+
+// changeStock reserves or releases the packs of o as change says.
+func changeStock(ctx context.Context, tx *sql.Tx, o pack.Order, change lifecycle.StockChange) error {
+	switch change {
+	case lifecycle.ReserveStock:
+		return reserveStock(ctx, tx, o.Catalog, o.Packs)
+	case lifecycle.ReleaseStock:
+		return releaseStock(ctx, tx, o.Catalog, o.Packs)
+	}
+	return nil
+}
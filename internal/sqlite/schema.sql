@@ -0,0 +1,78 @@
+-- Schema for SQLite storage, kept in step with infra/atlas/schema.sql.
+-- Timestamps are Unix times in nanoseconds so they sort and compare exactly.
+
+CREATE TABLE IF NOT EXISTS pack_catalogs (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS pack_sizes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  catalog_id TEXT NOT NULL REFERENCES pack_catalogs (id) ON DELETE CASCADE,
+  size INTEGER NOT NULL,
+  unit_cost_cents INTEGER NOT NULL DEFAULT 0,
+  label TEXT NOT NULL DEFAULT '',
+  sku TEXT NOT NULL DEFAULT '',
+  length_mm INTEGER NOT NULL DEFAULT 0,
+  width_mm INTEGER NOT NULL DEFAULT 0,
+  height_mm INTEGER NOT NULL DEFAULT 0,
+  tare_weight_g INTEGER NOT NULL DEFAULT 0,
+  active INTEGER NOT NULL DEFAULT 1,
+  available_from INTEGER,
+  retires_at INTEGER,
+  UNIQUE (catalog_id, size)
+);
+
+CREATE TABLE IF NOT EXISTS pack_inventory (
+  catalog_id TEXT NOT NULL REFERENCES pack_catalogs (id) ON DELETE CASCADE,
+  size INTEGER NOT NULL,
+  on_hand INTEGER NOT NULL CHECK (on_hand >= 0),
+  PRIMARY KEY (catalog_id, size)
+);
+
+CREATE TABLE IF NOT EXISTS pack_catalog_versions (
+  catalog_id TEXT NOT NULL REFERENCES pack_catalogs (id) ON DELETE CASCADE,
+  version INTEGER NOT NULL,
+  changed_at INTEGER NOT NULL,
+  changed_by TEXT NOT NULL,
+  change TEXT NOT NULL,
+  sizes TEXT NOT NULL,
+  PRIMARY KEY (catalog_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS pack_orders (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at INTEGER NOT NULL,
+  requested_by TEXT NOT NULL,
+  catalog_id TEXT NOT NULL,
+  catalog_version INTEGER NOT NULL,
+  sizes TEXT NOT NULL,
+  strategy TEXT NOT NULL,
+  requested INTEGER NOT NULL,
+  total_items INTEGER NOT NULL,
+  total_packs INTEGER NOT NULL,
+  total_cost_cents INTEGER NOT NULL,
+  packs TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS pack_orders_created_at_idx ON pack_orders (created_at);
+
+CREATE TABLE IF NOT EXISTS order_transitions (
+  order_id INTEGER NOT NULL REFERENCES pack_orders (id) ON DELETE CASCADE,
+  seq INTEGER NOT NULL,
+  from_state TEXT NOT NULL,
+  to_state TEXT NOT NULL,
+  changed_at INTEGER NOT NULL,
+  changed_by TEXT NOT NULL,
+  PRIMARY KEY (order_id, seq)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  email TEXT NOT NULL UNIQUE,
+  password_hash TEXT NOT NULL,
+  role TEXT NOT NULL DEFAULT 'viewer',
+  created_at INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO pack_catalogs (id, name) VALUES ('default', 'Default');
@@ -0,0 +1,490 @@
+// Package sqlite stores pack catalogs, stock, orders and user accounts in a
+// single SQLite file, so the service can run as one binary without a
+// database server.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"pfg/internal/account"
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var schema string
+
+var (
+	_ pack.Repository      = (*Repository)(nil)
+	_ lifecycle.Repository = (*Repository)(nil)
+	_ account.Repository   = (*Repository)(nil)
+)
+
+// Repository implements pack.Repository, lifecycle.Repository and
+// account.Repository on top of SQLite, behaving like the Postgres
+// db.Repository.
+type Repository struct {
+	db *sql.DB
+}
+
+// Open opens or creates the database at path, creating any missing tables
+// along with the default catalog. Use ":memory:" for a private in-memory
+// database.
+func Open(path string) (*Repository, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; one connection keeps transactions
+	// from tripping over each other and makes ":memory:" a single database.
+	db.SetMaxOpenConns(1)
+
+	if err := bootstrap(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("bootstrap sqlite schema: %w", err)
+	}
+	return &Repository{db: db}, nil
+}
+
+// addedColumns are columns added to tables after they were first created.
+// The schema only creates missing tables, so bootstrap adds these to
+// databases made by older versions.
+var addedColumns = []struct{ table, column, definition string }{
+	{"pack_sizes", "label", "TEXT NOT NULL DEFAULT ''"},
+	{"pack_sizes", "sku", "TEXT NOT NULL DEFAULT ''"},
+	{"pack_sizes", "length_mm", "INTEGER NOT NULL DEFAULT 0"},
+	{"pack_sizes", "width_mm", "INTEGER NOT NULL DEFAULT 0"},
+	{"pack_sizes", "height_mm", "INTEGER NOT NULL DEFAULT 0"},
+	{"pack_sizes", "tare_weight_g", "INTEGER NOT NULL DEFAULT 0"},
+	{"pack_sizes", "active", "INTEGER NOT NULL DEFAULT 1"},
+	{"pack_sizes", "available_from", "INTEGER"},
+	{"pack_sizes", "retires_at", "INTEGER"},
+	{"users", "role", "TEXT NOT NULL DEFAULT 'viewer'"},
+}
+
+// backfills fill added columns from the data older versions kept, run once
+// when bootstrap adds the column named by the key.
+var backfills = map[string]string{
+	"users.role": `UPDATE users SET role = 'admin' WHERE is_admin`,
+}
+
+func bootstrap(db *sql.DB) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	for _, c := range addedColumns {
+		var exists bool
+		err := db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.column, c.definition)); err != nil {
+				return err
+			}
+			if fill, ok := backfills[c.table+"."+c.column]; ok {
+				if _, err := db.Exec(fill); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	_, err := db.Exec(`
+		INSERT INTO pack_catalog_versions (catalog_id, version, changed_at, changed_by, change, sizes)
+		SELECT 'default', 1, ?, 'system', 'initial version', '[]'
+		WHERE NOT EXISTS (SELECT 1 FROM pack_catalog_versions WHERE catalog_id = 'default')`,
+		time.Now().UnixNano())
+	return err
+}
+
+func (r *Repository) Close() error {
+	return r.db.Close()
+}
+
+// inTx runs fn in a transaction, committing it if fn succeeds.
+func (r *Repository) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) GetCatalogs(ctx context.Context) ([]pack.Catalog, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, name FROM pack_catalogs ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalogs := []pack.Catalog{}
+	for rows.Next() {
+		var c pack.Catalog
+		if err := rows.Scan(&c.ID, &c.Name); err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, c)
+	}
+	return catalogs, rows.Err()
+}
+
+func (r *Repository) GetCatalog(ctx context.Context, id string) (pack.Catalog, error) {
+	c := pack.Catalog{ID: id}
+	err := r.db.QueryRowContext(ctx, `SELECT name FROM pack_catalogs WHERE id = ?`, id).Scan(&c.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pack.Catalog{}, pack.ErrUnknownCatalog
+	}
+	return c, err
+}
+
+func (r *Repository) InsertCatalog(ctx context.Context, c pack.Catalog, change pack.Change) error {
+	return r.changeCatalog(ctx, c.ID, change, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `INSERT INTO pack_catalogs (id, name) VALUES (?, ?) ON CONFLICT (id) DO NOTHING`, c.ID, c.Name)
+		return inserted(res, err, pack.ErrDuplicateCatalog)
+	})
+}
+
+func (r *Repository) DeleteCatalog(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM pack_catalogs WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return pack.ErrUnknownCatalog
+	}
+	return nil
+}
+
+func (r *Repository) GetPackSizes(ctx context.Context, catalogID string) ([]pack.PackSize, error) {
+	return packSizes(ctx, r.db, catalogID)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func packSizes(ctx context.Context, q querier, catalogID string) ([]pack.PackSize, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT size, unit_cost_cents, label, sku, length_mm, width_mm, height_mm, tare_weight_g, NOT active, available_from, retires_at
+		FROM pack_sizes WHERE catalog_id = ? ORDER BY size ASC`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	packs := []pack.PackSize{}
+	for rows.Next() {
+		var (
+			p                        pack.PackSize
+			availableFrom, retiresAt sql.NullInt64
+		)
+		err := rows.Scan(&p.Size, &p.UnitCost, &p.Label, &p.SKU, &p.Dimensions.Length, &p.Dimensions.Width, &p.Dimensions.Height, &p.TareWeight, &p.Inactive, &availableFrom, &retiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if availableFrom.Valid {
+			p.Schedule.AvailableFrom = time.Unix(0, availableFrom.Int64)
+		}
+		if retiresAt.Valid {
+			p.Schedule.RetiresAt = time.Unix(0, retiresAt.Int64)
+		}
+		packs = append(packs, p)
+	}
+	return packs, rows.Err()
+}
+
+// insertPackSize inserts a pack size given the arguments from
+// packSizeArgs.
+const insertPackSize = `
+	INSERT INTO pack_sizes (catalog_id, size, unit_cost_cents, label, sku, length_mm, width_mm, height_mm, tare_weight_g, active, available_from, retires_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+func packSizeArgs(catalogID string, p pack.PackSize) []any {
+	return []any{catalogID, p.Size, p.UnitCost, p.Label, p.SKU, p.Dimensions.Length, p.Dimensions.Width, p.Dimensions.Height, p.TareWeight, !p.Inactive,
+		nullNanos(p.Schedule.AvailableFrom), nullNanos(p.Schedule.RetiresAt)}
+}
+
+func (r *Repository) InsertPackSize(ctx context.Context, catalogID string, p pack.PackSize, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, insertPackSize+` ON CONFLICT (catalog_id, size) DO NOTHING`, packSizeArgs(catalogID, p)...)
+		return inserted(res, err, pack.ErrDuplicatePackSize)
+	})
+}
+
+func (r *Repository) UpdatePackCost(ctx context.Context, catalogID string, size int, unitCost int64, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `UPDATE pack_sizes SET unit_cost_cents = ? WHERE catalog_id = ? AND size = ?`, unitCost, catalogID, size)
+		return affected(res, err)
+	})
+}
+
+func (r *Repository) UpdatePackDetails(ctx context.Context, catalogID string, p pack.PackSize, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE pack_sizes SET label = ?, sku = ?, length_mm = ?, width_mm = ?, height_mm = ?, tare_weight_g = ?
+			WHERE catalog_id = ? AND size = ?`,
+			p.Label, p.SKU, p.Dimensions.Length, p.Dimensions.Width, p.Dimensions.Height, p.TareWeight, catalogID, p.Size)
+		return affected(res, err)
+	})
+}
+
+func (r *Repository) SetPackActive(ctx context.Context, catalogID string, size int, active bool, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `UPDATE pack_sizes SET active = ? WHERE catalog_id = ? AND size = ?`, active, catalogID, size)
+		return affected(res, err)
+	})
+}
+
+func (r *Repository) UpdatePackSchedule(ctx context.Context, catalogID string, size int, s pack.Schedule, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `UPDATE pack_sizes SET available_from = ?, retires_at = ? WHERE catalog_id = ? AND size = ?`,
+			nullNanos(s.AvailableFrom), nullNanos(s.RetiresAt), catalogID, size)
+		return affected(res, err)
+	})
+}
+
+// nullNanos stores a time as Unix nanoseconds, or the zero time as NULL.
+func nullNanos(t time.Time) sql.NullInt64 {
+	if t.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.UnixNano(), Valid: true}
+}
+
+func (r *Repository) DeletePackSize(ctx context.Context, catalogID string, size int, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx *sql.Tx) (bool, error) {
+		res, err := tx.ExecContext(ctx, `DELETE FROM pack_sizes WHERE catalog_id = ? AND size = ?`, catalogID, size)
+		if _, err := affected(res, err); err != nil {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pack_inventory WHERE catalog_id = ? AND size = ?`, catalogID, size); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// dropStaleStock deletes the stock kept for sizes a catalog no longer has.
+const dropStaleStock = `
+	DELETE FROM pack_inventory WHERE catalog_id = ?
+	AND size NOT IN (SELECT size FROM pack_sizes WHERE catalog_id = ?)`
+
+func (r *Repository) ReplacePackSizes(ctx context.Context, catalogID string, packs []pack.PackSize, change pack.Change) error {
+	return r.changeCatalog(ctx, catalogID, change, func(tx *sql.Tx) (bool, error) {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pack_sizes WHERE catalog_id = ?`, catalogID); err != nil {
+			return false, err
+		}
+		for _, p := range packs {
+			if _, err := tx.ExecContext(ctx, insertPackSize, packSizeArgs(catalogID, p)...); err != nil {
+				return false, err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, dropStaleStock, catalogID, catalogID); err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+}
+
+// inserted reports whether an insert that ignores conflicts added a row,
+// failing with duplicate when it did not.
+func inserted(res sql.Result, err error, duplicate error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, duplicate
+	}
+	return true, nil
+}
+
+// affected reports whether a statement changed a pack size, failing with
+// pack.ErrUnknownPackSize when there was none.
+func affected(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, pack.ErrUnknownPackSize
+	}
+	return true, nil
+}
+
+// changeCatalog runs apply in a transaction and, if it reports a change,
+// records the catalog's pack sizes as a new version.
+func (r *Repository) changeCatalog(ctx context.Context, catalogID string, change pack.Change, apply func(tx *sql.Tx) (bool, error)) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		changed, err := apply(tx)
+		if err != nil || !changed {
+			return err
+		}
+
+		packs, err := packSizes(ctx, tx, catalogID)
+		if err != nil {
+			return err
+		}
+		snapshot, err := pack.MarshalSnapshot(packs)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO pack_catalog_versions (catalog_id, version, changed_at, changed_by, change, sizes)
+			SELECT ?, COALESCE(MAX(version), 0) + 1, ?, ?, ?, ?
+			FROM pack_catalog_versions WHERE catalog_id = ?`,
+			catalogID, time.Now().UnixNano(), change.By, change.Description, string(snapshot), catalogID)
+		return err
+	})
+}
+
+const versionColumns = `catalog_id, version, changed_at, changed_by, change, sizes`
+
+func scanVersion(row interface{ Scan(...any) error }) (pack.CatalogVersion, error) {
+	var (
+		v         pack.CatalogVersion
+		changedAt int64
+		snapshot  string
+	)
+	if err := row.Scan(&v.Catalog, &v.Version, &changedAt, &v.ChangedBy, &v.Change, &snapshot); err != nil {
+		return pack.CatalogVersion{}, err
+	}
+	v.ChangedAt = time.Unix(0, changedAt)
+
+	var err error
+	v.Sizes, err = pack.UnmarshalSnapshot([]byte(snapshot))
+	return v, err
+}
+
+func (r *Repository) GetCatalogVersions(ctx context.Context, catalogID string) ([]pack.CatalogVersion, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+versionColumns+` FROM pack_catalog_versions WHERE catalog_id = ? ORDER BY version DESC`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []pack.CatalogVersion{}
+	for rows.Next() {
+		v, err := scanVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+func (r *Repository) GetCatalogVersion(ctx context.Context, catalogID string, version int) (pack.CatalogVersion, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+versionColumns+` FROM pack_catalog_versions
+		WHERE catalog_id = ? AND (? = 0 OR version = ?)
+		ORDER BY version DESC LIMIT 1`, catalogID, version, version)
+	v, err := scanVersion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pack.CatalogVersion{}, pack.ErrUnknownVersion
+	}
+	return v, err
+}
+
+func (r *Repository) GetCatalogVersionAt(ctx context.Context, catalogID string, at time.Time) (pack.CatalogVersion, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT `+versionColumns+` FROM pack_catalog_versions
+		WHERE catalog_id = ? AND changed_at <= ?
+		ORDER BY version DESC LIMIT 1`, catalogID, at.UnixNano())
+	v, err := scanVersion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return pack.CatalogVersion{}, pack.ErrUnknownVersion
+	}
+	return v, err
+}
+
+func (r *Repository) GetStock(ctx context.Context, catalogID string) (map[int]int, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT size, on_hand FROM pack_inventory WHERE catalog_id = ?`, catalogID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := map[int]int{}
+	for rows.Next() {
+		var size, onHand int
+		if err := rows.Scan(&size, &onHand); err != nil {
+			return nil, err
+		}
+		stock[size] = onHand
+	}
+	return stock, rows.Err()
+}
+
+func (r *Repository) SetStock(ctx context.Context, catalogID string, size int, onHand int) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO pack_inventory (catalog_id, size, on_hand) VALUES (?, ?, ?)
+		ON CONFLICT (catalog_id, size) DO UPDATE SET on_hand = excluded.on_hand`, catalogID, size, onHand)
+	return err
+}
+
+func (r *Repository) DeleteStock(ctx context.Context, catalogID string, size int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM pack_inventory WHERE catalog_id = ? AND size = ?`, catalogID, size)
+	return err
+}
+
+func (r *Repository) ReserveStock(ctx context.Context, catalogID string, packs map[int]int) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return reserveStock(ctx, tx, catalogID, packs)
+	})
+}
+
+// reserveStock takes packs out of stock inside tx. Transactions take the
+// write lock when they begin, so no other reservation can interleave.
+func reserveStock(ctx context.Context, tx *sql.Tx, catalogID string, packs map[int]int) error {
+	for size, count := range packs {
+		var onHand int
+		err := tx.QueryRowContext(ctx, `SELECT on_hand FROM pack_inventory WHERE catalog_id = ? AND size = ?`, catalogID, size).Scan(&onHand)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if onHand < count {
+			return pack.ErrInsufficientStock
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE pack_inventory SET on_hand = on_hand - ? WHERE catalog_id = ? AND size = ?`, count, catalogID, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseStock puts packs back into the stock of the sizes that track it.
+func releaseStock(ctx context.Context, tx *sql.Tx, catalogID string, packs map[int]int) error {
+	for size, count := range packs {
+		if _, err := tx.ExecContext(ctx, `UPDATE pack_inventory SET on_hand = on_hand + ? WHERE catalog_id = ? AND size = ?`, count, catalogID, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"pfg/internal/account"
+	"pfg/internal/account/accounttest"
+	"pfg/internal/lifecycle"
+	"pfg/internal/pack"
+	"pfg/internal/pack/packtest"
+	"pfg/internal/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func open(t *testing.T) *sqlite.Repository {
+	t.Helper()
+	repo, err := sqlite.Open(filepath.Join(t.TempDir(), "packaging.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+func TestPackSizes(t *testing.T) {
+	repo := open(t)
+	ctx := context.Background()
+	change := pack.Change{By: "tester", Description: "add"}
+
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 500, UnitCost: 150}, change))
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250}, change))
+	assert.ErrorIs(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250}, change), pack.ErrDuplicatePackSize)
+
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 250}, {Size: 500, UnitCost: 150}}, packs)
+
+	assert.EqualError(t, repo.DeletePackSize(ctx, pack.DefaultCatalog, 1000, change), "pack size not found")
+	assert.EqualError(t, repo.UpdatePackCost(ctx, pack.DefaultCatalog, 1000, 1, change), "pack size not found")
+	assert.ErrorIs(t, repo.DeleteCatalog(ctx, "missing"), pack.ErrUnknownCatalog)
+
+	versions, err := repo.GetCatalogVersions(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	require.Len(t, versions, 3, "the duplicate insert and failed changes add no version")
+	assert.Equal(t, 3, versions[0].Version)
+	assert.Equal(t, "tester", versions[0].ChangedBy)
+	assert.Equal(t, packs, versions[0].Sizes)
+	assert.Empty(t, versions[2].Sizes)
+
+	_, err = repo.GetCatalogVersionAt(ctx, pack.DefaultCatalog, time.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, pack.ErrUnknownVersion)
+}
+
+func TestDataSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packaging.db")
+	ctx := context.Background()
+
+	repo, err := sqlite.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertCatalog(ctx, pack.Catalog{ID: "widgets", Name: "Widgets"}, pack.Change{By: "tester"}))
+	require.NoError(t, repo.InsertPackSize(ctx, "widgets", pack.PackSize{Size: 6}, pack.Change{By: "tester"}))
+	require.NoError(t, repo.Close())
+
+	repo, err = sqlite.Open(path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	catalogs, err := repo.GetCatalogs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.Catalog{{ID: pack.DefaultCatalog, Name: "Default"}, {ID: "widgets", Name: "Widgets"}}, catalogs)
+
+	versions, err := repo.GetCatalogVersions(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Len(t, versions, 1)
+}
+
+func TestOpenAddsNewColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packaging.db")
+	ctx := context.Background()
+
+	// A pack_sizes table as created before pack sizes had details.
+	db, err := sql.Open("sqlite", "file:"+path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE pack_catalogs (id TEXT PRIMARY KEY, name TEXT NOT NULL);
+		CREATE TABLE pack_sizes (
+		  id INTEGER PRIMARY KEY AUTOINCREMENT,
+		  catalog_id TEXT NOT NULL REFERENCES pack_catalogs (id) ON DELETE CASCADE,
+		  size INTEGER NOT NULL,
+		  unit_cost_cents INTEGER NOT NULL DEFAULT 0,
+		  UNIQUE (catalog_id, size)
+		);
+		INSERT INTO pack_catalogs (id, name) VALUES ('default', 'Default');
+		INSERT INTO pack_sizes (catalog_id, size, unit_cost_cents) VALUES ('default', 250, 120);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	repo, err := sqlite.Open(path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	packs, err := repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, []pack.PackSize{{Size: 250, UnitCost: 120}}, packs)
+
+	require.NoError(t, repo.UpdatePackDetails(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250, Label: "Small box"}, pack.Change{By: "tester"}))
+	packs, err = repo.GetPackSizes(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, "Small box", packs[0].Label)
+}
+
+func TestOpenGivesAdminsTheirRole(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packaging.db")
+	ctx := context.Background()
+
+	// A users table as created before accounts had roles.
+	db, err := sql.Open("sqlite", "file:"+path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE users (
+		  id INTEGER PRIMARY KEY AUTOINCREMENT,
+		  email TEXT NOT NULL UNIQUE,
+		  password_hash TEXT NOT NULL,
+		  is_admin INTEGER NOT NULL DEFAULT 0,
+		  created_at INTEGER NOT NULL
+		);
+		INSERT INTO users (email, password_hash, is_admin, created_at) VALUES ('amy@example.com', 'hash-1', 1, 0), ('zoe@example.com', 'hash-2', 0, 0);`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	repo, err := sqlite.Open(path)
+	require.NoError(t, err)
+	defer repo.Close()
+
+	users, err := repo.GetUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, account.RoleAdmin, users[0].Role)
+	assert.Equal(t, account.RoleViewer, users[1].Role)
+
+	// New users are stored although the old column stays behind.
+	_, err = repo.InsertUser(ctx, account.User{Email: "bob@example.com", PasswordHash: "hash-3", Role: account.RoleOperator})
+	require.NoError(t, err)
+}
+
+func TestConcurrentReservations(t *testing.T) {
+	repo := open(t)
+	ctx := context.Background()
+	require.NoError(t, repo.InsertPackSize(ctx, pack.DefaultCatalog, pack.PackSize{Size: 250}, pack.Change{}))
+	require.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 250, 10))
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		reserved int
+	)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := repo.ReserveStock(ctx, pack.DefaultCatalog, map[int]int{250: 1}); err == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			} else {
+				assert.ErrorIs(t, err, pack.ErrInsufficientStock)
+			}
+		}()
+	}
+	wg.Wait()
+
+	stock, err := repo.GetStock(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, 10, reserved)
+	assert.Equal(t, 0, stock[250])
+}
+
+func TestOrdersAndTransitions(t *testing.T) {
+	repo := open(t)
+	ctx := context.Background()
+	require.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 250, 1))
+
+	o, err := repo.InsertOrder(ctx, pack.Order{
+		RequestedBy: "tester",
+		Catalog:     pack.DefaultCatalog,
+		Sizes:       []pack.PackSize{{Size: 250, UnitCost: 10}},
+		Strategy:    "min-items",
+		Requested:   200,
+		TotalItems:  250,
+		TotalPacks:  1,
+		TotalCost:   10,
+		Packs:       map[int]int{250: 1},
+	}, false)
+	require.NoError(t, err)
+
+	got, err := repo.GetOrder(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, o.Packs, got.Packs)
+	assert.Equal(t, o.Sizes, got.Sizes)
+	assert.True(t, o.CreatedAt.Equal(got.CreatedAt))
+
+	_, err = repo.GetOrder(ctx, o.ID+1)
+	assert.ErrorIs(t, err, pack.ErrUnknownOrder)
+
+	quote := lifecycle.Transition{OrderID: o.ID, Seq: 1, To: lifecycle.Quoted, By: "tester"}
+	_, err = repo.InsertTransition(ctx, o, quote, lifecycle.KeepStock)
+	require.NoError(t, err)
+	_, err = repo.InsertTransition(ctx, o, quote, lifecycle.KeepStock)
+	assert.ErrorIs(t, err, lifecycle.ErrStaleState)
+
+	confirm := lifecycle.Transition{OrderID: o.ID, Seq: 2, From: lifecycle.Quoted, To: lifecycle.Confirmed, By: "tester"}
+	_, err = repo.InsertTransition(ctx, o, confirm, lifecycle.ReserveStock)
+	require.NoError(t, err)
+
+	stock, err := repo.GetStock(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, 0, stock[250])
+
+	states, err := repo.GetStates(ctx, []int64{o.ID})
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]lifecycle.State{o.ID: lifecycle.Confirmed}, states)
+
+	cancel := lifecycle.Transition{OrderID: o.ID, Seq: 3, From: lifecycle.Confirmed, To: lifecycle.Cancelled, By: "tester"}
+	_, err = repo.InsertTransition(ctx, o, cancel, lifecycle.ReleaseStock)
+	require.NoError(t, err)
+
+	stock, err = repo.GetStock(ctx, pack.DefaultCatalog)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stock[250])
+
+	orders, err := repo.GetOrders(ctx, pack.OrderFilter{RequestedBy: "tester", Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, orders, 1)
+}
+
+func TestCreateOrder(t *testing.T) {
+	repo := open(t)
+	ctx := context.Background()
+	require.NoError(t, repo.SetStock(ctx, pack.DefaultCatalog, 250, 1))
+
+	order := pack.Order{RequestedBy: "tester", Catalog: pack.DefaultCatalog, Packs: map[int]int{250: 1}}
+	confirm := lifecycle.Transition{Seq: 1, To: lifecycle.Confirmed, By: "tester"}
+	o, tr, err := repo.CreateOrder(ctx, order, confirm, lifecycle.ReserveStock)
+	require.NoError(t, err)
+	assert.Equal(t, o.ID, tr.OrderID)
+
+	history, err := repo.GetTransitions(ctx, o.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, lifecycle.Confirmed, history[0].To)
+	assert.True(t, tr.At.Equal(history[0].At))
+
+	// Without stock left neither the order nor its transition is stored.
+	_, _, err = repo.CreateOrder(ctx, order, confirm, lifecycle.ReserveStock)
+	assert.ErrorIs(t, err, pack.ErrInsufficientStock)
+	orders, err := repo.GetOrders(ctx, pack.OrderFilter{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, orders, 1)
+	history, err = repo.GetTransitions(ctx, o.ID+1)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	packtest.RunRepositoryTests(t, func(t *testing.T) pack.Repository {
+		return open(t)
+	})
+}
+
+func TestUserRepositoryConformance(t *testing.T) {
+	accounttest.RunRepositoryTests(t, func(t *testing.T) account.Repository {
+		return open(t)
+	})
+}
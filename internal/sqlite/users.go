@@ -0,0 +1,139 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"pfg/internal/account"
+)
+
+const userColumns = `id, email, password_hash, role, created_at`
+
+func scanUser(row interface{ Scan(...any) error }) (account.User, error) {
+	var (
+		u         account.User
+		createdAt int64
+	)
+	err := row.Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role, &createdAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return account.User{}, account.ErrUnknownUser
+	}
+	if err != nil {
+		return account.User{}, err
+	}
+	u.CreatedAt = time.Unix(0, createdAt)
+	return u, nil
+}
+
+func (r *Repository) GetUsers(ctx context.Context) ([]account.User, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY email ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []account.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *Repository) GetUser(ctx context.Context, id int64) (account.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+}
+
+func (r *Repository) GetUserByEmail(ctx context.Context, email string) (account.User, error) {
+	return scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, email))
+}
+
+func (r *Repository) InsertUser(ctx context.Context, u account.User) (account.User, error) {
+	u.CreatedAt = time.Now()
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO users (email, password_hash, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (email) DO NOTHING`,
+		u.Email, u.PasswordHash, u.Role, u.CreatedAt.UnixNano())
+	if _, err := inserted(res, err, account.ErrDuplicateUser); err != nil {
+		return account.User{}, err
+	}
+	if u.ID, err = res.LastInsertId(); err != nil {
+		return account.User{}, err
+	}
+	return u, nil
+}
+
+func (r *Repository) UpdatePassword(ctx context.Context, id int64, hash string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, hash, id)
+	return userAffected(res, err)
+}
+
+func (r *Repository) UpdateRole(ctx context.Context, id int64, role account.Role) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if role != account.RoleAdmin {
+			if err := keepAnAdmin(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE users SET role = ? WHERE id = ?`, role, id)
+		return userAffected(res, err)
+	})
+}
+
+func (r *Repository) DeleteUser(ctx context.Context, id int64) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		if err := keepAnAdmin(ctx, tx, id); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+		return userAffected(res, err)
+	})
+}
+
+// keepAnAdmin fails with account.ErrLastAdmin if the user with the given ID
+// is the only admin. Transactions take the write lock when they begin, so
+// no other change to the admins can interleave.
+func keepAnAdmin(ctx context.Context, tx *sql.Tx, id int64) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM users WHERE role = ?`, account.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var admins []int64
+	for rows.Next() {
+		var admin int64
+		if err := rows.Scan(&admin); err != nil {
+			return err
+		}
+		admins = append(admins, admin)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(admins) == 1 && admins[0] == id {
+		return account.ErrLastAdmin
+	}
+	return nil
+}
+
+// userAffected fails with account.ErrUnknownUser when a statement changed
+// no user.
+func userAffected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return account.ErrUnknownUser
+	}
+	return nil
+}